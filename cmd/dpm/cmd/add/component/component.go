@@ -32,7 +32,7 @@ func Cmd(config *assistantconfig.Config) *cobra.Command {
 			ctx := cmd.Context()
 			uri := args[0]
 
-			damlPackagePath, multiPackagePath, err := getDamlYamlOrMultiPackageYaml()
+			damlPackagePath, multiPackagePath, err := assistantconfig.GetDamlPackageOrMultiPackageAbsolutePath()
 			if err != nil {
 				return err
 			}
@@ -143,26 +143,6 @@ func asSdkManifest(uri string) (*sdkmanifest.SdkManifest, error) {
 	}, nil
 }
 
-func getDamlYamlOrMultiPackageYaml() (string, string, error) {
-	p, ok, err := assistantconfig.GetDamlPackageAbsolutePath()
-	if err != nil {
-		return "", "", err
-	}
-	if ok {
-		return p, "", nil
-	}
-
-	p, ok, err = assistantconfig.GetMultiPackageAbsolutePath()
-	if err != nil {
-		return "", "", err
-	}
-	if ok {
-		return "", p, nil
-	}
-
-	return "", "", fmt.Errorf("not in a (single-package or multi-package) project directory")
-}
-
 func findExistingComponent(components componentlist.ComponentList, uriRef registry.Reference) (int, error) {
 	for i, compEntry := range components {
 		if compEntry.StringBased == nil {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/url"
+	"path/filepath"
 	"strings"
 
 	project "daml.com/x/assistant/cmd/dpm/cmd/install/package"
@@ -56,7 +57,8 @@ func Cmd(config *assistantconfig.Config) *cobra.Command {
 
 			// update
 			if existingDep != nil {
-				ref, err := registry.ParseReference(strings.TrimPrefix(uri, "oci://"))
+				withoutRange, _, _ := ocilister.SplitRange(uri)
+				ref, err := registry.ParseReference(strings.TrimPrefix(withoutRange, "oci://"))
 				if err != nil {
 					return err
 				}
@@ -97,7 +99,8 @@ func findExistingDependency(uri, depsFieldName string) (*damlpackage.ParsedDarDe
 		deps = damlPackage.ParsedDarDependencies.DataDependencies
 	}
 
-	uriRef, err := registry.ParseReference(strings.TrimPrefix(uri, "oci://"))
+	withoutRange, _, _ := ocilister.SplitRange(uri)
+	uriRef, err := registry.ParseReference(strings.TrimPrefix(withoutRange, "oci://"))
 	if err != nil {
 		return nil, err
 	}
@@ -122,9 +125,11 @@ func findExistingDependency(uri, depsFieldName string) (*damlpackage.ParsedDarDe
 	return nil, nil
 }
 
-// AddOrUpdateDar will add when the passed index is -1, otherwise it will update at that index
+// AddOrUpdateDar will add when the passed index is -1, otherwise it will update at that index.
+// A uri with a semver range (e.g. oci://example.com/foo:^1.4) is pinned to the highest matching version, keeping the range
 func AddOrUpdateDar(ctx context.Context, config *assistantconfig.Config, uri string, insecure bool, yamlTarget yamledit.YamlTarget) error {
-	ref, err := registry.ParseReference(strings.TrimPrefix(uri, "oci://"))
+	withoutRange, semverRange, _ := ocilister.SplitRange(uri)
+	ref, err := registry.ParseReference(strings.TrimPrefix(withoutRange, "oci://"))
 	if err != nil {
 		return err
 	}
@@ -132,6 +137,12 @@ func AddOrUpdateDar(ctx context.Context, config *assistantconfig.Config, uri str
 	if err != nil {
 		return err
 	}
+	if semverRange != "" {
+		_, ref.Reference, err = ocilister.ResolveRange(ctx, client, ref.Repository, semverRange)
+		if err != nil {
+			return err
+		}
+	}
 
 	// Resolve to sha256
 	resolvedDigest, manifest, err := ocilister.FetchManifest(ctx, client, ref)
@@ -141,12 +152,13 @@ func AddOrUpdateDar(ctx context.Context, config *assistantconfig.Config, uri str
 	yamlTarget.LineComment = manifest.Annotations[v1.AnnotationVersion]
 	resolvedUri := uri + "@" + resolvedDigest.String()
 
-	parsedUrl, err := url.Parse(resolvedUri)
+	parsedUrl, err := url.Parse(withoutRange + "@" + resolvedDigest.String())
 	if err != nil {
 		return err
 	}
 	parsedDarDep := &damlpackage.ParsedDarDependency{
 		FullUrl: parsedUrl,
+		Range:   semverRange,
 		Location: &damlpackage.ArtifactLocation{
 			Insecure: insecure,
 		},
@@ -159,6 +171,9 @@ func AddOrUpdateDar(ctx context.Context, config *assistantconfig.Config, uri str
 	if err := yamledit.EditYaml(yamlTarget, resolvedUri); err != nil {
 		return err
 	}
+	if err := config.KnownProjects.Add(filepath.Dir(yamlTarget.YamlFilePath)); err != nil {
+		return err
+	}
 
 	fmt.Printf("Successfully installed and added dar %q to %q\n", resolvedUri, yamlTarget.YamlFilePath)
 	return nil
@@ -7,26 +7,34 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
 	"unicode"
 	"unicode/utf8"
 
 	"daml.com/x/assistant/cmd/dpm/cmd/add"
+	"daml.com/x/assistant/cmd/dpm/cmd/cache"
+	"daml.com/x/assistant/cmd/dpm/cmd/outdated"
 	"daml.com/x/assistant/cmd/dpm/cmd/publish"
 	"daml.com/x/assistant/cmd/dpm/cmd/tags"
+	"daml.com/x/assistant/cmd/dpm/cmd/tree"
 	"daml.com/x/assistant/cmd/dpm/cmd/uninstall"
 	"daml.com/x/assistant/cmd/dpm/cmd/update"
+	"daml.com/x/assistant/cmd/dpm/cmd/why"
 
 	"daml.com/x/assistant/cmd/dpm/cmd/bootstrap"
 	componentCmd "daml.com/x/assistant/cmd/dpm/cmd/component"
 	"daml.com/x/assistant/cmd/dpm/cmd/install"
+	"daml.com/x/assistant/cmd/dpm/cmd/remove"
 	"daml.com/x/assistant/cmd/dpm/cmd/repo"
 	"daml.com/x/assistant/cmd/dpm/cmd/resolve"
 	"daml.com/x/assistant/cmd/dpm/cmd/versions"
 	"daml.com/x/assistant/pkg/assistant"
 	"daml.com/x/assistant/pkg/assistantconfig"
+	"daml.com/x/assistant/pkg/assistantconfig/assistantremote"
 	"daml.com/x/assistant/pkg/assistantversion"
 	"daml.com/x/assistant/pkg/builtincommand"
 	"daml.com/x/assistant/pkg/logging"
+	"daml.com/x/assistant/pkg/progress"
 	"github.com/goccy/go-yaml"
 	"github.com/samber/lo"
 	"github.com/spf13/cobra"
@@ -63,14 +71,35 @@ func RootCmd(ctx context.Context, da *assistant.DamlAssistant) (*cobra.Command,
 		return nil, err
 	}
 
+	// set up from the env var already, as resolving the sdk commands below might pull components
+	progressMode, err := progress.ParseMode(os.Getenv(assistantconfig.ProgressEnvVar))
+	if err != nil {
+		return nil, err
+	}
+	progress.SetDefault(progress.New(progressMode, os.Stderr))
+	cmd.PersistentFlags().String("progress", string(progressMode), fmt.Sprintf("how to report the progress of pulls and pushes: %v", progress.Modes))
+	cmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		f := cmd.Flags().Lookup("progress")
+		if f == nil || !f.Changed {
+			return nil
+		}
+		mode, err := progress.ParseMode(f.Value.String())
+		if err != nil {
+			return err
+		}
+		progress.SetDefault(progress.New(mode, cmd.ErrOrStderr()))
+		return nil
+	}
+
 	config, err := assistantconfig.Get()
 	if err != nil {
 		return nil, err
 	}
-	if err := config.EnsureDirs(); err != nil {
+	assistantremote.Configure(config)
+	if err := config.EnsureDirs(ctx); err != nil {
 		return nil, err
 	}
-	if lo.Contains(da.OsArgs, cobra.ShellCompRequestCmd) || lo.Contains(da.OsArgs, cobra.ShellCompNoDescRequestCmd) {
+	if isCompletionRequest(da.OsArgs) {
 		config.AutoInstall = false // auto-install is normally disabled by default, but be explicit about it in this case still.
 	}
 
@@ -82,14 +111,20 @@ func RootCmd(ctx context.Context, da *assistant.DamlAssistant) (*cobra.Command,
 		setCmdMetaGroup(repo.Cmd(config)),
 		setCmdMetaGroup(resolve.Cmd(config)),
 		setCmdMetaGroup(update.Cmd(config)),
+		setCmdMetaGroup(outdated.Cmd(config)),
+		setCmdMetaGroup(tree.Cmd(config)),
+		setCmdMetaGroup(why.Cmd(config)),
 		setCmdMetaGroup(publish.Cmd()),
 		setCmdMetaGroup(tags.Cmd(config)),
 		setCmdMetaGroup(add.Cmd(config)),
+		setCmdMetaGroup(remove.Cmd(config)),
+		setCmdMetaGroup(cache.Cmd(config)),
 		componentCmd.Cmd(config),
 	)
 
+	// completing only needs the commands, like listing them does
 	resolutionType := lo.Ternary(
-		isHelp(da.OsArgs),
+		isHelp(da.OsArgs) || isCompletionRequest(da.OsArgs),
 		assistant.ShallowResolution,
 		assistant.DeepResolution,
 	)
@@ -143,3 +178,7 @@ func isHelp(osArgs []string) bool {
 	return len(osArgs) == 1 || (len(osArgs) == 2 &&
 		lo.Contains([]string{"--help", "-h", "help"}, osArgs[1]))
 }
+
+func isCompletionRequest(osArgs []string) bool {
+	return lo.Contains(osArgs, cobra.ShellCompRequestCmd) || lo.Contains(osArgs, cobra.ShellCompNoDescRequestCmd)
+}
@@ -4,10 +4,12 @@
 package cmd
 
 import (
+	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
 
@@ -792,6 +794,33 @@ func createStdTestRootCmd(t *testing.T, args ...string) (rootCmd *cobra.Command)
 	return
 }
 
+// activateLocalComponent activates a daml package whose only component is a local one named name, at ./<name>,
+// and writes that component (see writeLocalComponent). It returns the component's dir
+func activateLocalComponent(t *testing.T, name string, files map[string]string, spec string) string {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses shell scripts")
+	}
+	t.Setenv(assistantconfig.DpmHomeEnvVar, t.TempDir())
+
+	packageDir := testutil.ActivateDamlYamlForTest(t, fmt.Sprintf("name: foo\nversion: 1.0.0\ncomponents:\n  - name: %s\n    path: ./%s\n", name, name))
+	componentDir := filepath.Join(packageDir, name)
+	writeLocalComponent(t, componentDir, files, spec)
+	return componentDir
+}
+
+// writeLocalComponent writes a component to dir, with the given executable files (by their slash-separated paths, e.g. "bin/tool")
+// and a component.yaml with the given spec
+func writeLocalComponent(t *testing.T, dir string, files map[string]string, spec string) {
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0o755))
+		require.NoError(t, os.WriteFile(p, []byte(content), 0o755))
+	}
+	manifest := "apiVersion: digitalasset.com/v1\nkind: Component\nspec:\n" + spec
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "component.yaml"), []byte(manifest), 0o666))
+}
+
 func installFloatySdk(t *testing.T, version string, floatyTag string) {
 	ctx := testutil.Context(t)
 	_, reg := testutil.StartRegistry(t)
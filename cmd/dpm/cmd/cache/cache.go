@@ -0,0 +1,27 @@
+// Copyright (c) 2017-2026 Digital Asset (Switzerland) GmbH and/or its affiliates. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package cache
+
+import (
+	"daml.com/x/assistant/cmd/dpm/cmd/cache/gc"
+	"daml.com/x/assistant/cmd/dpm/cmd/cache/ls"
+	"daml.com/x/assistant/cmd/dpm/cmd/cache/rm"
+	"daml.com/x/assistant/cmd/dpm/cmd/cache/verify"
+	"daml.com/x/assistant/pkg/assistantconfig"
+	"daml.com/x/assistant/pkg/builtincommand"
+	"github.com/spf13/cobra"
+)
+
+func Cmd(config *assistantconfig.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   string(builtincommand.Cache),
+		Short: "manage dpm's cache of components, dars and OCI blobs",
+	}
+
+	cmd.AddCommand(gc.Cmd(config))
+	cmd.AddCommand(ls.Cmd(config))
+	cmd.AddCommand(verify.Cmd(config))
+	cmd.AddCommand(rm.Cmd(config))
+	return cmd
+}
@@ -0,0 +1,56 @@
+// Copyright (c) 2017-2026 Digital Asset (Switzerland) GmbH and/or its affiliates. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package gc
+
+import (
+	"daml.com/x/assistant/pkg/assistantconfig"
+	"daml.com/x/assistant/pkg/cachegc"
+	"daml.com/x/assistant/pkg/utils"
+	"github.com/samber/lo"
+	"github.com/spf13/cobra"
+)
+
+func Cmd(config *assistantconfig.Config) *cobra.Command {
+	var dryRun bool
+	var lockfiles []string
+
+	cmd := &cobra.Command{
+		Use:   "gc",
+		Short: "delete cached components, dars and blobs that are no longer in use",
+		Long: `Delete cached components, dars and OCI blobs that aren't used by any of
+  - the installed SDK versions
+  - the current (single or multi-package) project
+  - the projects dpm has previously installed components or dars for
+  - the lockfiles passed via --lockfile`,
+		Example: "dpm cache gc --dry-run",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+
+			report, err := cachegc.New(config).Collect(cmd.Context(), cachegc.Opts{
+				DryRun:    dryRun,
+				Lockfiles: lockfiles,
+			})
+			if err != nil {
+				return err
+			}
+
+			verb := lo.Ternary(dryRun, "would remove", "removed")
+			for _, item := range report.Items {
+				cmd.Printf("%s %s %s (%s)\n", verb, item.Kind, item.Path, utils.HumanSize(item.Size))
+			}
+
+			if dryRun {
+				cmd.Printf("%d entries, %s would be reclaimed\n", len(report.Items), utils.HumanSize(report.ReclaimedBytes))
+			} else {
+				cmd.Printf("%d entries removed, %s reclaimed\n", len(report.Items), utils.HumanSize(report.ReclaimedBytes))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "only report what would be deleted, and how many bytes would be reclaimed")
+	cmd.Flags().StringSliceVar(&lockfiles, "lockfile", nil, "path to a dpm.lock or multi-package.lock whose dependencies should be kept (can be repeated)")
+	return cmd
+}
@@ -0,0 +1,74 @@
+// Copyright (c) 2017-2026 Digital Asset (Switzerland) GmbH and/or its affiliates. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package ls
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"daml.com/x/assistant/pkg/assistantconfig"
+	"daml.com/x/assistant/pkg/cacheinspect"
+	"daml.com/x/assistant/pkg/utils"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/lipgloss/table"
+	"github.com/samber/lo"
+	"github.com/spf13/cobra"
+)
+
+func Cmd(config *assistantconfig.Config) *cobra.Command {
+	var output string
+	var lockfiles []string
+
+	cmd := &cobra.Command{
+		Use:     "ls",
+		Short:   "list cached components, and what references them",
+		Example: "dpm cache ls -o json",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+
+			entries, err := cacheinspect.List(cmd.Context(), config, lockfiles)
+			if err != nil {
+				return err
+			}
+
+			switch output {
+			case "table":
+				cmd.Println(entriesTable(entries))
+			case "json":
+				data, err := json.MarshalIndent(lo.Ternary(entries == nil, []*cacheinspect.Entry{}, entries), "", "    ")
+				if err != nil {
+					return err
+				}
+				cmd.Println(string(data))
+			default:
+				return fmt.Errorf("output format not supported: %s", output)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "table", "output format: json, table")
+	cmd.Flags().StringSliceVar(&lockfiles, "lockfile", nil, "path to an additional dpm.lock or multi-package.lock to consider when listing references (can be repeated)")
+	return cmd
+}
+
+func entriesTable(entries []*cacheinspect.Entry) string {
+	return table.New().
+		Border(lipgloss.HiddenBorder()).
+		BorderTop(false).
+		BorderBottom(false).
+		Headers("NAME", "VERSION", "DIGEST", "SIZE", "REFERENCED BY").
+		Rows(lo.Map(entries, func(e *cacheinspect.Entry, _ int) []string {
+			return []string{
+				e.Name,
+				e.Version,
+				lo.Ternary(e.Digest == "", "-", e.Digest),
+				utils.HumanSize(e.Size),
+				lo.Ternary(len(e.ReferencedBy) == 0, "-", strings.Join(e.ReferencedBy, "\n")),
+			}
+		})...).
+		String()
+}
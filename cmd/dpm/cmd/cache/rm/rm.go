@@ -0,0 +1,68 @@
+// Copyright (c) 2017-2026 Digital Asset (Switzerland) GmbH and/or its affiliates. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package rm
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"daml.com/x/assistant/pkg/assistantconfig"
+	"daml.com/x/assistant/pkg/cacheinspect"
+	"daml.com/x/assistant/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+func Cmd(config *assistantconfig.Config) *cobra.Command {
+	var output string
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:   "rm <name>[:<version>]",
+		Short: "remove cached components",
+		Long: `Remove all cached versions of the named component, or only the given version.
+Components that are still referenced by an installed sdk or a known project are only removed with --force`,
+		Example: "dpm cache rm meep:1.2.3",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if output != "table" && output != "json" {
+				return fmt.Errorf("output format not supported: %s", output)
+			}
+			cmd.SilenceUsage = true
+
+			name, version := parseNameVersion(args[0])
+			removed, err := cacheinspect.Remove(cmd.Context(), config, name, version, force)
+			if err != nil {
+				return err
+			}
+
+			if output == "json" {
+				data, err := json.MarshalIndent(removed, "", "    ")
+				if err != nil {
+					return err
+				}
+				cmd.Println(string(data))
+				return nil
+			}
+			for _, e := range removed {
+				cmd.Printf("removed %s:%s %s (%s)\n", e.Name, e.Version, e.Path, utils.HumanSize(e.Size))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "table", "output format: json, table")
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "remove the component even if it's still referenced")
+	return cmd
+}
+
+// parseNameVersion splits "<name>[:<version>]". Names may contain a registry host with a port,
+// so only a ':' after the last '/' separates the version
+func parseNameVersion(s string) (name, version string) {
+	i := strings.LastIndex(s, ":")
+	if i < 0 || i < strings.LastIndex(s, "/") {
+		return s, ""
+	}
+	return s[:i], s[i+1:]
+}
@@ -0,0 +1,68 @@
+// Copyright (c) 2017-2026 Digital Asset (Switzerland) GmbH and/or its affiliates. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package verify
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"daml.com/x/assistant/pkg/assistantconfig"
+	"daml.com/x/assistant/pkg/cacheinspect"
+	"github.com/samber/lo"
+	"github.com/spf13/cobra"
+)
+
+var ErrCacheCorrupt = errors.New("the cache failed verification. Remove the affected entries with 'dpm cache rm' and re-install them")
+
+func Cmd(config *assistantconfig.Config) *cobra.Command {
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   "verify",
+		Short: "recompute the digests of cached components and OCI blobs, and check them against the cache index",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+
+			results, err := cacheinspect.Verify(cmd.Context(), config)
+			if err != nil {
+				return err
+			}
+
+			switch output {
+			case "table":
+				for _, r := range results {
+					subject := lo.Ternary(r.Kind == cacheinspect.VerifyComponent, fmt.Sprintf("%s:%s (%s)", r.Name, r.Version, r.Digest), r.Digest)
+					if r.OK {
+						cmd.Printf("ok      %s %s\n", r.Kind, subject)
+						continue
+					}
+					cmd.Printf("FAILED  %s %s\n", r.Kind, subject)
+					for _, p := range r.Problems {
+						cmd.Printf("        - %s\n", p)
+					}
+				}
+			case "json":
+				data, err := json.MarshalIndent(lo.Ternary(results == nil, []*cacheinspect.VerifyResult{}, results), "", "    ")
+				if err != nil {
+					return err
+				}
+				cmd.Println(string(data))
+			default:
+				return fmt.Errorf("output format not supported: %s", output)
+			}
+
+			failed := lo.Filter(results, func(r *cacheinspect.VerifyResult, _ int) bool { return !r.OK })
+			if len(failed) > 0 {
+				return fmt.Errorf("%w: %s", ErrCacheCorrupt, strings.Join(lo.Map(failed, func(r *cacheinspect.VerifyResult, _ int) string { return r.Path }), ", "))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "table", "output format: json, table")
+	return cmd
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"io"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (suite *MainSuite) TestComponentCommandCompletion() {
+	t := suite.T()
+	// completes with the args it's asked to complete, as a cobra command would
+	tool := `#!/bin/sh
+[ "$1" = "__complete" ] || exit 1
+echo "args:$*"
+printf 'start\tstart the tool\n'
+echo "tool-home:$TOOL_HOME"
+echo ":4"
+`
+	componentDir := activateLocalComponent(t, "tool", map[string]string{"bin/tool": tool}, `  commands:
+    - path: bin/tool
+      name: static
+      completion:
+        flags: [--config, --port]
+        subcommands: [daemon, sandbox]
+    - path: bin/tool
+      name: proxied
+      exec-args: [run]
+      env:
+        TOOL_HOME: ${component:tool}
+      completion:
+        cobra: true
+    - path: bin/tool
+      name: plain
+`)
+
+	complete := func(args ...string) []string {
+		cmd, r, w := createTestRootCmd(t, append([]string{cobra.ShellCompRequestCmd}, args...)...)
+		require.NoError(t, cmd.Execute())
+		require.NoError(t, w.Close())
+		output, err := io.ReadAll(r)
+		require.NoError(t, err)
+		lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+		// drop the "Completion ended with directive: ..." cobra logs
+		return lines[:len(lines)-1]
+	}
+
+	assert.Equal(t, []string{"sandbox", ":4"}, complete("static", "s"))
+	// alongside dpm's own persistent flags
+	assert.Subset(t, complete("static", "sandbox", "--"), []string{"--config", "--port", ":4"})
+	assert.Subset(t, complete("static", "--po"), []string{"--port", ":4"})
+	// e.g. a file, after a subcommand
+	assert.Equal(t, []string{":0"}, complete("static", "sandbox", ""))
+
+	assert.Equal(t, []string{
+		"args:__complete run --config ./x st",
+		"start\tstart the tool",
+		"tool-home:" + componentDir,
+		":4",
+	}, complete("proxied", "--config", "./x", "st"))
+
+	assert.Equal(t, []string{":0"}, complete("plain", ""))
+}
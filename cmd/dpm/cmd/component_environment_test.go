@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"daml.com/x/assistant/pkg/assistantconfig"
+	"daml.com/x/assistant/pkg/testutil"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (suite *MainSuite) TestComponentCommandEnvironment() {
+	t := suite.T()
+	componentDir := activateLocalComponent(t, "tool", map[string]string{"bin/tool": "#!/bin/sh\n"}, `  commands:
+    - path: bin/tool
+      name: tool
+      env:
+        TOOL_HOME: ${component:tool}
+      path-prepends: [bin]
+      working-dir: component
+`)
+
+	wasCalled := atomic.Bool{}
+	assertCmd := func(cmd *exec.Cmd) {
+		wasCalled.Store(true)
+		assert.Contains(t, cmd.Env, "TOOL_HOME="+componentDir)
+		// the last PATH wins
+		kv, _ := lo.Last(lo.Filter(cmd.Env, func(kv string, _ int) bool { return strings.HasPrefix(kv, "PATH=") }))
+		assert.True(t, strings.HasPrefix(kv, "PATH="+filepath.Join(componentDir, "bin")+string(os.PathListSeparator)), kv)
+		assert.Equal(t, componentDir, cmd.Dir)
+	}
+	_ = createStdTestRootCmdWithPreRunHook(t, assertCmd, "tool").Execute()
+	assert.True(t, wasCalled.Load())
+}
+
+func (suite *MainSuite) TestJarCommandJava() {
+	t := suite.T()
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script java")
+	}
+	t.Setenv(assistantconfig.DpmHomeEnvVar, t.TempDir())
+	t.Setenv("JAVA_HOME", "/usr/lib/jvm/some-other-jdk")
+
+	packageDir := testutil.ActivateDamlYamlForTest(t, `name: foo
+version: 1.0.0
+components:
+  - name: tool
+    path: ./tool
+  - name: jdk
+    path: ./jdk
+`)
+	toolDir, jdkDir := filepath.Join(packageDir, "tool"), filepath.Join(packageDir, "jdk")
+	writeLocalComponent(t, toolDir, map[string]string{"tool.jar": ""}, `  jar-commands:
+    - path: tool.jar
+      name: tool
+      java-version: ">=21"
+`)
+	writeLocalComponent(t, jdkDir, map[string]string{"bin/java": "#!/bin/sh\n"}, "  commands: []\n")
+	writeRelease := func(version string) {
+		require.NoError(t, os.WriteFile(filepath.Join(jdkDir, "release"), []byte(`JAVA_VERSION="`+version+`"`+"\n"), 0o644))
+	}
+
+	t.Run("incompatible", func(t *testing.T) {
+		writeRelease("17.0.2")
+		err := createStdTestRootCmdWithPreRunHook(t, func(*exec.Cmd) {
+			t.Error("an incompatible java shouldn't be run")
+		}, "tool").Execute()
+		assert.ErrorContains(t, err, `is version 17.0.2, which doesn't satisfy ">=21"`)
+	})
+
+	t.Run("compatible", func(t *testing.T) {
+		writeRelease("21.0.4")
+		wasCalled := atomic.Bool{}
+		_ = createStdTestRootCmdWithPreRunHook(t, func(cmd *exec.Cmd) {
+			wasCalled.Store(true)
+			assert.Equal(t, filepath.Join(jdkDir, "bin", "java"), cmd.Path)
+			// the last JAVA_HOME wins
+			kv, _ := lo.Last(lo.Filter(cmd.Env, func(kv string, _ int) bool { return strings.HasPrefix(kv, "JAVA_HOME=") }))
+			assert.Equal(t, "JAVA_HOME="+jdkDir, kv)
+		}, "tool").Execute()
+		assert.True(t, wasCalled.Load())
+	})
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"io"
+	"os/exec"
+	"path/filepath"
+	"sync/atomic"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (suite *MainSuite) TestComponentCommandGroups() {
+	t := suite.T()
+	componentDir := activateLocalComponent(t, "canton", map[string]string{"bin/canton": "#!/bin/sh\n"}, `  groups:
+    - name: canton
+      desc: run and interact with canton
+    - name: admin
+      desc: administer canton nodes
+      group: canton
+  commands:
+    - path: bin/canton
+      name: sandbox
+      desc: run a canton sandbox
+      group: canton
+      exec-args: [sandbox]
+    - path: bin/canton
+      name: prune
+      desc: prune a node
+      group: admin
+      exec-args: [prune]
+`)
+
+	help := func(args ...string) string {
+		cmd, r, w := createTestRootCmd(t, args...)
+		require.NoError(t, cmd.Execute())
+		require.NoError(t, w.Close())
+		output, err := io.ReadAll(r)
+		require.NoError(t, err)
+		return string(output)
+	}
+
+	output := help("--help")
+	assert.Regexp(t, `canton\s+Run and interact with canton`, output)
+	assert.NotContains(t, output, "sandbox")
+
+	output = help("canton", "--help")
+	assert.Regexp(t, `sandbox\s+run a canton sandbox`, output)
+	assert.Regexp(t, `admin\s+administer canton nodes`, output)
+	assert.Contains(t, output, "dpm canton [command]")
+
+	assert.Regexp(t, `prune\s+prune a node`, help("canton", "admin"))
+
+	wasCalled := atomic.Bool{}
+	_ = createStdTestRootCmdWithPreRunHook(t, func(cmd *exec.Cmd) {
+		wasCalled.Store(true)
+		assert.Equal(t, []string{filepath.Join(componentDir, "bin", "canton"), "prune", "--force"}, cmd.Args)
+	}, "canton", "admin", "prune", "--force").Execute()
+	assert.True(t, wasCalled.Load())
+
+	assert.ErrorContains(t, createStdTestRootCmd(t, "canton", "nope").Execute(), `unknown command "nope" for "dpm canton"`)
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"daml.com/x/assistant/pkg/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (suite *MainSuite) TestDpmRemoveDarCommand() {
+	t := suite.T()
+
+	damlYaml := `sdk-version: 3.4.0
+dependencies:
+  - daml-prim
+  # pinned for the demo
+  - oci://example.com/dars/foo:1.2.3@sha256:12d74505ebae3959746e8a2f5ab68b942a5580634dda7ea1a586874e07b52eb9 # 1.2.3
+  - ./libs/bar.dar
+data-dependencies:
+  - oci://example.com/dars/foo:1.2.3
+`
+
+	t.Run("by name", func(t *testing.T) {
+		projectDir := testutil.ActivateDamlYamlForTest(t, damlYaml)
+
+		require.NoError(t, createStdTestRootCmd(t, "remove", "dar", "foo").Execute())
+
+		newContent, err := os.ReadFile(filepath.Join(projectDir, "daml.yaml"))
+		require.NoError(t, err)
+		assert.Equal(t, `sdk-version: 3.4.0
+dependencies:
+  - daml-prim
+  - ./libs/bar.dar
+`, string(newContent))
+	})
+
+	t.Run("by uri", func(t *testing.T) {
+		projectDir := testutil.ActivateDamlYamlForTest(t, damlYaml)
+
+		require.NoError(t, createStdTestRootCmd(t, "remove", "dar", "bar.dar").Execute())
+		require.NoError(t, createStdTestRootCmd(t, "remove", "dar", "oci://example.com/dars/foo:1.0.0").Execute())
+
+		newContent, err := os.ReadFile(filepath.Join(projectDir, "daml.yaml"))
+		require.NoError(t, err)
+		assert.Equal(t, `sdk-version: 3.4.0
+dependencies:
+  - daml-prim
+`, string(newContent))
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		testutil.ActivateDamlYamlForTest(t, damlYaml)
+		assert.ErrorContains(t, createStdTestRootCmd(t, "remove", "dar", "baz").Execute(), `no dar matching "baz"`)
+	})
+}
+
+func (suite *MainSuite) TestDpmRemoveComponentCommand() {
+	t := suite.T()
+
+	t.Run("multi-package", func(t *testing.T) {
+		projectDir := testutil.ActivateMultiPackageYamlForTest(t, `packages: []
+components:
+  - damlc:1.2.3
+  # some component
+  - oci://example.com/some/component:1.2.3 # 1.2.3
+  - name: local
+    path: ./local
+`)
+
+		require.NoError(t, createStdTestRootCmd(t, "remove", "component", "oci://example.com/some/component:4.5.6").Execute())
+		require.NoError(t, createStdTestRootCmd(t, "remove", "component", "local").Execute())
+
+		newContent, err := os.ReadFile(filepath.Join(projectDir, "multi-package.yaml"))
+		require.NoError(t, err)
+		assert.Equal(t, `packages: []
+components:
+  - damlc:1.2.3
+`, string(newContent))
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		testutil.ActivateDamlYamlForTest(t, `components:
+  - damlc:1.2.3
+`)
+		assert.ErrorContains(t, createStdTestRootCmd(t, "remove", "component", "daml-script").Execute(), "components: damlc")
+	})
+}
@@ -119,6 +119,40 @@ components:
 	})
 }
 
+func (suite *MainSuite) TestDpmUpdateCommandForRangeComponents() {
+	t := suite.T()
+
+	c := testutil.MkConfig(t)
+	ctx := testutil.Context(t)
+	_, reg := testutil.StartRegistry(t)
+	testutil.PushComponent(t, ctx, reg, "meep", "1.2.3", testutil.TestdataPath(t, "meepy-component", testutil.OS))
+	testutil.PushComponent(t, ctx, reg, "meep", "2.0.0", testutil.TestdataPath(t, "meepy-component", testutil.OS))
+
+	projectDir := testutil.ActivateDamlYamlForTest(t, `
+components:
+  - meep:^1.0
+`)
+
+	t.Run("update within the range", func(t *testing.T) {
+		require.NoError(t, createStdTestRootCmd(t, "update").Execute())
+
+		pkg, err := damlpackage.Read(filepath.Join(projectDir, "daml.yaml"))
+		require.NoError(t, err)
+		assert.Equal(t, "meep:^1.0", *pkg.ComponentsList[0].StringBased)
+		assert.DirExists(t, filepath.Join(c.CachePath, "components", "meep", "1.2.3"))
+		assert.NoDirExists(t, filepath.Join(c.CachePath, "components", "meep", "2.0.0"))
+	})
+
+	t.Run("--latest moves the range up", func(t *testing.T) {
+		require.NoError(t, createStdTestRootCmd(t, "update", "--latest").Execute())
+
+		pkg, err := damlpackage.Read(filepath.Join(projectDir, "daml.yaml"))
+		require.NoError(t, err)
+		assert.Equal(t, "meep:^2.0.0", *pkg.ComponentsList[0].StringBased)
+		assert.DirExists(t, filepath.Join(c.CachePath, "components", "meep", "2.0.0"))
+	})
+}
+
 func (suite *MainSuite) TestDpmUpdateCommandForFloatyDars() {
 	t := suite.T()
 
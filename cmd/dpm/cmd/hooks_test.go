@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"daml.com/x/assistant/pkg/assistant"
+	"daml.com/x/assistant/pkg/assistantconfig"
+	"daml.com/x/assistant/pkg/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (suite *MainSuite) TestHooks() {
+	t := suite.T()
+	if runtime.GOOS == "windows" {
+		t.Skip("uses shell scripts")
+	}
+	t.Setenv(assistantconfig.DpmHomeEnvVar, t.TempDir())
+
+	multiPackageDir := t.TempDir()
+	packageDir := filepath.Join(multiPackageDir, "pkg")
+	componentDir := filepath.Join(packageDir, "tool")
+	logPath := filepath.Join(packageDir, "hooks.log")
+	writeLocalComponent(t, componentDir, map[string]string{"bin/tool": `#!/bin/sh
+echo "tool $*" >> "$DAML_PACKAGE/hooks.log"
+exit ${TOOL_EXIT_CODE:-0}
+`}, `  commands:
+    - path: bin/tool
+      name: tool
+      env:
+        TOOL_HOME: ${component:tool}
+`)
+
+	// hooks aren't env-expanded when read, so they can use the injected env
+	require.NoError(t, os.WriteFile(filepath.Join(multiPackageDir, "multi-package.yaml"), []byte(`packages: [./pkg]
+hooks:
+  pre-tool: echo "pre multi $(basename $PWD) $TOOL_HOME" >> "$DAML_PACKAGE/hooks.log"
+  post-tool: echo "post multi" >> "$DAML_PACKAGE/hooks.log"
+`), 0o666))
+	require.NoError(t, os.WriteFile(filepath.Join(packageDir, "daml.yaml"), []byte(`name: foo
+version: 1.0.0
+components:
+  - name: tool
+    path: ./tool
+hooks:
+  pre-tool:
+    - echo "pre pkg" >> hooks.log
+    - test ! -e fail-pre
+  post-tool: echo "post pkg" >> "$DAML_PACKAGE/hooks.log"
+`), 0o666))
+	t.Chdir(packageDir)
+
+	run := func(t *testing.T, args ...string) (log []string, exitCode int, err error) {
+		require.NoError(t, os.RemoveAll(logPath))
+		da := assistant.DamlAssistant{
+			Stderr: os.Stderr,
+			Stdout: os.Stdout,
+			ExitFn: func(c int) { exitCode = c },
+			OsArgs: append([]string{DpmName}, args...),
+		}
+		cmd, err := RootCmd(testutil.Context(t), &da)
+		require.NoError(t, err)
+		err = cmd.Execute()
+
+		contents, _ := os.ReadFile(logPath)
+		return strings.Split(strings.TrimSpace(string(contents)), "\n"), exitCode, err
+	}
+
+	t.Run("around the command", func(t *testing.T) {
+		log, exitCode, err := run(t, "tool", "a", "b")
+		require.NoError(t, err)
+		assert.Equal(t, 0, exitCode)
+		assert.Equal(t, []string{
+			"pre multi " + filepath.Base(multiPackageDir) + " " + componentDir,
+			"pre pkg",
+			"tool a b",
+			"post pkg",
+			"post multi",
+		}, log)
+	})
+
+	t.Run("failing pre hook", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(filepath.Join(packageDir, "fail-pre"), nil, 0o644))
+		t.Cleanup(func() { _ = os.Remove(filepath.Join(packageDir, "fail-pre")) })
+
+		log, _, err := run(t, "tool")
+		assert.ErrorContains(t, err, `pre-tool hook "test ! -e fail-pre"`)
+		assert.NotContains(t, log, "tool ", "the command doesn't run")
+	})
+
+	t.Run("failing command", func(t *testing.T) {
+		t.Setenv("TOOL_EXIT_CODE", "3")
+		log, exitCode, err := run(t, "tool")
+		require.NoError(t, err)
+		assert.Equal(t, 3, exitCode)
+		assert.Equal(t, []string{"post pkg", "post multi"}, log[len(log)-2:], "post hooks still run")
+	})
+
+	t.Run("command that can't be started", func(t *testing.T) {
+		toolPath := filepath.Join(componentDir, "bin", "tool")
+		require.NoError(t, os.Chmod(toolPath, 0o644))
+		t.Cleanup(func() { _ = os.Chmod(toolPath, 0o755) })
+
+		log, _, err := run(t, "tool")
+		assert.ErrorContains(t, err, "failed to spawn")
+		assert.Equal(t, []string{"post pkg", "post multi"}, log[len(log)-2:], "post hooks still run")
+	})
+
+	t.Run("--no-hooks", func(t *testing.T) {
+		log, _, err := run(t, "tool", "a", "--no-hooks", "--", "--no-hooks")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"tool a -- --no-hooks"}, log)
+
+		t.Setenv(assistantconfig.NoHooksEnvVar, "true")
+		log, _, err = run(t, "tool")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"tool"}, log)
+	})
+}
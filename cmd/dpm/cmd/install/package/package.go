@@ -8,24 +8,33 @@ import (
 	"errors"
 	"fmt"
 	"net/url"
+	"os"
 	"path/filepath"
 	"strings"
 
+	"daml.com/x/assistant/cmd/dpm/cmd/resolve/resolutionerrors"
 	"daml.com/x/assistant/pkg/assembler"
 	"daml.com/x/assistant/pkg/assembler/assemblyplan"
+	"daml.com/x/assistant/pkg/dargraph"
+	"daml.com/x/assistant/pkg/gitsource"
+	"daml.com/x/assistant/pkg/httpdar"
 	"daml.com/x/assistant/pkg/multipackage"
 	"daml.com/x/assistant/pkg/ocilister"
 	"daml.com/x/assistant/pkg/ocipuller/remotepuller"
+	"daml.com/x/assistant/pkg/packagelock"
 	"daml.com/x/assistant/pkg/utils"
 	"daml.com/x/assistant/pkg/yamledit"
+	"github.com/opencontainers/go-digest"
 	v1 "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/samber/lo"
 
 	"daml.com/x/assistant/pkg/assistantconfig"
+	"daml.com/x/assistant/pkg/assistantconfig/assistantremote"
 	"daml.com/x/assistant/pkg/damlpackage"
 	"daml.com/x/assistant/pkg/sdkinstall"
 	"github.com/Masterminds/semver/v3"
 	"github.com/spf13/cobra"
+	"oras.land/oras-go/v2/registry"
 )
 
 func Cmd(config *assistantconfig.Config) *cobra.Command {
@@ -72,6 +81,9 @@ func InstallPackage(config *assistantconfig.Config, cmd *cobra.Command) error {
 			return err
 		}
 		pkgs := multiDamlPackage.AbsolutePackages()
+		if err := config.KnownProjects.Add(filepath.Dir(multiPackagePath)); err != nil {
+			return err
+		}
 
 		for _, p := range pkgs {
 			cmd.Printf("Processing package %q...\n", p)
@@ -92,6 +104,9 @@ func InstallPackage(config *assistantconfig.Config, cmd *cobra.Command) error {
 		if !isDamlPackage {
 			return fmt.Errorf("not in a package directory or subdirectory")
 		}
+		if err := config.KnownProjects.Add(filepath.Dir(damlPackagePath)); err != nil {
+			return err
+		}
 		if err := processDamlPackage(ctx, cmd, modifiedConfig, damlPackagePath); err != nil {
 			return err
 		}
@@ -137,6 +152,19 @@ func processDamlPackage(ctx context.Context, cmd *cobra.Command, config *assista
 
 func installDars(ctx context.Context, config *assistantconfig.Config, dars []*damlpackage.ParsedDarDependency, yamlTarget yamledit.YamlTarget) error {
 	for _, d := range dars {
+		if httpdar.IsHttp(d) {
+			if err := InstallHttpDar(ctx, config, d, filepath.Join(filepath.Dir(yamlTarget.YamlFilePath), assistantconfig.DpmLockFileName)); err != nil {
+				return err
+			}
+			continue
+		}
+		if d.Git != nil {
+			if err := InstallGitDar(ctx, config, d, filepath.Join(filepath.Dir(yamlTarget.YamlFilePath), assistantconfig.DpmLockFileName)); err != nil {
+				return err
+			}
+			continue
+		}
+
 		updatedDar, version, err := InstallDar(ctx, config, d)
 		if err != nil {
 			return err
@@ -158,15 +186,15 @@ func InstallDar(ctx context.Context, config *assistantconfig.Config, dar *damlpa
 	if dar.FullUrl.Scheme != "oci" {
 		return nil, "", nil
 	}
-	fmt.Printf("installing dar %q...\n", dar.FullUrl.String())
+	fmt.Printf("installing dar %q...\n", dar.StringWithAlias())
 
-	client, ref, err := dar.GetOciRemote()
+	client, ref, err := dar.GetResolvedOciRemote(ctx)
 	if err != nil {
 		return nil, "", err
 	}
 
 	if !assistantconfig.ShaPinningEnabled() && ocilister.IsFloaty(ref.Reference) {
-		return nil, "", fmt.Errorf("tag not allowed in %q: only strict semver OCI tags are supported currently", dar.FullUrl.String())
+		return nil, "", fmt.Errorf("tag not allowed in %q: only strict semver OCI tags are supported currently", dar.StringWithAlias())
 	}
 
 	if assistantconfig.ShaPinningEnabled() && !strings.Contains(dar.FullUrl.String(), "@sha256:") {
@@ -181,6 +209,7 @@ func InstallDar(ctx context.Context, config *assistantconfig.Config, dar *damlpa
 		}
 		updatedDar = &damlpackage.ParsedDarDependency{
 			FullUrl:       newUrl,
+			Range:         dar.Range,
 			Location:      dar.Location,
 			MainPackageId: dar.MainPackageId,
 			Index:         dar.Index,
@@ -194,22 +223,112 @@ func InstallDar(ctx context.Context, config *assistantconfig.Config, dar *damlpa
 		}
 	}
 
+	darDir, err := pullDar(ctx, config, client, ref)
+	if err != nil {
+		return nil, version, err
+	}
+
+	// the dars it was built against, as recorded in its dar.yaml
+	graph := dargraph.New()
+	dep, err := dargraph.NewDependency("oci://"+ref.String(), nil)
+	if err != nil {
+		return nil, version, err
+	}
+	if _, err := graph.Require(dep); err != nil {
+		return nil, version, err
+	}
+	pullTransitive := func(ctx context.Context, dep *dargraph.Dependency) (string, error) {
+		fmt.Printf("installing dar %q (required via %s)...\n", dep.URI, strings.Join(dep.RequiredBy, " -> "))
+		client, err := assistantremote.New(dep.Ref.Registry, config.RegistryAuthPath, config.Insecure || client.Insecure)
+		if err != nil {
+			return "", err
+		}
+		return pullDar(ctx, config, client, &dep.Ref)
+	}
+	if _, err := graph.Walk(ctx, darDir, []string{dar.StringWithAlias()}, pullTransitive); err != nil {
+		return nil, version, err
+	}
+
+	return updatedDar, version, nil
+}
+
+// InstallHttpDar downloads an http(s) dar, which must be pinned to a sha256 inline or in the lockfile at lockfilePath
+func InstallHttpDar(ctx context.Context, config *assistantconfig.Config, dar *damlpackage.ParsedDarDependency, lockfilePath string) error {
+	dgst := dar.Digest
+	if dgst == "" {
+		lock, err := packagelock.ReadPackageLock(lockfilePath)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if lock != nil {
+			if locked, ok := lock.FindDar(dar.FullUrl); ok {
+				dgst = locked.Digest
+			}
+		}
+	}
+	if dgst == "" {
+		return httpdar.UnpinnedError(dar)
+	}
+
+	fmt.Printf("installing dar %q...\n", dar.StringWithAlias())
+	_, err := httpdar.Pull(ctx, config, dar, digest.Digest(dgst))
+	return err
+}
+
+// InstallGitDar checks out a git dar at its rev, if that's a commit, or else at the commit it's locked to in the lockfile at lockfilePath.
+// Without a lockfile in use, the rev is checked out at the commit it currently resolves to
+func InstallGitDar(ctx context.Context, config *assistantconfig.Config, dar *damlpackage.ParsedDarDependency, lockfilePath string) error {
+	authPath := ""
+	if dar.Location != nil {
+		authPath = dar.Location.Auth
+	}
+
+	commit := ""
+	if dar.Git.IsCommit() {
+		commit = dar.Git.Rev
+	} else {
+		lock, err := packagelock.ReadPackageLock(lockfilePath)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if lock != nil {
+			if locked, ok := lock.FindDar(dar.FullUrl); ok {
+				commit = locked.Commit
+			}
+		}
+	}
+	if commit == "" && !assistantconfig.DpmLockfileEnabled() {
+		var err error
+		if commit, err = gitsource.Resolve(ctx, config, dar.Git, authPath); err != nil {
+			return err
+		}
+	}
+	if commit == "" {
+		return resolutionerrors.NewUnpinnedDarError(fmt.Errorf("dar %q isn't locked to a commit yet. Run 'dpm update' to lock it", dar.StringWithAlias()))
+	}
+
+	fmt.Printf("installing dar %q...\n", dar.StringWithAlias())
+	_, err := gitsource.Checkout(ctx, config, dar.Git, commit, authPath)
+	return err
+}
+
+// pullDar pulls the (digest-pinned) dar into its directory in the cache, unless it's already there
+func pullDar(ctx context.Context, config *assistantconfig.Config, client *assistantremote.Remote, ref *registry.Reference) (string, error) {
 	puller := remotepuller.New(config.OciLayoutCache, client)
 	darDir := config.CachePathForDar(ref)
 
 	ok, err := utils.DirExists(darDir)
 	if err != nil {
-		return nil, "", err
+		return "", err
 	}
 	if ok {
 		fmt.Println("Dar already installed.")
-		return updatedDar, version, nil
+		return darDir, nil
 	}
 	if _, err = puller.PullDarByFullPath(ctx, ref.Repository, ref.Reference, darDir); err != nil {
-		return nil, version, err
+		return "", err
 	}
-
-	return updatedDar, version, nil
+	return darDir, nil
 }
 
 func installMultiPackageYamlComponentsOnly(ctx context.Context, cmd *cobra.Command, config *assistantconfig.Config) error {
@@ -0,0 +1,93 @@
+// Copyright (c) 2017-2026 Digital Asset (Switzerland) GmbH and/or its affiliates. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package outdated
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"daml.com/x/assistant/pkg/assistantconfig"
+	"daml.com/x/assistant/pkg/builtincommand"
+	"daml.com/x/assistant/pkg/outdated"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/lipgloss/table"
+	"github.com/samber/lo"
+	"github.com/spf13/cobra"
+)
+
+var ErrUpdatesAvailable = errors.New("updates are available. Run 'dpm update' to apply them")
+
+func Cmd(config *assistantconfig.Config) *cobra.Command {
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   string(builtincommand.Outdated),
+		Short: "report the project's sdk-version, components and dars that have newer versions",
+		Long: `Report the sdk-version, components and dars of every package in the project, with their current (locked) version,
+the newest version matching the declared one, and the newest version overall.
+Nothing is modified. Exits with an error if updates are available`,
+		Example: "dpm outdated -o json",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			return Run(cmd, config, output)
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "table", "output format: json, table")
+	return cmd
+}
+
+// Run prints the outdated report, and returns ErrUpdatesAvailable if anything is outdated
+func Run(cmd *cobra.Command, config *assistantconfig.Config, output string) error {
+	if output != "table" && output != "json" {
+		return fmt.Errorf("output format not supported: %s", output)
+	}
+
+	entries, err := outdated.New(config).Check(cmd.Context())
+	if err != nil {
+		return err
+	}
+
+	switch output {
+	case "table":
+		cmd.Println(entriesTable(entries))
+	case "json":
+		data, err := json.MarshalIndent(lo.Ternary(entries == nil, []*outdated.Entry{}, entries), "", "    ")
+		if err != nil {
+			return err
+		}
+		cmd.Println(string(data))
+	}
+
+	if lo.SomeBy(entries, (*outdated.Entry).Outdated) {
+		return ErrUpdatesAvailable
+	}
+	return nil
+}
+
+func entriesTable(entries []*outdated.Entry) string {
+	orDash := func(s string) string { return lo.Ternary(s == "", "-", s) }
+	return table.New().
+		Border(lipgloss.HiddenBorder()).
+		BorderTop(false).
+		BorderBottom(false).
+		Headers("KIND", "NAME", "CURRENT", "WANTED", "LATEST", "SOURCE").
+		Rows(lo.Map(entries, func(e *outdated.Entry, _ int) []string {
+			current := orDash(e.Current)
+			if e.Outdated() {
+				current = lipgloss.NewStyle().Foreground(lipgloss.Color("3")).Render(current)
+			}
+			return []string{
+				string(e.Kind),
+				e.Name,
+				current,
+				orDash(e.Wanted),
+				orDash(e.Latest),
+				e.Source,
+			}
+		})...).
+		String()
+}
@@ -59,6 +59,7 @@ func Cmd() *cobra.Command {
 			cmd.SilenceUsage = true
 			publishDarConfig := &publishdar.DarConfig{
 				Dars:           c.Dars,
+				Dependencies:   c.Dependencies,
 				LicenseFile:    c.LicenseFile,
 				Name:           name,
 				Version:        version,
@@ -81,6 +82,7 @@ func Cmd() *cobra.Command {
 	cmd.Flags().BoolVar(&c.ExcludeLicense, "exclude-license", false, "FOR NON-PRODUCTION USE: disable license file requirement for DAR publishing")
 	cmd.Flags().StringArrayVarP(&c.Dars, "dar", "f", nil, `REQUIRED path to the dar file to publish`)
 	cmd.MarkFlagRequired(publishcmd.FileFlagName)
+	cmd.Flags().StringArrayVar(&c.Dependencies, "dependency", nil, "oci uri of a dar this one depends on, recorded (pinned to its digest) in the published dar.yaml. Can be repeated")
 
 	cmd.Flags().StringSliceVarP(&c.ExtraTags, "extra-tags", "t", []string{}, "publish extra tags besides the semver")
 
@@ -0,0 +1,115 @@
+package component
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"daml.com/x/assistant/pkg/assistantconfig"
+	"daml.com/x/assistant/pkg/componentlist"
+	"daml.com/x/assistant/pkg/damlpackage"
+	"daml.com/x/assistant/pkg/multipackage"
+	"daml.com/x/assistant/pkg/packagelock"
+	"daml.com/x/assistant/pkg/yamledit"
+	"github.com/samber/lo"
+	"github.com/spf13/cobra"
+)
+
+func Cmd(config *assistantconfig.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "component <name|uri>",
+		Short: "remove a component from project",
+		Long: `Remove a component from the project's daml.yaml (or else its multi-package.yaml).
+
+The component can be given by its name (e.g. 'damlc', or 'example.com/some/component' for oci://example.com/some/component:1.2.3),
+or by its uri.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			damlPackagePath, multiPackagePath, err := assistantconfig.GetDamlPackageOrMultiPackageAbsolutePath()
+			if err != nil {
+				return err
+			}
+			projectManifest := cmp.Or(damlPackagePath, multiPackagePath)
+
+			var components componentlist.ComponentList
+			if damlPackagePath != "" {
+				obj, err := damlpackage.Read(damlPackagePath)
+				if err != nil {
+					return err
+				}
+				components = obj.ComponentsList
+			} else {
+				obj, err := multipackage.Read(multiPackagePath)
+				if err != nil {
+					return err
+				}
+				components = obj.ComponentsList
+			}
+
+			indices := findComponent(components, args[0])
+			if len(indices) == 0 {
+				return fmt.Errorf("no component matching %q found in %q (components: %s)", args[0], projectManifest, strings.Join(componentNames(components), ", "))
+			}
+
+			// remove from the bottom up, so that the indices of the remaining matches stay put
+			slices.Reverse(indices)
+			for _, i := range indices {
+				err := yamledit.RemoveFromYaml(yamledit.YamlTarget{
+					YamlFilePath: projectManifest,
+					FieldName:    "components",
+					Index:        i,
+				})
+				if err != nil {
+					return err
+				}
+			}
+			fmt.Printf("Removed component %q from %q\n", args[0], projectManifest)
+
+			// drop the component from the lockfiles too
+			if assistantconfig.DpmLockfileEnabled() {
+				if _, err := packagelock.New(config, packagelock.Regular).EnsureLockfiles(ctx); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+// findComponent returns the indices of the entries that are the component with the given name or uri
+func findComponent(components componentlist.ComponentList, nameOrUri string) []int {
+	// a uri is matched by the name of the component it denotes
+	name := nameOrUri
+	if n, ok := entryName(&componentlist.ComponentEntry{StringBased: &nameOrUri}); ok {
+		name = n
+	}
+
+	var indices []int
+	for i, entry := range components {
+		if entry.StringBased != nil && *entry.StringBased == nameOrUri {
+			indices = append(indices, i)
+		} else if n, ok := entryName(entry); ok && (n == name || n == nameOrUri) {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
+
+func entryName(entry *componentlist.ComponentEntry) (string, bool) {
+	m, err := componentlist.ComponentList{entry}.ToMap(nil)
+	if err != nil || len(m) != 1 {
+		return "", false
+	}
+	return lo.Keys(m)[0], true
+}
+
+func componentNames(components componentlist.ComponentList) []string {
+	return lo.FilterMap(components, func(entry *componentlist.ComponentEntry, _ int) (string, bool) {
+		return entryName(entry)
+	})
+}
@@ -0,0 +1,200 @@
+package dar
+
+import (
+	"cmp"
+	"fmt"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"daml.com/x/assistant/pkg/assistantconfig"
+	"daml.com/x/assistant/pkg/damlpackage"
+	"daml.com/x/assistant/pkg/gitsource"
+	"daml.com/x/assistant/pkg/multipackage"
+	"daml.com/x/assistant/pkg/ocilister"
+	"daml.com/x/assistant/pkg/packagelock"
+	"daml.com/x/assistant/pkg/yamledit"
+	"github.com/samber/lo"
+	"github.com/spf13/cobra"
+	"oras.land/oras-go/v2/registry"
+)
+
+func Cmd(config *assistantconfig.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "dar <uri|name>",
+		Short: "remove a dar from the project's dependencies and data-dependencies",
+		Long: `Remove a dar from the project's dependencies and data-dependencies.
+
+The dar can be given by its uri (with or without its tag, range or digest), or by its name,
+e.g. 'foo' for oci://example.com/dars/foo:1.2.3.
+In a multi-package, it's removed from every package that depends on it.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			damlPackagePaths, err := damlPackagesToEdit()
+			if err != nil {
+				return err
+			}
+
+			removed := 0
+			for _, p := range damlPackagePaths {
+				n, err := removeDar(p, args[0])
+				if err != nil {
+					return err
+				}
+				removed += n
+			}
+			if removed == 0 {
+				return fmt.Errorf("no dar matching %q found in %s", args[0], strings.Join(damlPackagePaths, ", "))
+			}
+
+			// drop the dar from the lockfiles too
+			if assistantconfig.DpmLockfileEnabled() {
+				if _, err := packagelock.New(config, packagelock.Regular).EnsureLockfiles(ctx); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+// damlPackagesToEdit returns the daml.yaml of the current package, or else those of all the packages of the multi-package
+func damlPackagesToEdit() ([]string, error) {
+	damlPackagePath, ok, err := assistantconfig.GetDamlPackageAbsolutePath()
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		return []string{damlPackagePath}, nil
+	}
+
+	multiPackagePath, ok, err := assistantconfig.GetMultiPackageAbsolutePath()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("not in a (single-package or multi-package) project directory")
+	}
+	m, err := multipackage.Read(multiPackagePath)
+	if err != nil {
+		return nil, err
+	}
+	return lo.Map(m.AbsolutePackages(), func(dir string, _ int) string {
+		return filepath.Join(dir, assistantconfig.DamlPackageFilename)
+	}), nil
+}
+
+// removeDar removes the dars matching the given uri or name from the daml.yaml at damlPackagePath,
+// returning how many entries were removed
+func removeDar(damlPackagePath, uriOrName string) (int, error) {
+	p, err := damlpackage.Read(damlPackagePath)
+	if err != nil {
+		return 0, err
+	}
+
+	type match struct {
+		target yamledit.YamlTarget
+		value  string
+		// the same dar may be declared differently in dependencies and data-dependencies
+		name string
+	}
+	var matches []match
+	fields := []struct {
+		name   string
+		raw    []*damlpackage.RawDependency
+		parsed map[string]*damlpackage.ParsedDarDependency
+	}{
+		{"dependencies", p.Dependencies, p.ParsedDarDependencies.Dependencies},
+		{"data-dependencies", p.DataDependencies, p.ParsedDarDependencies.DataDependencies},
+	}
+	for _, field := range fields {
+		for i, raw := range field.raw {
+			value, err := raw.Value()
+			if err != nil {
+				return 0, err
+			}
+			dep := field.parsed[value]
+			if isDar(value, dep, uriOrName) {
+				matches = append(matches, match{
+					target: yamledit.YamlTarget{YamlFilePath: damlPackagePath, FieldName: field.name, Index: i},
+					value:  value,
+					name:   lo.TernaryF(dep == nil, func() string { return value }, func() string { return darName(dep) }),
+				})
+			}
+		}
+	}
+
+	names := lo.Uniq(lo.Map(matches, func(m match, _ int) string { return m.name }))
+	if len(names) > 1 {
+		return 0, fmt.Errorf("%q matches several dars in %q (%s): give the dar's full uri instead", uriOrName, damlPackagePath, strings.Join(names, ", "))
+	}
+
+	// remove from the bottom up, so that the indices of the remaining matches stay put
+	slices.SortFunc(matches, func(a, b match) int {
+		return cmp.Or(strings.Compare(a.target.FieldName, b.target.FieldName), b.target.Index-a.target.Index)
+	})
+	for _, m := range matches {
+		if err := yamledit.RemoveFromYaml(m.target); err != nil {
+			return 0, err
+		}
+		fmt.Printf("Removed dar %q from %s of %q\n", m.value, m.target.FieldName, damlPackagePath)
+	}
+	return len(matches), nil
+}
+
+// isDar whether the dependency (as declared in daml.yaml) is the dar with the given uri or name.
+// Like 'dpm why', a dar's name is its uri without any tag, range or digest, or the last segment of it
+func isDar(value string, dep *damlpackage.ParsedDarDependency, uriOrName string) bool {
+	if value == uriOrName {
+		return true
+	}
+	if dep == nil {
+		return false
+	}
+	if dep.Workspace != "" && dep.Workspace == uriOrName {
+		return true
+	}
+	name := darName(dep)
+	return name == uriOrName ||
+		name == normalize(uriOrName) ||
+		strings.TrimPrefix(name, "oci://") == uriOrName ||
+		path.Base(name) == uriOrName ||
+		strings.TrimSuffix(path.Base(name), ".dar") == uriOrName
+}
+
+func darName(dep *damlpackage.ParsedDarDependency) string {
+	if dep.Git != nil {
+		return strings.TrimSuffix(dep.Git.String(), "@"+dep.Git.Rev)
+	}
+	if dep.FullUrl.Scheme == "oci" {
+		return normalize(dep.FullUrl.String())
+	}
+	return dep.FullUrl.String()
+}
+
+// normalize strips the tag, range or digest off an oci, git or http(s) uri
+func normalize(uri string) string {
+	switch {
+	case strings.HasPrefix(uri, "oci://"):
+		withoutRange, _, _ := ocilister.SplitRange(uri)
+		ref, err := registry.ParseReference(strings.TrimPrefix(withoutRange, "oci://"))
+		if err != nil {
+			return uri
+		}
+		return "oci://" + ref.Registry + "/" + ref.Repository
+	case gitsource.IsGit(uri):
+		src, err := gitsource.Parse(uri)
+		if err != nil {
+			return uri
+		}
+		return strings.TrimSuffix(src.String(), "@"+src.Rev)
+	default:
+		withoutDigest, _, _ := strings.Cut(uri, "@sha256:")
+		return withoutDigest
+	}
+}
@@ -0,0 +1,20 @@
+package remove
+
+import (
+	"daml.com/x/assistant/cmd/dpm/cmd/remove/component"
+	"daml.com/x/assistant/cmd/dpm/cmd/remove/dar"
+	"daml.com/x/assistant/pkg/assistantconfig"
+	"daml.com/x/assistant/pkg/builtincommand"
+	"github.com/spf13/cobra"
+)
+
+func Cmd(config *assistantconfig.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   string(builtincommand.Remove),
+		Short: "Remove components and dars from project",
+	}
+
+	cmd.AddCommand(component.Cmd(config))
+	cmd.AddCommand(dar.Cmd(config))
+	return cmd
+}
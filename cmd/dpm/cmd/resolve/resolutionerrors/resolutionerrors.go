@@ -12,6 +12,17 @@ const (
 	UnknownError      = "UNKNOWN_ERROR"
 	OutdatedLockfile  = "OUTDATED_DPM_LOCK"
 	DarNotInstalled   = "DAR_NOT_INSTALLED"
+	// a lockfile entry's digest doesn't match the dar on disk, or what its tag resolves to in the registry
+	LockfileDigestMismatch = "LOCKFILE_DIGEST_MISMATCH"
+	// two dependency paths require different versions of the same dar repository
+	DarVersionConflict = "DAR_VERSION_CONFLICT"
+	// resolved dars embed different main package IDs under the same name and version, or the same package ID
+	// comes from different URIs (or doesn't match the main-package-id declared in daml.yaml)
+	PackageIdConflict = "PACKAGE_ID_CONFLICT"
+	// an http(s) dar dependency has no sha256, neither inline nor in the lockfile
+	UnpinnedDar = "UNPINNED_DAR"
+	// a workspace:<package> dependency names no package of the multi-package, or the packages depend on each other in a cycle
+	InvalidWorkspaceDependency = "INVALID_WORKSPACE_DEPENDENCY"
 )
 
 type ResolutionError struct {
@@ -100,6 +111,41 @@ func NewDarNotInstalled(cause error) *ResolutionError {
 	}
 }
 
+func NewLockfileDigestMismatchError(cause error) *ResolutionError {
+	return &ResolutionError{
+		Code:  LockfileDigestMismatch,
+		Cause: cause,
+	}
+}
+
+func NewDarVersionConflictError(cause error) *ResolutionError {
+	return &ResolutionError{
+		Code:  DarVersionConflict,
+		Cause: cause,
+	}
+}
+
+func NewPackageIdConflictError(cause error) *ResolutionError {
+	return &ResolutionError{
+		Code:  PackageIdConflict,
+		Cause: cause,
+	}
+}
+
+func NewUnpinnedDarError(cause error) *ResolutionError {
+	return &ResolutionError{
+		Code:  UnpinnedDar,
+		Cause: cause,
+	}
+}
+
+func NewInvalidWorkspaceDependencyError(cause error) *ResolutionError {
+	return &ResolutionError{
+		Code:  InvalidWorkspaceDependency,
+		Cause: cause,
+	}
+}
+
 func Standardize(err error) []*ResolutionError {
 	if err == nil {
 		return nil
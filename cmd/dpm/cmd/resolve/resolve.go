@@ -27,7 +27,10 @@ func getDeepResolutionOutput(ctx context.Context, config *assistantconfig.Config
 		return "", err
 	}
 
-	deepResolution, err := resolver.New(config, assembler.New(config, puller)).RunDeepResolution(ctx)
+	r := resolver.New(config, assembler.New(config, puller))
+	// unlike the resolution SDK commands run with, catch re-pushed tags too
+	r.VerifyRegistryDigests = true
+	deepResolution, err := r.RunDeepResolution(ctx)
 	if err != nil {
 		slog.ErrorContext(ctx, "failed to run deep resolution", "error", err)
 		return "", err
@@ -0,0 +1,85 @@
+// Copyright (c) 2017-2026 Digital Asset (Switzerland) GmbH and/or its affiliates. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package tree
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"daml.com/x/assistant/pkg/assembler"
+	"daml.com/x/assistant/pkg/assistantconfig"
+	"daml.com/x/assistant/pkg/builtincommand"
+	"daml.com/x/assistant/pkg/deptree"
+	"daml.com/x/assistant/pkg/ocipuller/remotepuller"
+	"github.com/charmbracelet/lipgloss/tree"
+	"github.com/spf13/cobra"
+)
+
+func Cmd(config *assistantconfig.Config) *cobra.Command {
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   string(builtincommand.Tree),
+		Short: "show the dependency tree of each package in the project",
+		Long: `Show the dependency tree of each package in the project: the sdk and its components,
+the component overrides from multi-package.yaml and daml.yaml (and which of them is in effect),
+and the dars with their resolved versions, cache paths and own dependencies`,
+		Example: "dpm tree -o json",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			if output != "text" && output != "json" {
+				return fmt.Errorf("output format not supported: %s", output)
+			}
+
+			roots, err := Build(cmd.Context(), config)
+			if err != nil {
+				return err
+			}
+
+			if output == "json" {
+				data, err := json.MarshalIndent(roots, "", "    ")
+				if err != nil {
+					return err
+				}
+				cmd.Println(string(data))
+				return nil
+			}
+			for _, r := range roots {
+				cmd.Println(Render(r))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "text", "output format: json, text")
+	return cmd
+}
+
+// Build returns the dependency tree of each package in scope
+func Build(ctx context.Context, config *assistantconfig.Config) ([]*deptree.Node, error) {
+	puller, err := remotepuller.NewFromRemoteConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return deptree.New(config, assembler.New(config, puller)).Build(ctx)
+}
+
+// Render draws the node and its descendants as a tree
+func Render(n *deptree.Node) string {
+	return toTree(n).String()
+}
+
+func toTree(n *deptree.Node) *tree.Tree {
+	t := tree.Root(n.Label())
+	for _, c := range n.Children {
+		if len(c.Children) == 0 {
+			t.Child(c.Label())
+		} else {
+			t.Child(toTree(c))
+		}
+	}
+	return t
+}
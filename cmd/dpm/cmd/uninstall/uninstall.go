@@ -36,7 +36,7 @@ func Cmd(config *assistantconfig.Config) *cobra.Command {
 				return err
 			}
 
-			// TODO remove this version's components that aren't part of other sdk versions
+			// components that aren't part of other sdk versions (or projects) are left in the cache, for 'dpm cache gc' to remove
 
 			cmd.Println("successfully uninstalled sdk version " + v.String())
 			return nil
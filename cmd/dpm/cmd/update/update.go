@@ -7,14 +7,17 @@ import (
 	"strings"
 
 	"daml.com/x/assistant/cmd/dpm/cmd/add/dar"
+	"daml.com/x/assistant/cmd/dpm/cmd/outdated"
 	"daml.com/x/assistant/pkg/assembler"
 	"daml.com/x/assistant/pkg/assistantconfig"
 	"daml.com/x/assistant/pkg/assistantconfig/assistantremote"
 	"daml.com/x/assistant/pkg/builtincommand"
 	"daml.com/x/assistant/pkg/damlpackage"
 	"daml.com/x/assistant/pkg/multipackage"
+	ociconsts "daml.com/x/assistant/pkg/oci"
 	"daml.com/x/assistant/pkg/ocilister"
 	"daml.com/x/assistant/pkg/ocipuller/remotepuller"
+	"daml.com/x/assistant/pkg/packagelock"
 	"daml.com/x/assistant/pkg/sdkmanifest"
 	"daml.com/x/assistant/pkg/yamledit"
 	"github.com/spf13/cobra"
@@ -23,6 +26,9 @@ import (
 
 type updateCmd struct {
 	forceInsecure bool
+	check         bool
+	latest        bool
+	output        string
 	config        *assistantconfig.Config
 	printer       *cobra.Command
 }
@@ -40,6 +46,11 @@ func Cmd(config *assistantconfig.Config) *cobra.Command {
 			c.config = config
 			c.printer = cmd
 
+			if c.check {
+				cmd.SilenceUsage = true
+				return outdated.Run(cmd, config, c.output)
+			}
+
 			pkgs, multiPkg, err := c.packagesToUpdate()
 			if err != nil {
 				return err
@@ -57,6 +68,13 @@ func Cmd(config *assistantconfig.Config) *cobra.Command {
 				}
 			}
 
+			// record the versions and digests resolved above
+			if assistantconfig.DpmLockfileEnabled() {
+				if _, err := packagelock.New(c.config, packagelock.Regular).EnsureLockfiles(ctx); err != nil {
+					return err
+				}
+			}
+
 			fmt.Println("Successfully updated project.")
 			return nil
 
@@ -64,6 +82,9 @@ func Cmd(config *assistantconfig.Config) *cobra.Command {
 	}
 
 	cmd.Flags().BoolVar(&c.forceInsecure, "force-insecure", false, "ignoring ArtifactLocations and force http instead of https for OCI registry")
+	cmd.Flags().BoolVar(&c.latest, "latest", false, "move semver ranges (e.g. ^1.4) up to the latest version, rather than updating within them")
+	cmd.Flags().BoolVar(&c.check, "check", false, "only report what would be updated, like 'dpm outdated', without modifying anything")
+	cmd.Flags().StringVarP(&c.output, "output", "o", "table", "output format of --check: json, table")
 
 	return cmd
 }
@@ -166,19 +187,24 @@ func (c *updateCmd) updateDar(ctx context.Context, dep *damlpackage.ParsedDarDep
 		return nil
 	}
 
-	uri := dep.FullUrl.String()
+	uri := dep.String()
 
 	fmt.Printf("Updating dar %q...\n", uri)
 
-	_, ref, err := dep.GetOciRemote()
+	client, ref, err := dep.GetOciRemote()
 	if err != nil {
 		return err
 	}
 
-	// get rid of the sha256 pin on floaty tags because we're about to
+	// get rid of the sha256 pin on floaty tags and ranges because we're about to
 	// re-resolve and update them
-	tag := extractTag(uri)
-	if ocilister.IsFloaty(tag) {
+	if dep.Range != "" {
+		semverRange, err := c.moveRange(ctx, client, ref.Repository, dep.Range)
+		if err != nil {
+			return err
+		}
+		uri = fmt.Sprintf("oci://%s/%s:%s", ref.Registry, ref.Repository, semverRange)
+	} else if tag := extractTag(uri); ocilister.IsFloaty(tag) {
 		uri = fmt.Sprintf("oci://%s/%s:%s", ref.Registry, ref.Repository, tag)
 	}
 
@@ -193,6 +219,9 @@ func (c *updateCmd) updateDar(ctx context.Context, dep *damlpackage.ParsedDarDep
 }
 
 func (c *updateCmd) updateComponent(ctx context.Context, client *assistantremote.Remote, component *sdkmanifest.Component) error {
+	if component.Range != "" {
+		return c.updateRangeComponent(ctx, client, component)
+	}
 	if component.Uri == nil {
 		return nil
 	}
@@ -204,15 +233,25 @@ func (c *updateCmd) updateComponent(ctx context.Context, client *assistantremote
 
 	uri := *component.Uri
 
-	ref, err := registry.ParseReference(strings.TrimPrefix(uri, "oci://"))
+	withoutRange, semverRange, isRange := ocilister.SplitRange(uri)
+	ref, err := registry.ParseReference(strings.TrimPrefix(withoutRange, "oci://"))
 	if err != nil {
 		return err
 	}
 
-	// get rid of the sha256 pin on floaty tags because we're about to
+	// get rid of the sha256 pin on floaty tags and ranges because we're about to
 	// re-resolve and update them
-	tag := extractTag(uri)
-	if ocilister.IsFloaty(tag) {
+	if isRange {
+		client, err := assistantremote.New(ref.Registry, c.config.RegistryAuthPath, c.config.Insecure)
+		if err != nil {
+			return err
+		}
+		semverRange, err = c.moveRange(ctx, client, ref.Repository, semverRange)
+		if err != nil {
+			return err
+		}
+		uri = fmt.Sprintf("oci://%s/%s:%s", ref.Registry, ref.Repository, semverRange)
+	} else if tag := extractTag(uri); ocilister.IsFloaty(tag) {
 		uri = fmt.Sprintf("oci://%s/%s:%s", ref.Registry, ref.Repository, tag)
 	}
 	component.Uri = &uri
@@ -230,6 +269,46 @@ func (c *updateCmd) updateComponent(ctx context.Context, client *assistantremote
 	return nil
 }
 
+// updateRangeComponent updates a component given as "<name>:<range>" to the highest version within its range,
+// after moving the range (and its entry in daml.yaml / multi-package.yaml) up with --latest
+func (c *updateCmd) updateRangeComponent(ctx context.Context, client *assistantremote.Remote, component *sdkmanifest.Component) error {
+	fmt.Printf("Updating component %q...\n", component.String())
+
+	semverRange, err := c.moveRange(ctx, client, ociconsts.ComponentRepoPrefix+component.Name, component.Range)
+	if err != nil {
+		return err
+	}
+	if semverRange != component.Range {
+		if component.YamlEditTarget == nil {
+			return fmt.Errorf("could not update project's daml.yaml or multi-package.yaml with the range of component %q as the needed info to edit the yaml file is missing", component.String())
+		}
+		if err := yamledit.EditYaml(*component.YamlEditTarget, fmt.Sprintf("%s:%s", component.Name, semverRange)); err != nil {
+			return err
+		}
+		component.Range = semverRange
+	}
+
+	_, err = assembler.New(c.config, remotepuller.New(c.config.OciLayoutCache, client)).Assemble(ctx, &sdkmanifest.SdkManifest{
+		Spec: &sdkmanifest.Spec{
+			Components: map[string]*sdkmanifest.Component{component.Name: component},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Component updated: %q\n", component.String())
+	return nil
+}
+
+// moveRange returns the range to update within: the same one, or with --latest, one starting from the latest version
+func (c *updateCmd) moveRange(ctx context.Context, client *assistantremote.Remote, repo, semverRange string) (string, error) {
+	if !c.latest {
+		return semverRange, nil
+	}
+	return ocilister.LatestRange(ctx, client, repo, semverRange)
+}
+
 // extractTag returns the tag if there is one, or "" otherwise.
 // input uri is expected to begin with "oci://"
 func extractTag(uri string) string {
@@ -0,0 +1,84 @@
+// Copyright (c) 2017-2026 Digital Asset (Switzerland) GmbH and/or its affiliates. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package why
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"daml.com/x/assistant/cmd/dpm/cmd/tree"
+	"daml.com/x/assistant/pkg/assistantconfig"
+	"daml.com/x/assistant/pkg/builtincommand"
+	"daml.com/x/assistant/pkg/deptree"
+	"github.com/samber/lo"
+	"github.com/spf13/cobra"
+)
+
+func Cmd(config *assistantconfig.Config) *cobra.Command {
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   string(builtincommand.Why) + " <name>",
+		Short: "show every path through which a component or dar is pulled into the project",
+		Long: `Show every path through which a component or dar is pulled into the project's packages,
+including the declarations that are overridden by a higher layer (multi-package.yaml, then daml.yaml).
+Dars can be given by URI or by the last element of their repository`,
+		Example: `dpm why damlc
+dpm why oci://example.com/dars/foo`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+			if output != "text" && output != "json" {
+				return fmt.Errorf("output format not supported: %s", output)
+			}
+
+			roots, err := tree.Build(cmd.Context(), config)
+			if err != nil {
+				return err
+			}
+			paths := deptree.Why(roots, args[0])
+			if len(paths) == 0 {
+				return fmt.Errorf("%q isn't a component or dar of any package in scope", args[0])
+			}
+
+			if output == "json" {
+				data, err := json.MarshalIndent(lo.Map(paths, func(p deptree.Path, _ int) []*deptree.Node {
+					return withoutChildren(p)
+				}), "", "    ")
+				if err != nil {
+					return err
+				}
+				cmd.Println(string(data))
+				return nil
+			}
+			for _, p := range paths {
+				cmd.Println(describe(p))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&output, "output", "o", "text", "output format: json, text")
+	return cmd
+}
+
+// describe renders a path as one line per node, indented by depth
+func describe(p deptree.Path) string {
+	var b strings.Builder
+	for i, n := range p {
+		label := strings.ReplaceAll(n.Label(), "\n", "\n"+strings.Repeat("  ", i))
+		fmt.Fprintf(&b, "%s%s\n", strings.Repeat("  ", i), label)
+	}
+	return b.String()
+}
+
+// withoutChildren copies the path's nodes, leaving out their children (which are the next node, if anything)
+func withoutChildren(p deptree.Path) []*deptree.Node {
+	return lo.Map(p, func(n *deptree.Node, _ int) *deptree.Node {
+		c := *n
+		c.Children = nil
+		return &c
+	})
+}
@@ -11,12 +11,18 @@ import (
 	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 
 	"daml.com/x/assistant/pkg/assistantconfig"
 	"daml.com/x/assistant/pkg/assistantconfig/assistantremote"
 	"daml.com/x/assistant/pkg/builtincommand"
 	"daml.com/x/assistant/pkg/component"
+	"daml.com/x/assistant/pkg/gitsource"
+	"daml.com/x/assistant/pkg/jvm"
+	ociconsts "daml.com/x/assistant/pkg/oci"
+	"daml.com/x/assistant/pkg/ocicache"
 	"daml.com/x/assistant/pkg/ocilister"
 	"daml.com/x/assistant/pkg/ocipuller"
 	"daml.com/x/assistant/pkg/ocipuller/remotepuller"
@@ -29,6 +35,8 @@ import (
 	"github.com/opencontainers/go-digest"
 	v1 "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/samber/lo"
+	"golang.org/x/sync/singleflight"
+	"oras.land/oras-go/v2/errdef"
 	"oras.land/oras-go/v2/registry"
 )
 
@@ -51,6 +59,11 @@ type Assembler struct {
 	DependencyPathWarnOnly bool
 
 	ExportsPathsWarnOnly bool
+
+	// de-duplicates concurrent pulls of the same component
+	pulls singleflight.Group
+	// guards edits to daml.yaml/multi-package.yaml (and the known projects) from concurrent pulls
+	yamlEditMu sync.Mutex
 }
 
 type AssemblyResult struct {
@@ -63,11 +76,11 @@ type AssemblyResult struct {
 }
 
 func New(config *assistantconfig.Config, puller ocipuller.OciPuller) *Assembler {
-	return &Assembler{config, puller, nil, false, false}
+	return &Assembler{config: config, puller: puller}
 }
 
 func NewWithOverriddenPlatform(config *assistantconfig.Config, puller ocipuller.OciPuller, overridePlatform *simpleplatform.NonGeneric) *Assembler {
-	return &Assembler{config, puller, overridePlatform, false, false}
+	return &Assembler{config: config, puller: puller, overridePlatform: overridePlatform}
 }
 
 func (a *Assembler) ReadAndAssemble(ctx context.Context, assemblyManifestPath string) (*AssemblyResult, error) {
@@ -106,6 +119,12 @@ func (a *Assembler) AssembleManyWithOverlay(ctx context.Context, assemblyManifes
 		return nil, err
 	}
 
+	if err := a.setCommandsEnvironment(cmds, components); err != nil {
+		return nil, err
+	}
+
+	setCommandsJavaHome(cmds, components)
+
 	imports, err := a.computeImports(components)
 	if err != nil {
 		return nil, err
@@ -182,12 +201,83 @@ func (a *Assembler) setCommandsDependencyPaths(cmds map[string][]*ValidatedComma
 	return nil
 }
 
+// setCommandsEnvironment expands the ${component:<name>} references in the commands' env and path-prepends
+func (a *Assembler) setCommandsEnvironment(cmds map[string][]*ValidatedCommand, components map[string]*ResolvedComponent) error {
+	componentPaths := lo.MapValues(components, func(c *ResolvedComponent, _ string) string {
+		return c.AbsolutePath
+	})
+
+	var errs []error
+	for compName, commands := range cmds {
+		for _, cmd := range commands {
+			env := cmd.GetEnvironment()
+
+			cmd.Env = map[string]string{}
+			for k, v := range env.Env {
+				expanded, err := component.ExpandComponentPaths(v, componentPaths)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("component %q has an invalid env var %q for command %q: %w", compName, k, cmd.GetName(), err))
+					continue
+				}
+				cmd.Env[k] = expanded
+			}
+
+			cmd.PathPrepends = nil
+			for _, p := range env.PathPrepends {
+				expanded, err := component.ExpandComponentPaths(p, componentPaths)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("component %q has an invalid path-prepend for command %q: %w", compName, cmd.GetName(), err))
+					continue
+				}
+				cmd.PathPrepends = append(cmd.PathPrepends, utils.ResolvePath(cmd.ComponentPath, expanded))
+			}
+		}
+	}
+
+	err := errors.Join(errs...)
+	if err != nil && a.DependencyPathWarnOnly {
+		slog.Warn(err.Error())
+		return nil
+	}
+	return err
+}
+
+// setCommandsJavaHome picks the JDK each jar-command runs with: the component its own component maps to JAVA_HOME
+// in its dependency-paths, or else the SDK's jdk component. Jar-commands with neither run with the java on PATH
+func setCommandsJavaHome(cmds map[string][]*ValidatedCommand, components map[string]*ResolvedComponent) {
+	jdk, hasJdk := components[jvm.JdkComponentName]
+	for _, cmd := range lo.Flatten(lo.Values(cmds)) {
+		if _, ok := cmd.Command.(*component.JarCommand); !ok {
+			continue
+		}
+		if javaHome, ok := cmd.ResolvedDependencies[jvm.JavaHomeEnvVar]; ok {
+			cmd.JavaHome = javaHome
+		} else if hasJdk {
+			cmd.JavaHome = jdk.AbsolutePath
+		}
+	}
+}
+
 type ValidatedCommand struct {
 	component.Command
 	AbsolutePath         string
 	ComponentName        string
-	ResolvedDependencies map[string]string // <env var key> -> <some component's absolute path>
-	DpmSdkVersionEnvVar  string            // the DPM_SDK_VERSION to be injected into the commands env at runtime
+	ComponentPath        string             // the absolute path of the command's component
+	ResolvedDependencies map[string]string  // <env var key> -> <some component's absolute path>
+	DpmSdkVersionEnvVar  string             // the DPM_SDK_VERSION to be injected into the commands env at runtime
+	Env                  map[string]string  // the command's own env vars, with the component paths they refer to expanded
+	PathPrepends         []string           // absolute dirs to prepend to the command's PATH
+	JavaHome             string             // the JDK (component) a jar-command runs with, "" for the java on PATH
+	Groups               []*component.Group // the groups the command is nested under, outermost first
+}
+
+// FullName returns the command's name, prefixed with those of the groups it's nested under (e.g. "canton sandbox")
+func (c *ValidatedCommand) FullName() string {
+	return strings.Join(append(groupNames(c.Groups), c.GetName()), " ")
+}
+
+func groupNames(groups []*component.Group) []string {
+	return lo.Map(groups, func(g *component.Group, _ int) string { return g.Name })
 }
 
 type ResolvedComponent struct {
@@ -204,39 +294,87 @@ func extractCommands(comps map[string]*ResolvedComponent) map[string][]*Validate
 				Command:       c,
 				AbsolutePath:  utils.ResolvePath(comp.AbsolutePath, c.GetPath()),
 				ComponentName: comp.ComponentName,
+				ComponentPath: comp.AbsolutePath,
+				Groups:        comp.Spec.GroupPath(c.GetGroup()),
 			}
 		})
 	})
 }
 
+// commandTreeNode is a command or a group, at some level of the command tree
+type commandTreeNode struct {
+	parent        string // the full name of the group it's in, "" at the top-level
+	name          string
+	aliases       []string
+	isGroup       bool
+	componentName string
+}
+
+func (n commandTreeNode) fullName() string {
+	return strings.TrimSpace(n.parent + " " + n.name)
+}
+
+func (n commandTreeNode) kind() string {
+	return lo.Ternary(n.isGroup, "command group", "command")
+}
+
+// commandTree returns the nodes of the tree the commands are nested in, with each group once per component
+func commandTree(commands []*ValidatedCommand) []commandTreeNode {
+	var nodes []commandTreeNode
+	for _, cmd := range commands {
+		for i, g := range cmd.Groups {
+			nodes = append(nodes, commandTreeNode{
+				parent:        strings.Join(groupNames(cmd.Groups[:i]), " "),
+				name:          g.Name,
+				aliases:       g.Aliases,
+				isGroup:       true,
+				componentName: cmd.ComponentName,
+			})
+		}
+		nodes = append(nodes, commandTreeNode{
+			parent:        strings.Join(groupNames(cmd.Groups), " "),
+			name:          cmd.GetName(),
+			aliases:       cmd.GetAliases(),
+			componentName: cmd.ComponentName,
+		})
+	}
+	return lo.UniqBy(nodes, func(n commandTreeNode) string {
+		return strings.Join([]string{n.fullName(), n.kind(), n.componentName}, "\x00")
+	})
+}
+
 func validate(commands []*ValidatedCommand) error {
 	var errs []error
 
-	groupedByName := lo.GroupByMap(commands, func(cmd *ValidatedCommand) (string, string) {
-		return cmd.GetName(), cmd.ComponentName
-	})
+	nodes := commandTree(commands)
 
-	for cmd, comps := range groupedByName {
-		if len(comps) > 1 {
-			errs = append(errs, fmt.Errorf("command named %q is defined in multiple components %v", cmd, comps))
+	for name, sameName := range lo.GroupBy(nodes, func(n commandTreeNode) string { return n.fullName() }) {
+		if len(sameName) < 2 {
+			continue
+		}
+		comps := lo.Map(sameName, func(n commandTreeNode, _ int) string { return n.componentName })
+		if kinds := lo.Uniq(lo.Map(sameName, func(n commandTreeNode, _ int) string { return n.kind() })); len(kinds) > 1 {
+			errs = append(errs, fmt.Errorf("%q is defined as both a command and a command group, in components %v", name, lo.Uniq(comps)))
+		} else {
+			errs = append(errs, fmt.Errorf("%s named %q is defined in multiple components %v", kinds[0], name, comps))
 		}
 	}
 
 	builtin := lo.SliceToMap(builtincommand.BuiltinCommands, func(b builtincommand.BuiltinCommand) (string, struct{}) {
 		return string(b), struct{}{}
 	})
-	for _, cmd := range commands {
-		_, ok := builtin[cmd.GetName()]
-		if ok {
-			errs = append(errs, fmt.Errorf("command named %q (from component %q) conflicts with the assistant's built-in commands", cmd.GetName(), cmd.ComponentName))
+	for _, n := range nodes {
+		_, ok := builtin[n.name]
+		if ok && n.parent == "" {
+			errs = append(errs, fmt.Errorf("%s named %q (from component %q) conflicts with the assistant's built-in commands", n.kind(), n.name, n.componentName))
 		}
 	}
 
-	aliases := lo.FlatMap(commands, func(c *ValidatedCommand, _ int) []lo.Entry[string, string] {
-		return lo.Map(c.GetAliases(), func(alias string, _ int) lo.Entry[string, string] {
+	aliases := lo.FlatMap(nodes, func(n commandTreeNode, _ int) []lo.Entry[string, string] {
+		return lo.Map(n.aliases, func(alias string, _ int) lo.Entry[string, string] {
 			return lo.Entry[string, string]{
-				Key:   alias,
-				Value: c.ComponentName,
+				Key:   strings.TrimSpace(n.parent + " " + alias),
+				Value: n.componentName,
 			}
 		})
 	})
@@ -251,7 +389,7 @@ func validate(commands []*ValidatedCommand) error {
 
 	uniqueByPath := lo.UniqBy(commands, func(cmd *ValidatedCommand) string { return cmd.AbsolutePath })
 	for _, c := range uniqueByPath {
-		errMsg := fmt.Sprintf("component %q command validation failed for command %q", c.ComponentName, c.GetName())
+		errMsg := fmt.Sprintf("component %q command validation failed for command %q", c.ComponentName, c.FullName())
 		f, err := os.Stat(c.AbsolutePath)
 		if err != nil {
 			errs = append(errs, fmt.Errorf("%s: %w", errMsg, err))
@@ -303,27 +441,64 @@ func (a *Assembler) collectAssistant(ctx context.Context, assistant *sdkmanifest
 }
 
 // component name -> *ResolvedComponent
+// Components are collected (and pulled, if need be) concurrently, by at most pullConcurrency() at a time
 func (a *Assembler) collectComponents(ctx context.Context, assemblyManifest *sdkmanifest.SdkManifest) (result map[string]*ResolvedComponent, err error) {
-	var errs []error
-
-	result = make(map[string]*ResolvedComponent)
-	for _, comp := range assemblyManifest.Spec.Components {
-		resolved, err := a.collectComponent(ctx, assemblyManifest.AbsolutePath, comp)
-		if err != nil {
-			errs = append(errs, fmt.Errorf("error handling component %q in %q: %w", comp.Name, assemblyManifest.AbsolutePath, err))
-			continue
-		}
-		result[comp.Name] = resolved
+	comps := lo.Values(assemblyManifest.Spec.Components)
+	slices.SortFunc(comps, func(x, y *sdkmanifest.Component) int { return strings.Compare(x.Name, y.Name) })
+
+	resolved := make([]*ResolvedComponent, len(comps))
+	errs := make([]error, len(comps))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, a.pullConcurrency())
+	for i, comp := range comps {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			r, err := a.collectComponent(ctx, assemblyManifest.AbsolutePath, comp)
+			if err != nil {
+				errs[i] = fmt.Errorf("error handling component %q in %q: %w", comp.Name, assemblyManifest.AbsolutePath, err)
+				return
+			}
+			resolved[i] = r
+		}()
 	}
+	wg.Wait()
 
 	if err := errors.Join(errs...); err != nil {
 		return nil, err
 	}
+
+	result = make(map[string]*ResolvedComponent)
+	for i, comp := range comps {
+		result[comp.Name] = resolved[i]
+	}
 	return result, nil
 }
 
+func (a *Assembler) pullConcurrency() int {
+	if a.config == nil || a.config.PullConcurrency <= 0 {
+		return assistantconfig.DefaultPullConcurrency
+	}
+	return a.config.PullConcurrency
+}
+
+// dedupPull runs pull once for all concurrent callers using the same key (e.g. a digest or a component's cache dir)
+func (a *Assembler) dedupPull(key string, pull func() (string, error)) (string, error) {
+	p, err, _ := a.pulls.Do(key, func() (any, error) {
+		return pull()
+	})
+	if err != nil {
+		return "", err
+	}
+	return p.(string), nil
+}
+
 func (a *Assembler) collectComponent(ctx context.Context, basePath string, comp *sdkmanifest.Component) (*ResolvedComponent, error) {
-	var p string
+	var p, commit string
 	var err error
 	if comp.LocalPath != nil {
 		p = a.handleLocalDir(filepath.Dir(basePath), *comp.LocalPath)
@@ -332,7 +507,18 @@ func (a *Assembler) collectComponent(ctx context.Context, basePath string, comp
 		if err != nil {
 			return nil, err
 		}
+	} else if comp.Git != nil {
+		p, commit, err = a.handleGit(ctx, comp)
+		if err != nil {
+			return nil, err
+		}
 	} else {
+		if comp.Range != "" {
+			comp, err = a.resolveRange(ctx, comp)
+			if err != nil {
+				return nil, err
+			}
+		}
 		p, err = a.handleOCI(ctx, comp)
 		if err != nil {
 			return nil, err
@@ -350,6 +536,9 @@ func (a *Assembler) collectComponent(ctx context.Context, basePath string, comp
 	}
 
 	version := filepath.Base(absPath)
+	if commit != "" {
+		version = commit
+	}
 
 	return &ResolvedComponent{
 		Component:     parsedComp,
@@ -363,12 +552,59 @@ func (a *Assembler) handleLocalDir(basePath, componentPath string) string {
 	return utils.ResolvePath(basePath, componentPath)
 }
 
+// handleGit returns the checkout of a git component at the commit it's pinned to (by the lockfile, or by its rev being one),
+// along with that commit.
+// Like pulling, checking out (and fetching the rev of an unpinned component) requires auto-install.
+// Without it, an unpinned rev resolves to wherever it was when the component was last installed
+func (a *Assembler) handleGit(ctx context.Context, comp *sdkmanifest.Component) (string, string, error) {
+	src, err := gitsource.Parse(*comp.Git)
+	if err != nil {
+		return "", "", err
+	}
+	commit := comp.Commit
+	if commit == "" && src.IsCommit() {
+		commit = src.Rev
+	}
+	if commit == "" && !a.config.AutoInstall {
+		// not locked, so the rev resolves to wherever it was when it was last installed
+		if commit, _, err = gitsource.ResolveCached(a.config, src); err != nil {
+			return "", "", err
+		}
+	}
+	if commit != "" {
+		if dir, ok, err := gitsource.CheckedOut(a.config, src, commit); err != nil || ok {
+			return dir, commit, err
+		}
+	}
+	if !a.config.AutoInstall {
+		return "", "", fmt.Errorf("component %q is currently not installed.  Run `dpm install package` to install", comp.String())
+	}
+
+	// fetches and checkouts are per repo, whichever of its subdirs the components are in
+	if commit == "" {
+		commit, err = a.dedupPull(src.Repo+"\x00"+src.Rev, func() (string, error) {
+			return gitsource.Resolve(ctx, a.config, src, a.config.RegistryAuthPath)
+		})
+		if err != nil {
+			return "", "", err
+		}
+	}
+	_, err = a.dedupPull(src.Repo+"\x00"+commit, func() (string, error) {
+		fmt.Printf("checking out sdk component %s %s...\n", comp.Name, commit)
+		return gitsource.Checkout(ctx, a.config, src, commit, a.config.RegistryAuthPath)
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return gitsource.Dir(a.config, src, commit), commit, nil
+}
+
 func (a *Assembler) handleURI(ctx context.Context, comp *sdkmanifest.Component) (string, error) {
 	if a.config.AutoInstall {
 		return a.installUriComp(ctx, comp)
 	}
 
-	uri := *comp.Uri
+	uri, _, _ := ocilister.SplitRange(*comp.Uri)
 	ref, err := registry.ParseReference(strings.TrimPrefix(uri, "oci://"))
 	if err != nil {
 		return "", err
@@ -382,7 +618,7 @@ func (a *Assembler) handleURI(ctx context.Context, comp *sdkmanifest.Component)
 		return a.legacyUriComponentLookup(comp, ref)
 	}
 
-	destPath, ok, err := a.getFromCacheByDigest(comp, digest)
+	destPath, ok, err := a.getFromCacheByDigest(ctx, comp, digest)
 	if err != nil {
 		return "", err
 	}
@@ -414,7 +650,8 @@ func (a *Assembler) installUriComp(ctx context.Context, comp *sdkmanifest.Compon
 	var version, newUri string
 
 	uri := *comp.Uri
-	ref, err := registry.ParseReference(strings.TrimPrefix(uri, "oci://"))
+	withoutRange, semverRange, _ := ocilister.SplitRange(uri)
+	ref, err := registry.ParseReference(strings.TrimPrefix(withoutRange, "oci://"))
 	if err != nil {
 		return "", err
 	}
@@ -429,16 +666,23 @@ func (a *Assembler) installUriComp(ctx context.Context, comp *sdkmanifest.Compon
 	// if the URI doesn't already have a sha256, give it one.
 	// (this does not necessarily mean we're gonna bump dependencies. This method never changes existing SHAs!)
 	if digestErr != nil {
+		if semverRange != "" {
+			_, ref.Reference, err = ocilister.ResolveRange(ctx, client, ref.Repository, semverRange)
+			if err != nil {
+				return "", err
+			}
+		}
 		resolvedDigest, ociManifest, err := ocilister.FetchManifest(ctx, client, ref)
 		if err != nil {
 			return "", err
 		}
 		sha256Digest = resolvedDigest
 		version = ociManifest.Annotations[v1.AnnotationVersion]
+		// the range is kept, so that 'dpm update' can move within it
 		newUri = uri + "@" + resolvedDigest.String()
 
 		// update client and ref
-		ref, err = registry.ParseReference(strings.TrimPrefix(newUri, "oci://"))
+		ref, err = registry.ParseReference(strings.TrimPrefix(withoutRange+"@"+resolvedDigest.String(), "oci://"))
 		if err != nil {
 			return "", err
 		}
@@ -449,7 +693,7 @@ func (a *Assembler) installUriComp(ctx context.Context, comp *sdkmanifest.Compon
 	} else {
 		// The uri has a sha already.
 
-		destPath, ok, err := a.getFromCacheByDigest(comp, sha256Digest)
+		destPath, ok, err := a.getFromCacheByDigest(ctx, comp, sha256Digest)
 		if err != nil {
 			return "", err
 		}
@@ -475,20 +719,25 @@ func (a *Assembler) installUriComp(ctx context.Context, comp *sdkmanifest.Compon
 		platform = a.overridePlatform
 	}
 
-	ok, err := utils.DirExists(destPath)
-	if err != nil {
-		return "", err
-	}
-	if !ok {
-		if _, err := puller.PullComponentByFullPath(ctx, ref.Repository, ref.Reference, destPath, platform); err != nil {
+	_, err = a.dedupPull(sha256Digest.String()+"\x00"+destPath, func() (string, error) {
+		ok, err := utils.DirExists(destPath)
+		if err != nil {
 			return "", err
 		}
-	}
-
-	if err := a.config.CacheIndex.Store(sha256Digest, comp.Name, version); err != nil {
+		if !ok {
+			if _, err := puller.PullComponentByFullPath(ctx, ref.Repository, ref.Reference, destPath, platform); err != nil {
+				return "", err
+			}
+		}
+		return destPath, a.config.CacheIndex.Store(ctx, sha256Digest, comp.Name, version)
+	})
+	if err != nil {
 		return "", err
 	}
 
+	a.yamlEditMu.Lock()
+	defer a.yamlEditMu.Unlock()
+
 	// if we had to append a sha, update the daml.yaml / component.yaml
 	if newUri != "" {
 		if comp.YamlEditTarget == nil {
@@ -500,12 +749,17 @@ func (a *Assembler) installUriComp(ctx context.Context, comp *sdkmanifest.Compon
 			return "", err
 		}
 	}
+	if comp.YamlEditTarget != nil {
+		if err := a.config.KnownProjects.Add(filepath.Dir(comp.YamlEditTarget.YamlFilePath)); err != nil {
+			return "", err
+		}
+	}
 
 	return destPath, nil
 }
 
-func (a *Assembler) getFromCacheByDigest(comp *sdkmanifest.Component, d digest.Digest) (string, bool, error) {
-	_, version, ok, err := a.config.CacheIndex.Get(d.String())
+func (a *Assembler) getFromCacheByDigest(ctx context.Context, comp *sdkmanifest.Component, d digest.Digest) (string, bool, error) {
+	_, version, ok, err := a.config.CacheIndex.Get(ctx, d.String())
 	if err != nil {
 		return "", false, err
 	}
@@ -521,16 +775,107 @@ func (a *Assembler) getFromCacheByDigest(comp *sdkmanifest.Component, d digest.D
 	return destPath, ok, nil
 }
 
+// resolveRange returns a copy of comp with its semver range resolved to a version: the highest matching one in the registry
+// when installing, or else the highest matching one that's already installed
+func (a *Assembler) resolveRange(ctx context.Context, comp *sdkmanifest.Component) (*sdkmanifest.Component, error) {
+	c, err := semver.NewConstraint(comp.Range)
+	if err != nil {
+		return nil, fmt.Errorf("invalid semver range for component %q: %w", comp.String(), err)
+	}
+
+	resolved := *comp
+	resolved.Range = ""
+
+	_, isRemote := a.puller.(*remotepuller.RemoteOciPuller)
+	if !a.config.AutoInstall {
+		entries, err := os.ReadDir(a.ociComponentPath(comp.Name, ""))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		installed := lo.FilterMap(entries, func(e os.DirEntry, _ int) (string, bool) {
+			return e.Name(), e.IsDir()
+		})
+		if v, _, ok := ocilister.HighestMatch(installed, c); ok {
+			resolved.Version = sdkmanifest.AssemblySemVer(v)
+			return &resolved, nil
+		}
+		if isRemote {
+			return nil, fmt.Errorf("component %q is currently not installed.  Run `dpm install package` to install", comp.String())
+		}
+	}
+
+	client, err := assistantremote.NewFromConfig(a.config)
+	if err != nil {
+		return nil, err
+	}
+	v, _, err := ocilister.ResolveRange(ctx, client, ociconsts.ComponentRepoPrefix+comp.Name, comp.Range)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve component %q: %w", comp.String(), err)
+	}
+	resolved.Version = sdkmanifest.AssemblySemVer(v)
+	return &resolved, nil
+}
+
 func (a *Assembler) handleOCI(ctx context.Context, comp *sdkmanifest.Component) (string, error) {
 	destPath := a.ociComponentPath(comp.Name, comp.Version.Value().String())
 	tag := ComputeTagOrDigest(comp)
+	if comp.Digest != "" {
+		return a.handlePinnedOCI(ctx, comp, destPath)
+	}
 
-	// check if component is already in the cache
-	ok, err := utils.DirExists(destPath)
+	return a.dedupPull(destPath, func() (string, error) {
+		// check if component is already in the cache
+		ok, err := utils.DirExists(destPath)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			if _, isRemote := a.puller.(*remotepuller.RemoteOciPuller); isRemote && !a.config.AutoInstall {
+				return "", fmt.Errorf("component %q is currently not installed.  Run `dpm install package` to install", comp.String())
+			}
+			platform := simpleplatform.CurrentPlatform()
+			if a.overridePlatform != nil {
+				platform = a.overridePlatform
+			}
+			fmt.Printf("pulling sdk component %s %s...\n", comp.Name, tag)
+			if _, err := a.puller.PullComponent(ctx, comp.Name, tag, destPath, platform); err != nil {
+				return "", err
+			}
+		}
+
+		return destPath, nil
+	})
+}
+
+// handlePinnedOCI is handleOCI for components pinned (by the lockfile) to a digest.
+// They're pulled by digest, and a cached copy is only used if it's known to have been pulled from that digest
+func (a *Assembler) handlePinnedOCI(ctx context.Context, comp *sdkmanifest.Component, destPath string) (string, error) {
+	d, err := digest.Parse(comp.Digest)
 	if err != nil {
-		return "", err
+		return "", fmt.Errorf("invalid digest %q pinned for component %q: %w", comp.Digest, comp.String(), err)
 	}
-	if !ok {
+	version := comp.Version.Value().String()
+
+	return a.dedupPull(d.String()+"\x00"+destPath, func() (string, error) {
+		name, indexedVersion, indexed, err := a.config.CacheIndex.Get(ctx, d.String())
+		if err != nil {
+			return "", err
+		}
+		if indexed && (name != comp.Name || indexedVersion != version) {
+			return "", fmt.Errorf("component %q is locked at %s, which is %s:%s", comp.String(), d, name, indexedVersion)
+		}
+
+		ok, err := utils.DirExists(destPath)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			if indexed {
+				return destPath, nil
+			}
+			return destPath, a.checkCachedComponentDigest(ctx, comp, d)
+		}
+
 		if _, isRemote := a.puller.(*remotepuller.RemoteOciPuller); isRemote && !a.config.AutoInstall {
 			return "", fmt.Errorf("component %q is currently not installed.  Run `dpm install package` to install", comp.String())
 		}
@@ -538,13 +883,35 @@ func (a *Assembler) handleOCI(ctx context.Context, comp *sdkmanifest.Component)
 		if a.overridePlatform != nil {
 			platform = a.overridePlatform
 		}
-		fmt.Printf("pulling sdk component %s %s...\n", comp.Name, tag)
-		if _, err := a.puller.PullComponent(ctx, comp.Name, tag, destPath, platform); err != nil {
+		fmt.Printf("pulling sdk component %s %s@%s...\n", comp.Name, version, d)
+		if _, err := a.puller.PullComponent(ctx, comp.Name, d.String(), destPath, platform); err != nil {
 			return "", err
 		}
+		return destPath, a.config.CacheIndex.Store(ctx, d, comp.Name, version)
+	})
+}
+
+// checkCachedComponentDigest compares the pinned digest against the one the component's tag resolved to
+// when the (un-indexed) cached copy was pulled, as recorded in the oci-layout cache
+func (a *Assembler) checkCachedComponentDigest(ctx context.Context, comp *sdkmanifest.Component, d digest.Digest) error {
+	store, err := ocicache.Store(a.config.OciLayoutCache)
+	if err != nil {
+		return err
 	}
+	version := comp.Version.Value().String()
+	tagKey := fmt.Sprintf("%s/%s%s:%s", a.config.Registry, ociconsts.ComponentRepoPrefix, comp.Name, version)
 
-	return destPath, nil
+	desc, err := store.Resolve(ctx, tagKey)
+	if errors.Is(err, errdef.ErrNotFound) {
+		slog.Warn("can't tell whether the cached component matches the locked digest", "component", comp.String(), "digest", d.String())
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if desc.Digest != d {
+		return fmt.Errorf("the cached component %q was pulled from %s, but is locked at %s. Run 'dpm cache rm %s:%s' and try again", comp.String(), desc.Digest, d, comp.Name, version)
+	}
+	return a.config.CacheIndex.Store(ctx, d, comp.Name, version)
 }
 
 func ComputeTagOrDigest(comp *sdkmanifest.Component) string {
@@ -552,7 +919,7 @@ func ComputeTagOrDigest(comp *sdkmanifest.Component) string {
 }
 
 func (a *Assembler) ociComponentPath(componentUri string, tag string) string {
-	return filepath.Join(a.config.CachePath, "components", utils.UrlToFilePath(componentUri), tag)
+	return a.config.CachePathForComponent(componentUri, tag)
 }
 
 // computeImports merges all components' component.Exports, taking into account their conflict strategy,
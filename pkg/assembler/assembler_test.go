@@ -4,15 +4,27 @@
 package assembler
 
 import (
+	"context"
+	"fmt"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"daml.com/x/assistant/pkg/assistantconfig"
+	"daml.com/x/assistant/pkg/gitsource"
 	"daml.com/x/assistant/pkg/sdkmanifest"
+	"daml.com/x/assistant/pkg/simpleplatform"
 	"daml.com/x/assistant/pkg/testutil"
+	"github.com/Masterminds/semver/v3"
 	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/opencontainers/go-digest"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/samber/lo"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -62,6 +74,83 @@ func TestBinaryPathValidation(t *testing.T) {
 	require.ErrorContains(t, err, "oops")
 }
 
+func TestCollectComponentsAggregatesErrors(t *testing.T) {
+	ctx := testutil.Context(t)
+	t.Setenv(assistantconfig.EditionEnvVar, "enterprise")
+	t.Setenv(assistantconfig.PullConcurrencyEnvVar, "2")
+
+	manifestPath := filepath.Join(t.TempDir(), "sdk-manifest.yaml")
+	require.NoError(t, os.WriteFile(manifestPath, []byte(`apiVersion: digitalasset.com/v1
+kind: SdkManifest
+spec:
+  version: 1.2.3
+  edition: enterprise
+  components:
+    meep:
+      local-path: ./does-not-exist-meep
+    sheep:
+      local-path: ./does-not-exist-sheep
+    beep:
+      local-path: ./does-not-exist-beep
+`), 0o666))
+
+	a, err := Fake(nil)
+	require.NoError(t, err)
+
+	_, err = a.ReadAndAssemble(ctx, manifestPath)
+	require.ErrorIs(t, err, os.ErrNotExist)
+	for _, comp := range []string{"meep", "sheep", "beep"} {
+		assert.ErrorContains(t, err, fmt.Sprintf("error handling component %q", comp))
+	}
+}
+
+// countingPuller pulls any component by creating its dir, counting the pulls
+type countingPuller struct {
+	FakePuller
+	pulls atomic.Int32
+}
+
+func (p *countingPuller) PullComponent(ctx context.Context, componentName, tag, destPath string, platform simpleplatform.Platform) (*v1.Descriptor, error) {
+	p.pulls.Add(1)
+	// give the other caller the chance to join in
+	time.Sleep(50 * time.Millisecond)
+	return &v1.Descriptor{}, os.MkdirAll(destPath, 0o755)
+}
+
+func TestPinnedComponentsPulledOnce(t *testing.T) {
+	ctx := testutil.Context(t)
+
+	a, err := Fake(nil)
+	require.NoError(t, err)
+	puller := &countingPuller{}
+	a.puller = puller
+
+	// e.g. the sdk's meep, and the same meep declared again in daml.yaml, both locked at the same digest
+	d := digest.FromString("meep")
+	version := sdkmanifest.AssemblySemVer(semver.MustParse("1.2.3"))
+	comps := []*sdkmanifest.Component{
+		{Name: "meep", Version: version, Digest: d.String()},
+		{Name: "meep", Version: version, Digest: d.String()},
+	}
+
+	var wg sync.WaitGroup
+	paths := make([]string, len(comps))
+	for i, comp := range comps {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p, err := a.handleOCI(ctx, comp)
+			assert.NoError(t, err)
+			paths[i] = p
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), puller.pulls.Load())
+	assert.Equal(t, paths[0], paths[1])
+	assert.DirExists(t, paths[0])
+}
+
 func TestAssembleRemote(t *testing.T) {
 	ctx := testutil.Context(t)
 	t.Setenv(assistantconfig.EditionEnvVar, "enterprise")
@@ -87,9 +176,231 @@ func TestAssembleRemote(t *testing.T) {
 	}
 }
 
+func TestAssembleGit(t *testing.T) {
+	ctx := testutil.Context(t)
+	t.Setenv(assistantconfig.EditionEnvVar, "enterprise")
+
+	gitsource.AllowFileReposInTests(t)
+	repoDir, commit := testutil.GitRepo(t, map[string][]byte{
+		"components/meep/component.yaml": []byte(`apiVersion: digitalasset.com/v1
+kind: Component
+spec:
+  commands:
+    - path: ./meep.sh
+      name: meep
+`),
+		"components/meep/meep.sh": []byte("#!/bin/sh\necho meep\n"),
+	}, "v1.0.0")
+	source := "git+file://" + filepath.ToSlash(repoDir) + "//components/meep@v1.0.0"
+
+	manifestPath := filepath.Join(t.TempDir(), "sdk-manifest.yaml")
+	require.NoError(t, os.WriteFile(manifestPath, []byte(`apiVersion: digitalasset.com/v1
+kind: SdkManifest
+spec:
+  version: 1.2.3
+  edition: enterprise
+  components:
+    meep:
+      git: `+source+`
+`), 0o666))
+
+	a, err := Fake(nil)
+	require.NoError(t, err)
+
+	_, err = a.ReadAndAssemble(ctx, manifestPath)
+	require.ErrorContains(t, err, "not installed")
+
+	a.config.AutoInstall = true
+	result, err := a.ReadAndAssemble(ctx, manifestPath)
+	require.NoError(t, err)
+	dir := getCommandByName(result.ValidatedCommands, "meep").AbsolutePath
+	assert.True(t, strings.HasPrefix(dir, filepath.Join(a.config.CachePath, "git")), dir)
+	assert.Equal(t, commit, result.ShallowResolution.ComponentsV2["meep"]["version"])
+
+	// without a lockfile, the tag resolves from the mirror once installed
+	a.config.AutoInstall = false
+	result, err = a.ReadAndAssemble(ctx, manifestPath)
+	require.NoError(t, err)
+	assert.Equal(t, dir, getCommandByName(result.ValidatedCommands, "meep").AbsolutePath)
+}
+
 func getCommandByName(cmds map[string][]*ValidatedCommand, commandName string) *ValidatedCommand {
 	flattened := lo.Flatten(lo.Values(cmds))
 	return lo.FirstOr(lo.Filter(flattened, func(cmd *ValidatedCommand, _ int) bool {
 		return cmd.GetName() == commandName
 	}), nil)
 }
+
+// writeLocalComponent creates a component at dir/name whose spec is given, with an (empty) file for every bin/<x> it refers to
+func writeLocalComponent(t *testing.T, dir, name, spec string) {
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, name, "bin"), 0o755))
+	for _, bin := range regexp.MustCompile(`bin/[\w-]+`).FindAllString(spec, -1) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name, bin), []byte("#!/bin/sh\n"), 0o755))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name, "component.yaml"), []byte(`apiVersion: digitalasset.com/v1
+kind: Component
+spec:
+`+spec), 0o666))
+}
+
+// writeLocalManifest creates an sdk manifest in dir with the given components of dir
+func writeLocalManifest(t *testing.T, dir string, components ...string) string {
+	p := filepath.Join(dir, strings.Join(components, "-")+".yaml")
+	s := "apiVersion: digitalasset.com/v1\nkind: SdkManifest\nspec:\n  version: 1.2.3\n  edition: enterprise\n  components:\n"
+	for _, c := range components {
+		s += fmt.Sprintf("    %s:\n      local-path: ./%s\n", c, c)
+	}
+	require.NoError(t, os.WriteFile(p, []byte(s), 0o666))
+	return p
+}
+
+func TestCommandEnvironment(t *testing.T) {
+	ctx := testutil.Context(t)
+	t.Setenv(assistantconfig.EditionEnvVar, "enterprise")
+
+	dir := t.TempDir()
+	writeLocalComponent(t, dir, "canton", `  commands:
+    - path: bin/canton
+      name: canton
+`)
+	writeLocalComponent(t, dir, "console", `  commands:
+    - path: bin/console
+      name: console
+      env:
+        CANTON_LIB: ${component:canton}/lib
+      path-prepends: [bin, "${component:canton}/bin"]
+`)
+	writeLocalComponent(t, dir, "broken", `  commands:
+    - path: bin/broken
+      name: broken
+      env:
+        DAMLC: ${component:damlc}
+`)
+
+	a, err := Fake(nil)
+	require.NoError(t, err)
+
+	result, err := a.ReadAndAssemble(ctx, writeLocalManifest(t, dir, "canton", "console"))
+	require.NoError(t, err)
+	console := getCommandByName(result.ValidatedCommands, "console")
+	cantonPath := filepath.Join(dir, "canton")
+	assert.Equal(t, map[string]string{"CANTON_LIB": cantonPath + "/lib"}, console.Env)
+	assert.Equal(t, []string{filepath.Join(dir, "console", "bin"), filepath.Join(cantonPath, "bin")}, console.PathPrepends)
+	assert.Equal(t, filepath.Join(dir, "console"), console.ComponentPath)
+
+	_, err = a.ReadAndAssemble(ctx, writeLocalManifest(t, dir, "broken"))
+	assert.ErrorContains(t, err, `refers to component "damlc"`)
+
+	a.DependencyPathWarnOnly = true
+	result, err = a.ReadAndAssemble(ctx, writeLocalManifest(t, dir, "broken"))
+	require.NoError(t, err)
+	assert.Empty(t, getCommandByName(result.ValidatedCommands, "broken").Env)
+}
+
+func TestCommandJavaHome(t *testing.T) {
+	ctx := testutil.Context(t)
+	t.Setenv(assistantconfig.EditionEnvVar, "enterprise")
+
+	dir := t.TempDir()
+	writeLocalComponent(t, dir, "jdk", "  commands: []\n")
+	writeLocalComponent(t, dir, "openjdk", "  commands: []\n")
+	writeLocalComponent(t, dir, "codegen", `  commands:
+    - path: bin/codegen
+      name: codegen
+  jar-commands:
+    - path: bin/codegen-java
+      name: codegen-java
+      java-version: ">=17"
+`)
+	writeLocalComponent(t, dir, "canton", `  dependency-paths:
+    openjdk: JAVA_HOME
+  jar-commands:
+    - path: bin/canton
+      name: canton
+`)
+
+	a, err := Fake(nil)
+	require.NoError(t, err)
+
+	result, err := a.ReadAndAssemble(ctx, writeLocalManifest(t, dir, "codegen"))
+	require.NoError(t, err)
+	assert.Empty(t, getCommandByName(result.ValidatedCommands, "codegen-java").JavaHome, "the java on PATH")
+
+	result, err = a.ReadAndAssemble(ctx, writeLocalManifest(t, dir, "jdk", "openjdk", "codegen", "canton"))
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "jdk"), getCommandByName(result.ValidatedCommands, "codegen-java").JavaHome)
+	assert.Equal(t, filepath.Join(dir, "openjdk"), getCommandByName(result.ValidatedCommands, "canton").JavaHome)
+	assert.Empty(t, getCommandByName(result.ValidatedCommands, "codegen").JavaHome, "not a jar-command")
+}
+
+func TestCommandGroups(t *testing.T) {
+	ctx := testutil.Context(t)
+	t.Setenv(assistantconfig.EditionEnvVar, "enterprise")
+
+	dir := t.TempDir()
+	writeLocalComponent(t, dir, "canton", `  groups:
+    - name: canton
+      desc: run and interact with canton
+    - name: admin
+      group: canton
+  commands:
+    - path: bin/canton
+      name: sandbox
+      group: canton
+    - path: bin/canton
+      name: prune
+      group: admin
+`)
+	// the same leaf names as canton's, in different groups
+	writeLocalComponent(t, dir, "pqs", `  groups:
+    - name: pqs
+  commands:
+    - path: bin/pqs
+      name: sandbox
+      group: pqs
+    - path: bin/pqs
+      name: prune
+`)
+	// a top-level command named like canton's group
+	writeLocalComponent(t, dir, "shadow", `  commands:
+    - path: bin/shadow
+      name: canton
+`)
+	// canton's group again, from another component
+	writeLocalComponent(t, dir, "sibling", `  groups:
+    - name: canton
+  commands:
+    - path: bin/sibling
+      name: console
+      group: canton
+`)
+	// a group named like a built-in command
+	writeLocalComponent(t, dir, "builtin", `  groups:
+    - name: install
+  commands:
+    - path: bin/builtin
+      name: things
+      group: install
+`)
+
+	a, err := Fake(nil)
+	require.NoError(t, err)
+
+	result, err := a.ReadAndAssemble(ctx, writeLocalManifest(t, dir, "canton", "pqs"))
+	require.NoError(t, err)
+	assert.Equal(t, "canton admin prune", lo.FindOrElse(result.ValidatedCommands["canton"], nil, func(c *ValidatedCommand) bool {
+		return c.GetName() == "prune"
+	}).FullName())
+	assert.Equal(t, "pqs sandbox", lo.FindOrElse(result.ValidatedCommands["pqs"], nil, func(c *ValidatedCommand) bool {
+		return c.GetName() == "sandbox"
+	}).FullName())
+
+	_, err = a.ReadAndAssemble(ctx, writeLocalManifest(t, dir, "canton", "shadow"))
+	assert.ErrorContains(t, err, `"canton" is defined as both a command and a command group`)
+
+	_, err = a.ReadAndAssemble(ctx, writeLocalManifest(t, dir, "canton", "sibling"))
+	assert.ErrorContains(t, err, `command group named "canton" is defined in multiple components`)
+
+	_, err = a.ReadAndAssemble(ctx, writeLocalManifest(t, dir, "builtin"))
+	assert.ErrorContains(t, err, `command group named "install" (from component "builtin") conflicts with the assistant's built-in commands`)
+}
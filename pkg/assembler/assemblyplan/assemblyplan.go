@@ -164,6 +164,12 @@ func NewShallow(ctx context.Context, config *assistantconfig.Config, a *assemble
 		}
 	}
 
+	if assistantconfig.DpmLockfileEnabled() {
+		if err := plan.applyLocks(ctx, damlPackagePath, multiPackagePath); err != nil {
+			return nil, err
+		}
+	}
+
 	return plan, nil
 }
 
@@ -4,14 +4,17 @@
 package assemblyplan
 
 import (
+	"net/url"
 	"os"
 	"path/filepath"
 	"testing"
 
 	"daml.com/x/assistant/pkg/assembler"
 	"daml.com/x/assistant/pkg/assistantconfig"
+	"daml.com/x/assistant/pkg/packagelock"
 	"daml.com/x/assistant/pkg/sdkmanifest"
 	"daml.com/x/assistant/pkg/testutil"
+	"github.com/Masterminds/semver/v3"
 	"github.com/samber/lo"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -119,3 +122,54 @@ func loadDamlPackage(t *testing.T, installedSdksPath, damlPackagePath, sdkVersio
 	require.NoError(t, err)
 	return plan, result.ValidatedCommands
 }
+
+func TestPinComponents(t *testing.T) {
+	plan := &AssemblyPlan{config: &assistantconfig.Config{Registry: "example.com/public"}}
+	lockedURI := func(s string) *url.URL {
+		u, err := url.Parse(s)
+		require.NoError(t, err)
+		return u
+	}
+	pins := map[string]*packagelock.Component{
+		"meep":  {Name: "meep", URI: lockedURI("oci://example.com/public/components/meep:1.2.3"), Digest: "sha256:aaaa"},
+		"sheep": {Name: "sheep", URI: lockedURI("oci://example.com/sheep:latest"), Digest: "sha256:bbbb"},
+		"deep":  {Name: "deep", URI: lockedURI("oci://example.com/public/components/deep:1.4.2"), Range: "^1.4", Digest: "sha256:cccc"},
+	}
+
+	t.Run("by version", func(t *testing.T) {
+		comp := &sdkmanifest.Component{Name: "meep", Version: sdkmanifest.AssemblySemVer(semver.MustParse("1.2.3"))}
+		pinned := plan.pin(comp, pins)
+		assert.Equal(t, "sha256:aaaa", pinned.Digest)
+		assert.Empty(t, comp.Digest, "the original component is left as is")
+	})
+
+	t.Run("by uri", func(t *testing.T) {
+		comp := &sdkmanifest.Component{Name: "sheep", Uri: lo.ToPtr("oci://example.com/sheep:latest")}
+		pinned := plan.pin(comp, pins)
+		assert.Equal(t, "oci://example.com/sheep:latest@sha256:bbbb", *pinned.Uri)
+	})
+
+	t.Run("by range", func(t *testing.T) {
+		comp := &sdkmanifest.Component{Name: "deep", Range: "^1.4"}
+		pinned := plan.pin(comp, pins)
+		require.NotNil(t, pinned.Version)
+		assert.Equal(t, "1.4.2", pinned.Version.Value().String())
+		assert.Empty(t, pinned.Range)
+		assert.Equal(t, "sha256:cccc", pinned.Digest)
+	})
+
+	t.Run("range differs from the lockfile", func(t *testing.T) {
+		comp := &sdkmanifest.Component{Name: "deep", Range: "^2.0"}
+		assert.Same(t, comp, plan.pin(comp, pins))
+	})
+
+	t.Run("version differs from the lockfile", func(t *testing.T) {
+		comp := &sdkmanifest.Component{Name: "meep", Version: sdkmanifest.AssemblySemVer(semver.MustParse("1.2.4"))}
+		assert.Same(t, comp, plan.pin(comp, pins))
+	})
+
+	t.Run("not locked", func(t *testing.T) {
+		comp := &sdkmanifest.Component{Name: "beep", Version: sdkmanifest.AssemblySemVer(semver.MustParse("1.2.3"))}
+		assert.Same(t, comp, plan.pin(comp, pins))
+	})
+}
@@ -0,0 +1,149 @@
+// Copyright (c) 2017-2026 Digital Asset (Switzerland) GmbH and/or its affiliates. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package assemblyplan
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"daml.com/x/assistant/pkg/assistantconfig"
+	"daml.com/x/assistant/pkg/packagelock"
+	"daml.com/x/assistant/pkg/sdkmanifest"
+	"github.com/Masterminds/semver/v3"
+	"oras.land/oras-go/v2/registry"
+)
+
+// applyLocks pins the plan's components to the digests locked in multi-package.lock and dpm.lock
+// (the latter taking precedence), and checks the installed sdk manifest against the locked one
+func (plan *AssemblyPlan) applyLocks(ctx context.Context, damlPackagePath, multiPackagePath string) error {
+	var multiPackageLock, packageLock *packagelock.PackageLock
+	var err error
+	if multiPackagePath != "" {
+		multiPackageLock, err = readLock(filepath.Join(filepath.Dir(multiPackagePath), assistantconfig.DpmMultiPackageLockFileName))
+		if err != nil {
+			return err
+		}
+	}
+	if damlPackagePath != "" {
+		packageLock, err = readLock(filepath.Join(filepath.Dir(damlPackagePath), assistantconfig.DpmLockFileName))
+		if err != nil {
+			return err
+		}
+	}
+
+	pins := map[string]*packagelock.Component{}
+	for _, lock := range []*packagelock.PackageLock{multiPackageLock, packageLock} {
+		if lock == nil {
+			continue
+		}
+		for _, c := range lock.Components {
+			pins[c.Name] = c
+		}
+	}
+
+	// the lock that decided the sdk version
+	sdkLock := packageLock
+	if damlPackagePath == "" {
+		sdkLock = multiPackageLock
+	}
+	if sdkLock != nil && plan.SdkVersion != nil && plan.Base.AbsolutePath != "" &&
+		sdkLock.SdkVersion.Version == plan.SdkVersion.String() && sdkLock.SdkVersion.Digest != "" {
+		if err := packagelock.VerifySdkManifest(ctx, plan.config, sdkLock.SdkVersion, plan.Base.AbsolutePath); err != nil {
+			return err
+		}
+	}
+
+	if len(pins) == 0 {
+		return nil
+	}
+	plan.Base = plan.pinAll(plan.Base, pins)
+	if plan.MultiPackage != nil {
+		m := plan.pinAll(*plan.MultiPackage, pins)
+		plan.MultiPackage = &m
+	}
+	if plan.DamlPackage != nil {
+		m := plan.pinAll(*plan.DamlPackage, pins)
+		plan.DamlPackage = &m
+	}
+	return nil
+}
+
+func readLock(path string) (*packagelock.PackageLock, error) {
+	lock, err := packagelock.ReadPackageLock(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return lock, err
+}
+
+// pinAll returns a copy of m with its components pinned
+func (plan *AssemblyPlan) pinAll(m sdkmanifest.SdkManifest, pins map[string]*packagelock.Component) sdkmanifest.SdkManifest {
+	if m.Spec == nil {
+		return m
+	}
+	spec := *m.Spec
+	spec.Components = make(map[string]*sdkmanifest.Component, len(m.Spec.Components))
+	for name, comp := range m.Spec.Components {
+		spec.Components[name] = plan.pin(comp, pins)
+	}
+	if spec.Assistant != nil {
+		spec.Assistant = plan.pin(spec.Assistant, pins)
+	}
+	m.Spec = &spec
+	return m
+}
+
+// pin returns a copy of comp pinned to its locked digest, or comp itself if it isn't locked (as declared)
+func (plan *AssemblyPlan) pin(comp *sdkmanifest.Component, pins map[string]*packagelock.Component) *sdkmanifest.Component {
+	locked, ok := pins[comp.Name]
+	if !ok || (locked.Digest == "" && locked.Commit == "") || locked.URI == nil {
+		return comp
+	}
+	declared, ok, err := packagelock.UnresolvedComponent(plan.config, comp, "")
+	if err != nil || !ok {
+		return comp
+	}
+	if declared.Declaration() != locked.Declaration() {
+		slog.Warn("component doesn't match the lockfile, so it isn't pinned. Run 'dpm update' to update the lockfile",
+			"component", comp.String(), "locked", locked.Declaration())
+		return comp
+	}
+
+	pinned := *comp
+	switch {
+	case comp.Git != nil:
+		pinned.Commit = locked.Commit
+	case comp.Uri != nil:
+		ref, err := registry.ParseReference(strings.TrimPrefix(declared.URI.String(), "oci://"))
+		if err != nil {
+			return comp
+		}
+		if _, err := ref.Digest(); err == nil {
+			// already pinned in the yaml itself
+			return comp
+		}
+		uri := *comp.Uri + "@" + locked.Digest
+		pinned.Uri = &uri
+	case comp.Version != nil:
+		pinned.Digest = locked.Digest
+	case comp.Range != "":
+		ref, err := registry.ParseReference(strings.TrimPrefix(locked.URI.String(), "oci://"))
+		if err != nil {
+			return comp
+		}
+		v, err := semver.StrictNewVersion(ref.Reference)
+		if err != nil {
+			return comp
+		}
+		pinned.Version = sdkmanifest.AssemblySemVer(v)
+		pinned.Range = ""
+		pinned.Digest = locked.Digest
+	default:
+		return comp
+	}
+	return &pinned
+}
@@ -30,7 +30,7 @@ func Fake(registry *httptest.Server) (*Assembler, error) {
 	if err != nil {
 		return nil, err
 	}
-	if err := config.EnsureDirs(); err != nil {
+	if err := config.EnsureDirs(context.Background()); err != nil {
 		return nil, err
 	}
 	if registry != nil {
@@ -12,11 +12,15 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
+	"strings"
 
 	"daml.com/x/assistant/pkg/assembler"
 	"daml.com/x/assistant/pkg/assembler/assemblyplan"
 	"daml.com/x/assistant/pkg/assistantconfig"
 	"daml.com/x/assistant/pkg/component"
+	"daml.com/x/assistant/pkg/hooks"
+	"daml.com/x/assistant/pkg/jvm"
 	"daml.com/x/assistant/pkg/ocipuller/remotepuller"
 	"daml.com/x/assistant/pkg/resolution"
 	"daml.com/x/assistant/pkg/resolver"
@@ -161,52 +165,115 @@ func (da *DamlAssistant) toCobraCommands(execContext context.Context, config *as
 		return nil, err
 	}
 
-	return lo.Map(cmds, func(c *assembler.ValidatedCommand, _ int) *cobra.Command {
+	// the java version is only checked when the command is run, as completion should be quick (and best-effort anyway)
+	newInvocation := func(c *assembler.ValidatedCommand, checkJavaVersion bool) (*sdkCommandInvocation, error) {
+		inv := &sdkCommandInvocation{
+			extraEnv: map[string]string{
+				assistantconfig.DpmPathInjectedEnvVar: dpmPath,
+			},
+			// env vars that win over the caller's
+			overrideEnv: map[string]string{},
+		}
+
+		switch v := c.Command.(type) {
+		case *component.JarCommand:
+			java, err := findJava(execContext, c, v, checkJavaVersion)
+			if err != nil {
+				return nil, err
+			}
+			inv.binaryPath = java.Path
+			if java.Home != "" {
+				inv.overrideEnv[jvm.JavaHomeEnvVar] = java.Home
+			}
+			inv.launchArgs = append(slices.Clone(v.JvmArgs), "-jar", c.AbsolutePath)
+			inv.commandArgs = v.JarArgs
+		case *component.NativeCommand:
+			inv.binaryPath = c.AbsolutePath
+			inv.commandArgs = v.ExecArgs
+		}
+
+		if c.ResolvedDependencies != nil {
+			maps.Copy(inv.extraEnv, c.ResolvedDependencies)
+		}
+
+		inv.extraEnv[assistantconfig.ResolutionFilePathEnvVar] = deepResolutionFilePath
+		inv.extraEnv[assistantconfig.DpmSdkVersionEnvVar] = c.DpmSdkVersionEnvVar
+
+		// inject DAML_PACKAGE env var into command for their convenience
+		if isDamlPkg {
+			inv.extraEnv[assistantconfig.DamlPackageEnvVar] = filepath.Dir(damlYamlAbsPath)
+		}
+
+		// the component's own env vars
+		maps.Copy(inv.extraEnv, c.Env)
+
+		dir, err := workingDir(c, damlYamlAbsPath, isDamlPkg)
+		if err != nil {
+			return nil, err
+		}
+		inv.dir = dir
+
+		if len(c.PathPrepends) > 0 {
+			paths := append(slices.Clone(c.PathPrepends), filepath.SplitList(os.Getenv("PATH"))...)
+			inv.overrideEnv["PATH"] = strings.Join(paths, string(os.PathListSeparator))
+		}
+		return inv, nil
+	}
+
+	cobraCmds := lo.Map(cmds, func(c *assembler.ValidatedCommand, _ int) *cobra.Command {
 		cmd := &cobra.Command{
 			Use:                c.GetName(),
 			DisableFlagParsing: true,
 			SilenceUsage:       true,
 			Aliases:            c.GetAliases(),
 			RunE: func(cmd *cobra.Command, args []string) error {
-				var binaryPath string
-				var fullArgs []string
-
-				switch v := c.Command.(type) {
-				case *component.JarCommand:
-					binaryPath = "java"
-					fullArgs = append(fullArgs, v.JvmArgs...)
-					fullArgs = append(fullArgs, "-jar")
-					fullArgs = append(fullArgs, c.AbsolutePath)
-					fullArgs = append(fullArgs, v.JarArgs...)
-					fullArgs = append(fullArgs, args...)
-				case *component.NativeCommand:
-					binaryPath = c.AbsolutePath
-					fullArgs = append(fullArgs, v.ExecArgs...)
-					fullArgs = append(fullArgs, args...)
+				args, noHooks, err := stripNoHooks(args)
+				if err != nil {
+					return err
 				}
 
-				extraEnv := map[string]string{
-					assistantconfig.DpmPathInjectedEnvVar: dpmPath,
-				}
-				if c.ResolvedDependencies != nil {
-					maps.Copy(extraEnv, c.ResolvedDependencies)
+				inv, err := newInvocation(c, true)
+				if err != nil {
+					return err
 				}
 
-				extraEnv[assistantconfig.ResolutionFilePathEnvVar] = deepResolutionFilePath
-				extraEnv[assistantconfig.DpmSdkVersionEnvVar] = c.DpmSdkVersionEnvVar
+				var pre, post []*hooks.Hook
+				if !noHooks {
+					pre, post, err = commandHooks(c.FullName(), damlYamlAbsPath, isDamlPkg)
+					if err != nil {
+						return err
+					}
+				}
+				// the hooks get the same env as the command
+				env := commandEnv(inv.extraEnv, inv.overrideEnv)
 
-				// inject DAML_PACKAGE env var into command for their convenience
-				if isDamlPkg {
-					extraEnv[assistantconfig.DamlPackageEnvVar] = filepath.Dir(damlYamlAbsPath)
+				for _, h := range pre {
+					if err := h.Run(execContext, env, da.Stdin, da.Stdout, da.Stderr); err != nil {
+						return err
+					}
 				}
 
-				exitCode, err := da.execSdkCommand(execContext, binaryPath, fullArgs, extraEnv)
-				if err != nil {
-					return err
+				exitCode, runErr := da.execSdkCommand(execContext, inv.binaryPath, inv.args(args...), inv.extraEnv, inv.overrideEnv, inv.dir)
+
+				// post hooks run even if the command failed, or couldn't be started (e.g. to upload its test reports),
+				// but its own failure wins
+				for _, h := range post {
+					if err := h.Run(execContext, env, da.Stdin, da.Stdout, da.Stderr); err != nil {
+						if exitCode == 0 && runErr == nil {
+							return err
+						}
+						slog.Error(err.Error())
+					}
+				}
+				if runErr != nil {
+					return runErr
 				}
 				da.ExitFn(exitCode)
 				return nil
 			},
+			ValidArgsFunction: completionFunc(execContext, c, func(c *assembler.ValidatedCommand) (*sdkCommandInvocation, error) {
+				return newInvocation(c, false)
+			}),
 		}
 		if c.GetDesc() != nil {
 			cmd.Short = *c.GetDesc()
@@ -214,20 +281,114 @@ func (da *DamlAssistant) toCobraCommands(execContext context.Context, config *as
 			cmd.Hidden = true
 		}
 		return cmd
-	}), nil
+	})
+	return nestInGroups(cmds, cobraCmds), nil
 }
 
-func (da *DamlAssistant) execSdkCommand(ctx context.Context, path string, args []string, extraEnv map[string]string) (int, error) {
+// nestInGroups adds each command to (a cobra command for) the group it's nested under, and returns the top-level ones
+func nestInGroups(cmds []*assembler.ValidatedCommand, cobraCmds []*cobra.Command) []*cobra.Command {
+	var topLevel []*cobra.Command
+	groups := map[string]*cobra.Command{} // by the groups' full names
+	add := func(parent, cmd *cobra.Command) {
+		if parent == nil {
+			topLevel = append(topLevel, cmd)
+		} else {
+			parent.AddCommand(cmd)
+		}
+	}
+
+	for i, c := range cmds {
+		var parent *cobra.Command
+		for j, g := range c.Groups {
+			fullName := strings.Join(lo.Map(c.Groups[:j+1], func(g *component.Group, _ int) string { return g.Name }), " ")
+			groupCmd, ok := groups[fullName]
+			if !ok {
+				groupCmd = groupCommand(g)
+				groups[fullName] = groupCmd
+				add(parent, groupCmd)
+			}
+			parent = groupCmd
+		}
+		add(parent, cobraCmds[i])
+	}
+	return topLevel
+}
+
+func groupCommand(g *component.Group) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          g.Name,
+		Aliases:      g.Aliases,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				return fmt.Errorf("unknown command %q for %q", args[0], cmd.CommandPath())
+			}
+			return cmd.Help()
+		},
+	}
+	if g.Desc != nil {
+		cmd.Short = *g.Desc
+	} else {
+		cmd.Hidden = true
+	}
+	return cmd
+}
+
+// sdkCommandInvocation is how a component's command is exec'd
+type sdkCommandInvocation struct {
+	binaryPath string
+	// the args that launch the command, i.e. the jvm's (and the jar) for a jar-command
+	launchArgs []string
+	// the command's own args, i.e. its exec-args or jar-args
+	commandArgs []string
+	extraEnv    map[string]string
+	overrideEnv map[string]string
+	dir         string
+}
+
+// args returns the args to exec the binary with, for the given user args
+func (inv *sdkCommandInvocation) args(userArgs ...string) []string {
+	return slices.Concat(inv.launchArgs, inv.commandArgs, userArgs)
+}
+
+// workingDir returns the dir the command should run in, or "" for dpm's own working dir
+func workingDir(c *assembler.ValidatedCommand, damlYamlAbsPath string, isDamlPkg bool) (string, error) {
+	switch c.GetEnvironment().WorkingDir {
+	case component.WorkingDirPackage:
+		if !isDamlPkg {
+			return "", fmt.Errorf("command %q must be run in a daml package", c.GetName())
+		}
+		return filepath.Dir(damlYamlAbsPath), nil
+	case component.WorkingDirComponent:
+		return c.ComponentPath, nil
+	default:
+		return "", nil
+	}
+}
+
+// findJava returns the java a jar-command runs with, failing unless it satisfies the command's java-version (if checkVersion)
+func findJava(ctx context.Context, c *assembler.ValidatedCommand, jarCmd *component.JarCommand, checkVersion bool) (*jvm.Java, error) {
+	java, err := jvm.Find(c.JavaHome)
+	if err == nil && checkVersion && jarCmd.JavaVersion != "" {
+		err = java.Require(ctx, jarCmd.JavaVersion)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("command %q (from component %q) can't be run: %w. "+
+			"Install a compatible JDK on PATH, or add a %q component to the project",
+			c.GetName(), c.ComponentName, err, jvm.JdkComponentName)
+	}
+	return java, nil
+}
+
+// execSdkCommand runs the command in dir (unless it's "") with extraEnv, which the caller's env takes precedence over,
+// and overrideEnv, which takes precedence over the caller's env
+func (da *DamlAssistant) execSdkCommand(ctx context.Context, path string, args []string, extraEnv, overrideEnv map[string]string, dir string) (int, error) {
 	cmd := exec.CommandContext(ctx, path, args...)
 	cmd.Stdin = da.Stdin
 	cmd.Stdout = da.Stdout
 	cmd.Stderr = da.Stderr
-
-	env := lo.MapToSlice(extraEnv, func(key string, value string) string {
-		return fmt.Sprintf("%s=%s", key, value)
-	})
-	env = append(env, os.Environ()...)
-	cmd.Env = env
+	cmd.Dir = dir
+	cmd.Env = commandEnv(extraEnv, overrideEnv)
 
 	if da.CmdPreRunHook != nil {
 		da.CmdPreRunHook(cmd)
@@ -243,3 +404,15 @@ func (da *DamlAssistant) execSdkCommand(ctx context.Context, path string, args [
 	}
 	return 0, nil
 }
+
+// commandEnv returns the caller's env, on top of extraEnv and under overrideEnv
+func commandEnv(extraEnv, overrideEnv map[string]string) []string {
+	env := lo.MapToSlice(extraEnv, func(key string, value string) string {
+		return fmt.Sprintf("%s=%s", key, value)
+	})
+	env = append(env, os.Environ()...)
+	// last, so that they win
+	return append(env, lo.MapToSlice(overrideEnv, func(key string, value string) string {
+		return fmt.Sprintf("%s=%s", key, value)
+	})...)
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2017-2026 Digital Asset (Switzerland) GmbH and/or its affiliates. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package assistant
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"daml.com/x/assistant/pkg/assembler"
+	"github.com/samber/lo"
+	"github.com/spf13/cobra"
+)
+
+// completionTimeout bounds how long the shell waits on a command that completes its own args
+const completionTimeout = 5 * time.Second
+
+// completionFunc returns how the shell completes the command's args, as declared in its component, nil if they aren't
+func completionFunc(ctx context.Context, c *assembler.ValidatedCommand, newInvocation func(*assembler.ValidatedCommand) (*sdkCommandInvocation, error)) cobra.CompletionFunc {
+	completion := c.GetCompletion()
+	if completion == nil {
+		return nil
+	}
+
+	if completion.Cobra {
+		return func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+			inv, err := newInvocation(c)
+			if err != nil {
+				cobra.CompDebugln(err.Error(), false)
+				return nil, cobra.ShellCompDirectiveDefault
+			}
+			completions, directive, err := proxyCompletion(ctx, inv, args, toComplete)
+			if err != nil {
+				cobra.CompDebugln(fmt.Sprintf("completing %q: %s", c.FullName(), err), false)
+				return nil, cobra.ShellCompDirectiveDefault
+			}
+			return completions, directive
+		}
+	}
+
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+		// like for cobra commands, flags once a '-' is typed, and subcommands only first
+		var candidates []string
+		if strings.HasPrefix(toComplete, "-") {
+			candidates = completion.Flags
+		} else if len(args) == 0 {
+			candidates = completion.Subcommands
+		}
+		completions := lo.Filter(candidates, func(candidate string, _ int) bool {
+			return strings.HasPrefix(candidate, toComplete)
+		})
+		if len(completions) == 0 {
+			// e.g. a file, as the value of a flag
+			return nil, cobra.ShellCompDirectiveDefault
+		}
+		return completions, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// proxyCompletion asks the command to complete its args itself, through cobra's __complete protocol
+func proxyCompletion(ctx context.Context, inv *sdkCommandInvocation, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective, error) {
+	ctx, cancel := context.WithTimeout(ctx, completionTimeout)
+	defer cancel()
+
+	// __complete has to come first, ahead of the command's own args
+	completeArgs := slices.Concat(inv.launchArgs, []string{cobra.ShellCompRequestCmd}, inv.commandArgs, args, []string{toComplete})
+	cmd := exec.CommandContext(ctx, inv.binaryPath, completeArgs...)
+	cmd.Dir = inv.dir
+	cmd.Env = commandEnv(inv.extraEnv, inv.overrideEnv)
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, 0, err
+	}
+	return parseCompletion(out)
+}
+
+// parseCompletion parses the output of a __complete request: a completion per line, then a line with the directive
+func parseCompletion(out []byte) ([]cobra.Completion, cobra.ShellCompDirective, error) {
+	lines := strings.Split(strings.TrimRight(string(bytes.ReplaceAll(out, []byte("\r\n"), []byte("\n"))), "\n"), "\n")
+	last := lines[len(lines)-1]
+	if !strings.HasPrefix(last, ":") {
+		return nil, 0, fmt.Errorf("no directive at the end of the completions: %q", last)
+	}
+	directive, err := strconv.Atoi(last[1:])
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid completion directive %q: %w", last, err)
+	}
+	return lo.Compact(lines[:len(lines)-1]), cobra.ShellCompDirective(directive), nil
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2017-2026 Digital Asset (Switzerland) GmbH and/or its affiliates. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package assistant
+
+import (
+	"path/filepath"
+	"slices"
+
+	"daml.com/x/assistant/pkg/assistantconfig"
+	"daml.com/x/assistant/pkg/damlpackage"
+	"daml.com/x/assistant/pkg/hooks"
+	"daml.com/x/assistant/pkg/multipackage"
+	"daml.com/x/assistant/pkg/utils"
+	"github.com/samber/lo"
+)
+
+// NoHooksFlag disables hooks when given among an SDK command's args (before any --), which it's stripped off of
+const NoHooksFlag = "--no-hooks"
+
+// stripNoHooks strips NoHooksFlag off the command's args, returning whether the hooks are disabled,
+// either by it or by DPM_NO_HOOKS
+func stripNoHooks(args []string) ([]string, bool, error) {
+	disabled, _, err := utils.BoolEnvVar(assistantconfig.NoHooksEnvVar)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// past --, the args are the command's only
+	end := lo.IndexOf(args, "--")
+	if end < 0 {
+		end = len(args)
+	}
+	stripped := slices.Concat(lo.Without(args[:end], NoHooksFlag), args[end:])
+	return stripped, disabled || len(stripped) < len(args), nil
+}
+
+// commandHooks returns the hooks to run before and after the given SDK command, with multi-package.yaml's
+// running around daml.yaml's
+func commandHooks(command, damlYamlAbsPath string, isDamlPkg bool) (pre, post []*hooks.Hook, err error) {
+	multiPackagePath, isMultiPackage, err := assistantconfig.GetMultiPackageAbsolutePath()
+	if err != nil {
+		return nil, nil, err
+	}
+	if isMultiPackage {
+		m, err := multipackage.Read(multiPackagePath)
+		if err != nil {
+			return nil, nil, err
+		}
+		pre = m.Hooks.Pre(command, filepath.Dir(multiPackagePath))
+		post = m.Hooks.Post(command, filepath.Dir(multiPackagePath))
+	}
+
+	if isDamlPkg {
+		p, err := damlpackage.Read(damlYamlAbsPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		pre = append(pre, p.Hooks.Pre(command, filepath.Dir(damlYamlAbsPath))...)
+		post = append(p.Hooks.Post(command, filepath.Dir(damlYamlAbsPath)), post...)
+	}
+	return pre, post, nil
+}
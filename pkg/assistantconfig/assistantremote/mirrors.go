@@ -0,0 +1,90 @@
+// Copyright (c) 2017-2026 Digital Asset (Switzerland) GmbH and/or its affiliates. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package assistantremote
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"daml.com/x/assistant/pkg/assistantconfig"
+	"oras.land/oras-go/v2/registry"
+	"oras.land/oras-go/v2/registry/remote/auth"
+)
+
+// doMirrored sends pulls of artifacts that have registry mirrors configured to those mirrors, in order,
+// moving on to the next mirror if one is unreachable or doesn't have the artifact.
+// All other requests (including pushes) go to the registry they're addressed to
+func (c *Remote) doMirrored(req *http.Request, mirrors assistantconfig.RegistryMirrors) (*http.Response, error) {
+	rr, ok := parseRegistryRequest(req)
+	if !ok || (req.Method != http.MethodGet && req.Method != http.MethodHead) {
+		return c.client.Do(req)
+	}
+	targets := mirrors.MirrorsFor(rr.name)
+	if len(targets) == 0 {
+		return c.client.Do(req)
+	}
+
+	for _, target := range targets[:len(targets)-1] {
+		resp, err := c.doVia(req, rr, target)
+		if !shouldTryNextMirror(resp, err) {
+			return resp, err
+		}
+
+		attrs := []any{"mirror", target.Name}
+		if err != nil {
+			attrs = append(attrs, "err", err.Error())
+		} else {
+			attrs = append(attrs, "status", resp.StatusCode)
+			_ = resp.Body.Close()
+		}
+		slog.Debug("registry mirror failed, trying the next one", attrs...)
+	}
+	return c.doVia(req, rr, targets[len(targets)-1])
+}
+
+func (c *Remote) doVia(req *http.Request, rr *registryRequest, target assistantconfig.MirrorTarget) (*http.Response, error) {
+	mirrorReq, err := rewriteRequest(req, rr, target)
+	if err != nil {
+		return nil, err
+	}
+	slog.Debug("OCI request via mirror", "method", req.Method, "url", req.URL.String(), "mirror", mirrorReq.URL.String())
+	return c.client.Do(mirrorReq)
+}
+
+// rewriteRequest addresses req to the mirror. The mirror's repository is added to the auth scopes,
+// so that a token for it (rather than for the upstream repository) is requested
+func rewriteRequest(req *http.Request, rr *registryRequest, target assistantconfig.MirrorTarget) (*http.Request, error) {
+	host, repo, ok := strings.Cut(target.Name, "/")
+	if !ok {
+		return nil, errors.New("registry mirror " + target.Name + " has no repository")
+	}
+	_, upstreamRepo, _ := strings.Cut(rr.name, "/")
+
+	ctx := auth.AppendRepositoryScope(req.Context(), registry.Reference{Registry: host, Repository: repo}, auth.ActionPull)
+	mirrorReq := req.Clone(ctx)
+	u := *req.URL
+	u.Host = host
+	u.Path = "/v2/" + repo + strings.TrimPrefix(req.URL.Path, "/v2/"+upstreamRepo)
+	u.RawPath = ""
+	if target.Scheme != "" {
+		u.Scheme = target.Scheme
+	}
+	mirrorReq.URL = &u
+	mirrorReq.Host = ""
+	return mirrorReq, nil
+}
+
+func shouldTryNextMirror(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	switch resp.StatusCode {
+	case http.StatusNotFound, http.StatusUnauthorized, http.StatusForbidden:
+		return true
+	}
+	return resp.StatusCode >= 500
+}
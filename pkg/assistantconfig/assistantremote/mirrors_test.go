@@ -0,0 +1,68 @@
+// Copyright (c) 2017-2026 Digital Asset (Switzerland) GmbH and/or its affiliates. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package assistantremote
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"daml.com/x/assistant/pkg/assistantconfig"
+	"github.com/opencontainers/go-digest"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPullsGoThroughMirrorsInOrder(t *testing.T) {
+	manifest := []byte(`{"schemaVersion":2,"mediaType":"` + v1.MediaTypeImageManifest + `"}`)
+
+	var emptyMirrorCalls atomic.Int32
+	emptyMirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		emptyMirrorCalls.Add(1)
+		http.NotFound(w, r)
+	}))
+	t.Cleanup(emptyMirror.Close)
+
+	var servedPath string
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		servedPath = r.URL.Path
+		w.Header().Set("Content-Type", v1.MediaTypeImageManifest)
+		w.Header().Set("Docker-Content-Digest", digest.FromBytes(manifest).String())
+		w.Header().Set("Content-Length", strconv.Itoa(len(manifest)))
+		_, _ = w.Write(manifest)
+	}))
+	t.Cleanup(mirror.Close)
+
+	Configure(&assistantconfig.Config{
+		RegistryMaxAttempts: 1,
+		RegistryMirrors: assistantconfig.RegistryMirrors{
+			{Prefix: "upstream.invalid", Mirrors: []string{"http://" + emptyMirror.Listener.Addr().String() + "/nothing"}},
+			{
+				Prefix: "upstream.invalid/da/public",
+				Mirrors: []string{
+					"http://" + emptyMirror.Listener.Addr().String() + "/nothing",
+					"http://" + mirror.Listener.Addr().String() + "/mirrored/",
+				},
+			},
+		},
+	})
+	t.Cleanup(func() {
+		Configure(&assistantconfig.Config{RegistryMaxAttempts: assistantconfig.DefaultRegistryMaxAttempts})
+	})
+
+	r, err := New("upstream.invalid/da/public", "", false)
+	require.NoError(t, err)
+	repo, err := r.Repo("components/foo")
+	require.NoError(t, err)
+
+	desc, err := repo.Resolve(context.Background(), "1.0")
+	require.NoError(t, err)
+	assert.Equal(t, digest.FromBytes(manifest), desc.Digest)
+	assert.Equal(t, "/v2/mirrored/components/foo/manifests/1.0", servedPath)
+	assert.EqualValues(t, 1, emptyMirrorCalls.Load())
+}
@@ -0,0 +1,277 @@
+// Copyright (c) 2017-2026 Digital Asset (Switzerland) GmbH and/or its affiliates. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package assistantremote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"daml.com/x/assistant/pkg/assistantconfig"
+	"daml.com/x/assistant/pkg/ocicache"
+	"github.com/opencontainers/go-digest"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/content/oci"
+	"oras.land/oras-go/v2/errdef"
+)
+
+// ErrOffline is matched (via errors.Is) by every OfflineError
+var ErrOffline = errors.New("dpm is offline")
+
+// OfflineError is returned for registry requests that can't be served from the local cache while dpm is offline
+type OfflineError struct {
+	// e.g. "<registry>/<repo>:<tag>" or "<registry>/<repo>@<digest>"
+	Artifact string
+}
+
+func (e *OfflineError) Error() string {
+	return fmt.Sprintf("%s isn't in the local cache, and can't be fetched because dpm is offline (%s or 'offline: true' in %s)",
+		e.Artifact, assistantconfig.OfflineEnvVar, assistantconfig.DpmConfigFileName)
+}
+
+func (e *OfflineError) Is(target error) bool {
+	return target == ErrOffline
+}
+
+// maxManifestBytes is the largest manifest that gets recorded in the oci-layout cache when resolving a tag
+const maxManifestBytes = 4 * 1024 * 1024
+
+var (
+	manifestPathRegex = regexp.MustCompile(`^/v2/(.+)/(manifests|blobs)/([^/]+)$`)
+	tagsPathRegex     = regexp.MustCompile(`^/v2/(.+)/tags/list$`)
+)
+
+// registryRequest is the part of a registry API request that identifies the artifact
+type registryRequest struct {
+	// registry host + repository
+	name string
+	// "manifests", "blobs" or "tags"
+	kind      string
+	reference string
+}
+
+func parseRegistryRequest(req *http.Request) (*registryRequest, bool) {
+	if m := manifestPathRegex.FindStringSubmatch(req.URL.Path); m != nil {
+		return &registryRequest{name: req.URL.Host + "/" + m[1], kind: m[2], reference: m[3]}, true
+	}
+	if m := tagsPathRegex.FindStringSubmatch(req.URL.Path); m != nil {
+		return &registryRequest{name: req.URL.Host + "/" + m[1], kind: "tags"}, true
+	}
+	return nil, false
+}
+
+func (r *registryRequest) artifact() string {
+	switch {
+	case r.kind == "tags":
+		return "the tags of " + r.name
+	case isDigest(r.reference):
+		return r.name + "@" + r.reference
+	default:
+		return r.name + ":" + r.reference
+	}
+}
+
+// tagKey is the ref name the tag is recorded under in the oci-layout cache.
+// It's qualified with the registry and repository, as the cache is shared by all of them
+func (r *registryRequest) tagKey() string {
+	return r.name + ":" + r.reference
+}
+
+func isDigest(reference string) bool {
+	_, err := digest.Parse(reference)
+	return err == nil
+}
+
+// serveOffline answers a registry request from the oci-layout cache, without touching the network
+func serveOffline(req *http.Request, ociLayout string) (*http.Response, error) {
+	rr, ok := parseRegistryRequest(req)
+	if !ok {
+		return nil, &OfflineError{Artifact: req.URL.String()}
+	}
+	if ociLayout == "" || (req.Method != http.MethodGet && req.Method != http.MethodHead) {
+		return nil, &OfflineError{Artifact: rr.artifact()}
+	}
+
+	store, err := ocicache.Store(ociLayout)
+	if err != nil {
+		return nil, err
+	}
+	ctx := req.Context()
+
+	if rr.kind == "tags" {
+		var tags []string
+		err := store.Tags(ctx, "", func(page []string) error {
+			for _, t := range page {
+				if tag, ok := strings.CutPrefix(t, rr.name+":"); ok {
+					tags = append(tags, tag)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(tags) == 0 {
+			return nil, &OfflineError{Artifact: rr.artifact()}
+		}
+		b, err := json.Marshal(map[string]any{"name": strings.SplitN(rr.name, "/", 2)[1], "tags": tags})
+		if err != nil {
+			return nil, err
+		}
+		return offlineResponse(req, "application/json", "", b), nil
+	}
+
+	key := rr.reference
+	if !isDigest(key) {
+		if rr.kind == "blobs" {
+			return nil, &OfflineError{Artifact: rr.artifact()}
+		}
+		key = rr.tagKey()
+	}
+	desc, err := store.Resolve(ctx, key)
+	if errors.Is(err, errdef.ErrNotFound) {
+		return nil, &OfflineError{Artifact: rr.artifact()}
+	} else if err != nil {
+		return nil, err
+	}
+
+	b, err := content.FetchAll(ctx, store, desc)
+	if errors.Is(err, errdef.ErrNotFound) {
+		return nil, &OfflineError{Artifact: rr.artifact()}
+	} else if err != nil {
+		return nil, err
+	}
+
+	mediaType := desc.MediaType
+	if rr.kind == "manifests" && (mediaType == "" || mediaType == "application/octet-stream") {
+		// resolved as a plain blob, so the media type has to come from the manifest itself
+		var m struct {
+			MediaType string `json:"mediaType"`
+		}
+		if err := json.Unmarshal(b, &m); err != nil || m.MediaType == "" {
+			return nil, &OfflineError{Artifact: rr.artifact()}
+		}
+		mediaType = m.MediaType
+	}
+
+	slog.Debug("OCI request served from the local cache", "method", req.Method, "url", req.URL.String())
+	return offlineResponse(req, mediaType, desc.Digest.String(), b), nil
+}
+
+func offlineResponse(req *http.Request, mediaType, dgst string, b []byte) *http.Response {
+	header := http.Header{}
+	header.Set("Content-Type", mediaType)
+	header.Set("Content-Length", strconv.Itoa(len(b)))
+	if dgst != "" {
+		header.Set("Docker-Content-Digest", dgst)
+	}
+
+	var body io.ReadCloser = http.NoBody
+	if req.Method != http.MethodHead {
+		body = io.NopCloser(bytes.NewReader(b))
+	}
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          body,
+		ContentLength: int64(len(b)),
+		Request:       req,
+	}
+}
+
+// recordTag stores the manifest a tag was resolved to in the oci-layout cache, and tags it there,
+// so that the tag can be resolved while offline. Failing to do so doesn't fail the request
+func recordTag(req *http.Request, resp *http.Response, ociLayout string) error {
+	if ociLayout == "" || resp.StatusCode != http.StatusOK || (req.Method != http.MethodGet && req.Method != http.MethodHead) {
+		return nil
+	}
+	rr, ok := parseRegistryRequest(req)
+	if !ok || rr.kind != "manifests" || isDigest(rr.reference) {
+		return nil
+	}
+
+	store, err := ocicache.Store(ociLayout)
+	if err != nil {
+		return err
+	}
+
+	mediaType := resp.Header.Get("Content-Type")
+	d, _ := digest.Parse(resp.Header.Get("Docker-Content-Digest"))
+	if req.Method == http.MethodHead {
+		if d == "" || isTagged(req.Context(), store, rr.tagKey(), d) {
+			return nil
+		}
+		// can only be tagged if the manifest was cached by an earlier request
+		err := store.Tag(req.Context(), v1.Descriptor{MediaType: mediaType, Digest: d, Size: resp.ContentLength}, rr.tagKey())
+		if errors.Is(err, errdef.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	if resp.ContentLength > maxManifestBytes {
+		return nil
+	}
+	b, err := io.ReadAll(io.LimitReader(resp.Body, maxManifestBytes+1))
+	_ = resp.Body.Close()
+	resp.Body = io.NopCloser(io.MultiReader(bytes.NewReader(b), &errReader{err: err}))
+	if err != nil || len(b) > maxManifestBytes {
+		return err
+	}
+
+	desc := v1.Descriptor{MediaType: mediaType, Digest: digest.FromBytes(b), Size: int64(len(b))}
+	if d != "" && d != desc.Digest {
+		// let the caller's own verification complain about it
+		return nil
+	}
+	return pushAndTag(req.Context(), store, desc, b, rr.tagKey())
+}
+
+func pushAndTag(ctx context.Context, store *oci.Store, desc v1.Descriptor, b []byte, ref string) error {
+	exists, err := store.Exists(ctx, desc)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		if err := store.Push(ctx, desc, bytes.NewReader(b)); err != nil && !errors.Is(err, errdef.ErrAlreadyExists) {
+			return err
+		}
+	}
+	if isTagged(ctx, store, ref, desc.Digest) {
+		return nil
+	}
+	return store.Tag(ctx, desc, ref)
+}
+
+// isTagged is whether ref already points at d in the oci-layout cache, in which case tagging it again
+// would only rewrite the index
+func isTagged(ctx context.Context, store *oci.Store, ref string, d digest.Digest) bool {
+	desc, err := store.Resolve(ctx, ref)
+	return err == nil && desc.Digest == d
+}
+
+// errReader returns err (or EOF, if there's none) once the buffered part of a body has been read
+type errReader struct {
+	err error
+}
+
+func (e *errReader) Read([]byte) (int, error) {
+	if e.err != nil {
+		return 0, e.err
+	}
+	return 0, io.EOF
+}
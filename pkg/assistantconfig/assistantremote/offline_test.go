@@ -0,0 +1,105 @@
+// Copyright (c) 2017-2026 Digital Asset (Switzerland) GmbH and/or its affiliates. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package assistantremote
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"daml.com/x/assistant/pkg/assistantconfig"
+	"github.com/opencontainers/go-digest"
+	"github.com/opencontainers/image-spec/specs-go"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOfflineServesRecordedTags(t *testing.T) {
+	manifest, err := json.Marshal(v1.Manifest{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		MediaType: v1.MediaTypeImageManifest,
+		Config:    v1.DescriptorEmptyJSON,
+		Layers:    []v1.Descriptor{},
+	})
+	require.NoError(t, err)
+	manifestDigest := digest.FromBytes(manifest)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/test/comp/manifests/1.0" && r.URL.Path != "/v2/test/comp/manifests/"+manifestDigest.String() {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", v1.MediaTypeImageManifest)
+		w.Header().Set("Docker-Content-Digest", manifestDigest.String())
+		w.Header().Set("Content-Length", strconv.Itoa(len(manifest)))
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(manifest)
+		}
+	}))
+	t.Cleanup(server.Close)
+	host := server.Listener.Addr().String()
+
+	config := &assistantconfig.Config{OciLayoutCache: t.TempDir(), RegistryMaxAttempts: 1}
+	Configure(config)
+	t.Cleanup(func() {
+		Configure(&assistantconfig.Config{RegistryMaxAttempts: assistantconfig.DefaultRegistryMaxAttempts})
+	})
+
+	r, err := New(host, "", true)
+	require.NoError(t, err)
+	repo, err := r.Repo("test/comp")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	online, rc, err := repo.FetchReference(ctx, "1.0")
+	require.NoError(t, err)
+	_, err = io.ReadAll(rc)
+	require.NoError(t, err)
+	require.NoError(t, rc.Close())
+
+	// resolving the same tag to the same manifest again doesn't rewrite the index
+	indexPath := filepath.Join(config.OciLayoutCache, "index.json")
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+	require.NoError(t, os.Chtimes(indexPath, past, past))
+	_, err = repo.Resolve(ctx, "1.0")
+	require.NoError(t, err)
+	_, rc, err = repo.FetchReference(ctx, "1.0")
+	require.NoError(t, err)
+	require.NoError(t, rc.Close())
+	info, err := os.Stat(indexPath)
+	require.NoError(t, err)
+	assert.Equal(t, past, info.ModTime())
+
+	server.Close()
+	config.Offline = true
+	Configure(config)
+
+	desc, err := repo.Resolve(ctx, "1.0")
+	require.NoError(t, err)
+	assert.Equal(t, online.Digest, desc.Digest)
+	assert.Equal(t, v1.MediaTypeImageManifest, desc.MediaType)
+
+	var tags []string
+	require.NoError(t, repo.Tags(ctx, "", func(page []string) error {
+		tags = append(tags, page...)
+		return nil
+	}))
+	assert.Equal(t, []string{"1.0"}, tags)
+
+	_, err = repo.Resolve(ctx, "2.0")
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrOffline))
+	var offlineErr *OfflineError
+	require.True(t, errors.As(err, &offlineErr))
+	assert.Equal(t, host+"/test/comp:2.0", offlineErr.Artifact)
+}
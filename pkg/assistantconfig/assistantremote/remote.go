@@ -55,8 +55,12 @@ func NewWithCustomClient(registry string, client *auth.Client, insecure bool) *R
 }
 
 func New(registry string, authConfigPath string, insecure bool) (*Remote, error) {
-	// This client has some default caching (e.g. for auth tokens) and retry settings
-	client := auth.DefaultClient
+	// A fresh client per Remote, so that credentials aren't shared through auth.DefaultClient,
+	// but with the default (process-wide) auth token cache, and dpm's own retry policy
+	client := &auth.Client{
+		Client: &http.Client{Transport: newRetryTransport()},
+		Cache:  auth.DefaultCache,
+	}
 	client.SetUserAgent(assistantconfig.GetAssistantUserAgent())
 
 	if authConfigPath != "" {
@@ -86,8 +90,21 @@ func New(registry string, authConfigPath string, insecure bool) (*Remote, error)
 var _ remote.Client = (*Remote)(nil)
 
 func (c *Remote) Do(req *http.Request) (*http.Response, error) {
+	settings := currentSettings()
+	if settings.offline {
+		return serveOffline(req, settings.ociLayoutCache)
+	}
+
 	slog.Debug("OCI request", "method", req.Method, "url", req.URL.String())
-	return c.client.Do(req)
+	resp, err := c.doMirrored(req, settings.mirrors)
+	if err != nil {
+		return nil, err
+	}
+	// tags are recorded under their upstream name, even if they were resolved via a mirror
+	if err := recordTag(req, resp, settings.ociLayoutCache); err != nil {
+		slog.Debug("failed to record tag in the oci-layout cache", "url", req.URL.String(), "err", err.Error())
+	}
+	return resp, nil
 }
 
 func NewFromConfig(config *assistantconfig.Config) (*Remote, error) {
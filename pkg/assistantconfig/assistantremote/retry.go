@@ -0,0 +1,142 @@
+// Copyright (c) 2017-2026 Digital Asset (Switzerland) GmbH and/or its affiliates. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package assistantremote
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"daml.com/x/assistant/pkg/assistantconfig"
+	"oras.land/oras-go/v2/registry/remote/retry"
+)
+
+const (
+	baseBackoff = 500 * time.Millisecond
+	maxBackoff  = 30 * time.Second
+	// upper bound on how long a registry's Retry-After can make us wait
+	maxRetryAfter = 2 * time.Minute
+	// how long a plain http(s) download waits for the server to start responding
+	responseHeaderTimeout = 30 * time.Second
+)
+
+var maxAttempts atomic.Int64
+
+func init() {
+	maxAttempts.Store(assistantconfig.DefaultRegistryMaxAttempts)
+}
+
+// SetMaxAttempts sets how many times a registry request is attempted in total (i.e. including the first try),
+// process-wide. Values below 1 are treated as 1
+func SetMaxAttempts(n int) {
+	maxAttempts.Store(int64(max(n, 1)))
+}
+
+// retryPolicy retries timeouts, dropped connections, 408, 429 and most 5xx responses
+// with exponential backoff, unless the registry says how long to wait via Retry-After
+type retryPolicy struct {
+	maxAttempts int
+}
+
+var _ retry.Policy = (*retryPolicy)(nil)
+
+func newRetryTransport() http.RoundTripper {
+	return &retry.Transport{Policy: newRetryPolicy}
+}
+
+func newRetryPolicy() retry.Policy {
+	return &retryPolicy{maxAttempts: int(maxAttempts.Load())}
+}
+
+// NewHttpClient returns a client for plain http(s) downloads (e.g. of dars) that retries like registry requests do,
+// and gives up on servers that don't start responding. base is the transport it retries over, nil for the default one
+func NewHttpClient(base http.RoundTripper) *http.Client {
+	if base == nil {
+		t := http.DefaultTransport.(*http.Transport).Clone()
+		t.ResponseHeaderTimeout = responseHeaderTimeout
+		base = t
+	}
+	return &http.Client{
+		Transport: &retry.Transport{Base: base, Policy: newRetryPolicy},
+	}
+}
+
+func (p *retryPolicy) Retry(attempt int, resp *http.Response, err error) (time.Duration, error) {
+	// attempt is 0-based, and counts the attempts made so far minus one
+	if attempt+1 >= p.maxAttempts || !isRetryable(resp, err) {
+		return -1, nil
+	}
+
+	wait := backoff(attempt)
+	if d, ok := retryAfter(resp); ok {
+		wait = d
+	}
+
+	attrs := []any{"attempt", attempt + 1, "max-attempts", p.maxAttempts, "wait", wait.String()}
+	if err != nil {
+		attrs = append(attrs, "err", err.Error())
+	} else {
+		attrs = append(attrs, "status", resp.StatusCode, "url", resp.Request.URL.String())
+	}
+	slog.Debug("retrying registry request", attrs...)
+	return wait, nil
+}
+
+func isRetryable(resp *http.Response, err error) bool {
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return false
+		}
+		var netErr net.Error
+		return (errors.As(err, &netErr) && netErr.Timeout()) ||
+			errors.Is(err, io.ErrUnexpectedEOF) ||
+			errors.Is(err, syscall.ECONNRESET) ||
+			errors.Is(err, syscall.ECONNREFUSED)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return true
+	case http.StatusNotImplemented, http.StatusHTTPVersionNotSupported:
+		return false
+	}
+	return resp.StatusCode == 0 || resp.StatusCode >= 500
+}
+
+// backoff is exponential, with +/- 20% jitter
+func backoff(attempt int) time.Duration {
+	d := float64(baseBackoff) * math.Pow(2, float64(attempt))
+	d = min(d, float64(maxBackoff))
+	return time.Duration(d * (0.8 + 0.4*rand.Float64()))
+}
+
+// retryAfter parses the Retry-After header, given either in seconds or as an HTTP date
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+
+	var d time.Duration
+	if secs, err := strconv.Atoi(v); err == nil {
+		d = time.Duration(secs) * time.Second
+	} else if t, err := http.ParseTime(v); err == nil {
+		d = time.Until(t)
+	} else {
+		return 0, false
+	}
+	return min(max(d, 0), maxRetryAfter), true
+}
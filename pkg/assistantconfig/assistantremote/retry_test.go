@@ -0,0 +1,98 @@
+// Copyright (c) 2017-2026 Digital Asset (Switzerland) GmbH and/or its affiliates. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package assistantremote
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"daml.com/x/assistant/pkg/assistantconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryHonoursRetryAfter(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, "slow down", http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte(expectedSuccessBody))
+	}))
+	t.Cleanup(server.Close)
+
+	r, err := New(server.Listener.Addr().String(), "", true)
+	require.NoError(t, err)
+
+	resp, err := r.Do(mustRequest(t, server.URL))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.EqualValues(t, 3, calls.Load())
+}
+
+func TestRetryGivesUpAfterMaxAttempts(t *testing.T) {
+	SetMaxAttempts(2)
+	t.Cleanup(func() { SetMaxAttempts(assistantconfig.DefaultRegistryMaxAttempts) })
+
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Retry-After", "0")
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(server.Close)
+
+	r, err := New(server.Listener.Addr().String(), "", true)
+	require.NoError(t, err)
+
+	resp, err := r.Do(mustRequest(t, server.URL))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.EqualValues(t, 2, calls.Load())
+}
+
+func TestRetryPolicy(t *testing.T) {
+	p := &retryPolicy{maxAttempts: 3}
+	resp := func(status int, retryAfter string) *http.Response {
+		r := &http.Response{StatusCode: status, Header: http.Header{}, Request: mustRequest(t, "http://example.com")}
+		if retryAfter != "" {
+			r.Header.Set("Retry-After", retryAfter)
+		}
+		return r
+	}
+
+	d, err := p.Retry(0, resp(http.StatusNotFound, ""), nil)
+	require.NoError(t, err)
+	assert.Negative(t, d, "4xx responses aren't retried")
+
+	d, err = p.Retry(0, resp(http.StatusNotImplemented, ""), nil)
+	require.NoError(t, err)
+	assert.Negative(t, d)
+
+	d, err = p.Retry(1, resp(http.StatusBadGateway, ""), nil)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, d, 800*time.Millisecond)
+	assert.LessOrEqual(t, d, 1200*time.Millisecond)
+
+	d, err = p.Retry(0, resp(http.StatusServiceUnavailable, "7"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, 7*time.Second, d)
+
+	d, err = p.Retry(2, resp(http.StatusServiceUnavailable, ""), nil)
+	require.NoError(t, err)
+	assert.Negative(t, d, "out of attempts")
+}
+
+func mustRequest(t *testing.T, url string) *http.Request {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+	return req
+}
@@ -0,0 +1,43 @@
+// Copyright (c) 2017-2026 Digital Asset (Switzerland) GmbH and/or its affiliates. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package assistantremote
+
+import (
+	"sync"
+
+	"daml.com/x/assistant/pkg/assistantconfig"
+)
+
+var (
+	settingsMu sync.RWMutex
+	current    settings
+)
+
+// settings are the parts of the config that apply to every Remote
+type settings struct {
+	offline        bool
+	ociLayoutCache string
+	mirrors        assistantconfig.RegistryMirrors
+}
+
+// Configure applies config's registry settings process-wide.
+// Once it's been called, the tags resolved against a registry are recorded in the oci-layout cache,
+// so that they can still be resolved when offline
+func Configure(config *assistantconfig.Config) {
+	SetMaxAttempts(config.RegistryMaxAttempts)
+
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	current = settings{
+		offline:        config.Offline,
+		ociLayoutCache: config.OciLayoutCache,
+		mirrors:        config.RegistryMirrors,
+	}
+}
+
+func currentSettings() settings {
+	settingsMu.RLock()
+	defer settingsMu.RUnlock()
+	return current
+}
@@ -4,6 +4,7 @@
 package assistantconfig
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -13,6 +14,7 @@ import (
 
 	"daml.com/x/assistant/cmd/dpm/cmd/resolve/resolutionerrors"
 	"daml.com/x/assistant/pkg/cacheindex"
+	"daml.com/x/assistant/pkg/knownprojects"
 	"daml.com/x/assistant/pkg/sdkmanifest"
 	"oras.land/oras-go/v2/registry"
 
@@ -50,6 +52,8 @@ type Config struct {
 	InstallLocalFilePath string `yaml:"-"`
 
 	AutoInstall bool `yaml:"auto-install,omitempty"`
+	// Offline makes dpm serve every registry operation from the local cache, and never touch the network
+	Offline bool `yaml:"offline,omitempty"`
 
 	// Edition defaults to open-source
 	Edition *LazyEdition `yaml:"edition,omitempty"`
@@ -57,8 +61,17 @@ type Config struct {
 	Registry         string `yaml:"registry,omitempty"`
 	RegistryAuthPath string `yaml:"registry-auth-path,omitempty"`
 	Insecure         bool   `yaml:"insecure,omitempty"`
+	// pulls from registries matching a rule's prefix go through its mirrors instead
+	RegistryMirrors RegistryMirrors `yaml:"registry-mirrors,omitempty"`
+
+	// maximum number of components pulled in parallel, defaults to DefaultPullConcurrency
+	PullConcurrency int `yaml:"pull-concurrency,omitempty"`
+	// how many times a registry request is attempted before giving up, defaults to DefaultRegistryMaxAttempts
+	RegistryMaxAttempts int `yaml:"registry-max-attempts,omitempty"`
 
 	CacheIndex *cacheindex.CacheIndex `yaml:"-"`
+	// project dirs that dpm has installed components or dars for
+	KnownProjects *knownprojects.KnownProjects `yaml:"-"`
 }
 
 func (c *Config) SdkManifestsRepo() (string, error) {
@@ -69,7 +82,7 @@ func (c *Config) SdkManifestsRepo() (string, error) {
 	return e.SdkManifestsRepo()
 }
 
-func (c *Config) EnsureDirs() error {
+func (c *Config) EnsureDirs(ctx context.Context) error {
 	err := utils.EnsureDirs(c.DamlHomePath, c.OciLayoutCache,
 		filepath.Join(c.InstalledSdkManifestsPath, sdkmanifest.Enterprise.String()),
 		filepath.Join(c.InstalledSdkManifestsPath, sdkmanifest.Private.String()),
@@ -78,7 +91,7 @@ func (c *Config) EnsureDirs() error {
 		return err
 	}
 
-	return c.CacheIndex.Init()
+	return c.CacheIndex.Init(ctx)
 }
 
 func Get() (*Config, error) {
@@ -133,6 +146,14 @@ func GetWithCustomDamlHome(dpmHomePath string) (*Config, error) {
 		config.AutoInstall = autoInstall
 	}
 
+	offline, ok, err := utils.BoolEnvVar(OfflineEnvVar)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		config.Offline = offline
+	}
+
 	registry, ok := os.LookupEnv(OciRegistryEnvVar)
 	if ok {
 		config.Registry = registry
@@ -156,6 +177,40 @@ func GetWithCustomDamlHome(dpmHomePath string) (*Config, error) {
 		config.Insecure = insecure
 	}
 
+	pullConcurrency, ok, err := utils.IntEnvVar(PullConcurrencyEnvVar)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		config.PullConcurrency = pullConcurrency
+	}
+	if config.PullConcurrency < 0 {
+		return nil, fmt.Errorf("pull concurrency must not be negative, got %d", config.PullConcurrency)
+	}
+	if config.PullConcurrency == 0 {
+		config.PullConcurrency = DefaultPullConcurrency
+	}
+
+	registryMaxAttempts, ok, err := utils.IntEnvVar(RegistryMaxAttemptsEnvVar)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		config.RegistryMaxAttempts = registryMaxAttempts
+	}
+	if config.RegistryMaxAttempts < 0 {
+		return nil, fmt.Errorf("registry max attempts must not be negative, got %d", config.RegistryMaxAttempts)
+	}
+	if config.RegistryMaxAttempts == 0 {
+		config.RegistryMaxAttempts = DefaultRegistryMaxAttempts
+	}
+
+	for _, m := range config.RegistryMirrors {
+		if err := m.validate(); err != nil {
+			return nil, fmt.Errorf("%s: %w", configFilePath, err)
+		}
+	}
+
 	cacheDir := filepath.Join(dpmHomePath, "cache")
 	config.DamlHomePath = dpmHomePath
 	config.CachePath = cacheDir
@@ -163,7 +218,12 @@ func GetWithCustomDamlHome(dpmHomePath string) (*Config, error) {
 	config.InstalledSdkManifestsPath = filepath.Join(cacheDir, "sdk")
 	config.InstallLocalFilePath = filepath.Join(config.InstalledSdkManifestsPath, ".lock")
 	config.CacheIndex = &cacheindex.CacheIndex{
-		AbsolutePath: filepath.Join(cacheDir, "index.json"),
+		AbsolutePath:   filepath.Join(cacheDir, "index.json"),
+		ComponentsPath: filepath.Join(cacheDir, "components"),
+		OciLayoutPath:  config.OciLayoutCache,
+	}
+	config.KnownProjects = &knownprojects.KnownProjects{
+		AbsolutePath: filepath.Join(cacheDir, "known-projects.json"),
 	}
 	return &config, nil
 }
@@ -331,6 +391,28 @@ func GetDamlPackageAbsolutePath() (damlYamlAbsPath string, found bool, err error
 	return
 }
 
+// GetDamlPackageOrMultiPackageAbsolutePath returns the absolute path of the daml.yaml in scope or, if there's none,
+// of the multi-package.yaml in scope. It fails outside of a (single-package or multi-package) project
+func GetDamlPackageOrMultiPackageAbsolutePath() (damlYamlAbsPath string, multiPackageAbsPath string, err error) {
+	p, ok, err := GetDamlPackageAbsolutePath()
+	if err != nil {
+		return "", "", err
+	}
+	if ok {
+		return p, "", nil
+	}
+
+	p, ok, err = GetMultiPackageAbsolutePath()
+	if err != nil {
+		return "", "", err
+	}
+	if ok {
+		return "", p, nil
+	}
+
+	return "", "", fmt.Errorf("not in a (single-package or multi-package) project directory")
+}
+
 func getDamlPackageAbsolutePath() (string, bool, error) {
 	// DAML_PACKAGE env var takes precedence
 	damlYamlPath, ok := os.LookupEnv(DamlPackageEnvVar)
@@ -400,3 +482,7 @@ func (c *Config) CachePathForDar(ref *registry.Reference) string {
 	}
 	return filepath.Join(c.CachePath, "dars", utils.UrlToFilePath(fmt.Sprintf("%s/%s", ref.Registry, ref.Repository)), ref.Reference)
 }
+
+func (c *Config) CachePathForComponent(componentUri string, tag string) string {
+	return filepath.Join(c.CachePath, "components", utils.UrlToFilePath(componentUri), tag)
+}
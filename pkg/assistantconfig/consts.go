@@ -15,6 +15,10 @@ const (
 
 	DpmConfigFileName = "dpm-config.yaml"
 
+	DefaultPullConcurrency = 4
+
+	DefaultRegistryMaxAttempts = 5
+
 	DpmPathInjectedEnvVar = "DPM_BIN_PATH"
 
 	// BlankSdkVersion this will be the value of the DPM_SDK_VERSION env var that dpm injects into the commands it runs
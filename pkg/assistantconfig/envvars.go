@@ -39,6 +39,12 @@ const (
 	// It also disables automatic installation of any remote components that are missing
 	AutoInstallEnvVar = envVarPrefix + "AUTO_INSTALL"
 
+	// OfflineEnvVar
+	// DPM_OFFLINE guarantees that dpm never touches the network. Registry operations are served from the
+	// oci-layout cache instead, and fail if what they need isn't there.
+	// 	Default: false
+	OfflineEnvVar = envVarPrefix + "OFFLINE"
+
 	// EditionEnvVar
 	// DPM_EDITION sets the edition of the assistant.
 	// 	Possible values: enterprise private open-source
@@ -65,6 +71,29 @@ const (
 	// (It doesn't affect the `install` command(s))
 	DpmSdkVersionEnvVar = "DPM_SDK_VERSION"
 
+	// PullConcurrencyEnvVar
+	// DPM_PULL_CONCURRENCY sets the maximum number of components pulled in parallel.
+	// 	Default: 4
+	PullConcurrencyEnvVar = envVarPrefix + "PULL_CONCURRENCY"
+
+	// RegistryMaxAttemptsEnvVar
+	// DPM_REGISTRY_MAX_ATTEMPTS sets how many times a registry request is attempted (including the first try)
+	// before giving up. Timeouts, dropped connections, 408, 429 and 5xx responses are retried with exponential backoff.
+	// 	Default: 5
+	RegistryMaxAttemptsEnvVar = envVarPrefix + "REGISTRY_MAX_ATTEMPTS"
+
+	// ProgressEnvVar
+	// DPM_PROGRESS sets how the progress of pulls and pushes is reported (same as the --progress flag).
+	// 	Default: auto
+	// 	Possible values: auto tty plain json none
+	ProgressEnvVar = envVarPrefix + "PROGRESS"
+
+	// NoHooksEnvVar
+	// DPM_NO_HOOKS disables the pre-<command> and post-<command> hooks of daml.yaml and multi-package.yaml
+	// (same as the --no-hooks flag).
+	// 	Default: false
+	NoHooksEnvVar = envVarPrefix + "NO_HOOKS"
+
 	DpmLockfileEnabledEnvVar = "DPM_LOCKFILE_ENABLED"
 
 	DpmShaPinningEnabled = "DPM_SHA_PINNING_ENABLED"
@@ -0,0 +1,89 @@
+// Copyright (c) 2017-2026 Digital Asset (Switzerland) GmbH and/or its affiliates. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package assistantconfig
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RegistryMirror redirects pulls of artifacts under an upstream registry prefix to one or more mirrors, e.g.
+//
+//	registry-mirrors:
+//	  - prefix: europe-docker.pkg.dev/da-images/public
+//	    mirrors:
+//	      - artifactory.example.com/da-public
+//	      - http://fallback.example.com/da-public
+//
+// pulls 'europe-docker.pkg.dev/da-images/public/components/foo:1.0' from 'artifactory.example.com/da-public/components/foo:1.0',
+// falling back to the next mirror should one be unreachable or not have the artifact.
+// The upstream registry is only contacted if it's listed as a mirror itself.
+// Only the requests are redirected, URIs (e.g. in lockfiles) keep referring to the upstream registry
+type RegistryMirror struct {
+	// registry host, optionally followed by a path
+	Prefix string `yaml:"prefix"`
+	// tried in order. A mirror can be prefixed with 'http://' if it doesn't support https
+	Mirrors []string `yaml:"mirrors"`
+}
+
+func (m *RegistryMirror) validate() error {
+	if m.Prefix == "" || strings.Contains(m.Prefix, "://") {
+		return fmt.Errorf("invalid registry mirror prefix %q, must be a registry host optionally followed by a path", m.Prefix)
+	}
+	if len(m.Mirrors) == 0 {
+		return fmt.Errorf("registry mirror for %q has no mirrors", m.Prefix)
+	}
+	for _, mirror := range m.Mirrors {
+		_, rest := cutScheme(mirror)
+		if rest == "" || strings.Contains(rest, "://") {
+			return fmt.Errorf("invalid mirror %q for registry prefix %q", mirror, m.Prefix)
+		}
+	}
+	return nil
+}
+
+type RegistryMirrors []RegistryMirror
+
+// MirrorTarget is where a mirrored artifact is pulled from
+type MirrorTarget struct {
+	// registry host + repository
+	Name string
+	// "http" or "https", empty if the mirror didn't specify one
+	Scheme string
+}
+
+// MirrorsFor returns the mirrors to pull the artifact with the given name ('<registry host>/<repository>') from, in order.
+// The longest matching prefix wins. It returns nil if no rule applies
+func (rules RegistryMirrors) MirrorsFor(name string) []MirrorTarget {
+	var match *RegistryMirror
+	for i, m := range rules {
+		prefix := strings.TrimSuffix(m.Prefix, "/")
+		if name != prefix && !strings.HasPrefix(name, prefix+"/") {
+			continue
+		}
+		if match == nil || len(prefix) > len(strings.TrimSuffix(match.Prefix, "/")) {
+			match = &rules[i]
+		}
+	}
+	if match == nil {
+		return nil
+	}
+
+	rest := strings.TrimPrefix(name, strings.TrimSuffix(match.Prefix, "/"))
+	targets := make([]MirrorTarget, 0, len(match.Mirrors))
+	for _, mirror := range match.Mirrors {
+		scheme, prefix := cutScheme(mirror)
+		targets = append(targets, MirrorTarget{Name: strings.TrimSuffix(prefix, "/") + rest, Scheme: scheme})
+	}
+	return targets
+}
+
+func cutScheme(s string) (scheme, rest string) {
+	for _, scheme := range []string{"http", "https"} {
+		if rest, ok := strings.CutPrefix(s, scheme+"://"); ok {
+			return scheme, rest
+		}
+	}
+	return "", s
+}
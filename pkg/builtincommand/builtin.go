@@ -23,9 +23,14 @@ const (
 	Publish   BuiltinCommand = "publish"
 	Tags      BuiltinCommand = "tags"
 	Add       BuiltinCommand = "add"
+	Remove    BuiltinCommand = "remove"
+	Cache     BuiltinCommand = "cache"
+	Outdated  BuiltinCommand = "outdated"
+	Tree      BuiltinCommand = "tree"
+	Why       BuiltinCommand = "why"
 )
 
-var BuiltinCommands = []BuiltinCommand{Versions, Version, Update, Bootstrap, Install, UnInstall, Component, Repo, Resolve, Login, Publish, Tags, Add}
+var BuiltinCommands = []BuiltinCommand{Versions, Version, Update, Bootstrap, Install, UnInstall, Component, Repo, Resolve, Login, Publish, Tags, Add, Remove, Cache, Outdated, Tree, Why}
 
 func IsBuiltinCommand(args []string) bool {
 	if len(args) > 1 {
@@ -0,0 +1,184 @@
+// Copyright (c) 2017-2026 Digital Asset (Switzerland) GmbH and/or its affiliates. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package cachegc prunes dpm-home's cache of the components, dars, git checkouts and OCI blobs
+// that are no longer referenced by any installed sdk, known project or lockfile.
+package cachegc
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"daml.com/x/assistant/pkg/assistantconfig"
+	"daml.com/x/assistant/pkg/cacheusage"
+	"daml.com/x/assistant/pkg/utils"
+	"github.com/samber/lo"
+)
+
+type Kind string
+
+const (
+	KindComponent Kind = "component"
+	KindDar       Kind = "dar"
+	KindBlob      Kind = "blob"
+	KindGit       Kind = "git"
+)
+
+type Item struct {
+	Kind Kind   `json:"kind"`
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+}
+
+type Report struct {
+	Items []*Item `json:"items"`
+	// total size of Items in bytes
+	ReclaimedBytes int64 `json:"reclaimed-bytes"`
+	DryRun         bool  `json:"dry-run"`
+}
+
+type Opts struct {
+	DryRun bool
+	// additional lockfiles (dpm.lock or multi-package.lock) whose dars, and sibling project's components, should be kept
+	Lockfiles []string
+}
+
+type Collector struct {
+	config *assistantconfig.Config
+}
+
+func New(config *assistantconfig.Config) *Collector {
+	return &Collector{config: config}
+}
+
+// Collect deletes everything in the cache that isn't referenced by
+// the installed sdks, the current project, the given lockfiles or any project dpm remembers installing for.
+// With Opts.DryRun nothing is deleted, and the report describes what would have been.
+func (c *Collector) Collect(ctx context.Context, opts Opts) (report *Report, err error) {
+	err = utils.WithInstallLock(ctx, c.config.InstallLocalFilePath, func() error {
+		report, err = c.collect(ctx, opts)
+		return err
+	})
+	return
+}
+
+func (c *Collector) collect(ctx context.Context, opts Opts) (*Report, error) {
+	live, err := cacheusage.Compute(ctx, c.config, opts.Lockfiles)
+	if err != nil {
+		return nil, err
+	}
+	if len(live.GoneProjects) > 0 && !opts.DryRun {
+		if err := c.config.KnownProjects.Forget(live.GoneProjects...); err != nil {
+			return nil, err
+		}
+	}
+
+	componentsGarbage, err := sweepDir(filepath.Join(c.config.CachePath, "components"), live, KindComponent)
+	if err != nil {
+		return nil, err
+	}
+	// dars are either cached directly under dars/, or (when installed by digest) under dars/sha256/
+	darsGarbage, err := sweepDir(filepath.Join(c.config.CachePath, "dars"), live, KindDar, "sha256")
+	if err != nil {
+		return nil, err
+	}
+	// git checkouts (and mirrors) are under git/<host>/<repo path>/, so repos that nothing uses anymore are removed as a whole
+	gitGarbage, err := sweepDir(filepath.Join(c.config.CachePath, "git"), live, KindGit)
+	if err != nil {
+		return nil, err
+	}
+	ociSweep, err := sweepOciLayout(ctx, c.config.OciLayoutCache, live)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{DryRun: opts.DryRun}
+	report.Items = slices.Concat(componentsGarbage, darsGarbage, gitGarbage, ociSweep.garbage)
+	report.ReclaimedBytes = lo.SumBy(report.Items, func(i *Item) int64 { return i.Size })
+
+	if opts.DryRun {
+		return report, nil
+	}
+
+	if ociSweep.changed {
+		if err := writeOciLayoutIndex(c.config.OciLayoutCache, ociSweep.keep); err != nil {
+			return nil, err
+		}
+	}
+
+	var errs []error
+	for _, item := range report.Items {
+		if err := os.RemoveAll(item.Path); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	if err := c.pruneCacheIndex(ctx); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// pruneCacheIndex drops the cache index entries whose component dir no longer exists
+func (c *Collector) pruneCacheIndex(ctx context.Context) error {
+	entries, err := c.config.CacheIndex.Entries(ctx)
+	if err != nil {
+		return err
+	}
+
+	var stale []string
+	for d, comp := range entries {
+		ok, err := utils.DirExists(c.config.CachePathForComponent(comp.Name, comp.Version))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			stale = append(stale, d)
+		}
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+	return c.config.CacheIndex.Delete(ctx, stale...)
+}
+
+// sweepDir returns every entry under root that is neither a live dir, nor contains one.
+// The given groupingDirs directly under root are always descended into rather than reported as a whole
+func sweepDir(root string, live *cacheusage.Usage, kind Kind, groupingDirs ...string) ([]*Item, error) {
+	entries, err := os.ReadDir(root)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var garbage []*Item
+	for _, e := range entries {
+		p := filepath.Join(root, e.Name())
+
+		isLive, isAncestor := live.IsDirOrAncestor(p)
+		switch {
+		case isLive:
+			continue
+		case (isAncestor || slices.Contains(groupingDirs, e.Name())) && e.IsDir():
+			nested, err := sweepDir(p, live, kind)
+			if err != nil {
+				return nil, err
+			}
+			garbage = append(garbage, nested...)
+		default:
+			size, err := utils.DiskUsage(p)
+			if err != nil {
+				return nil, err
+			}
+			garbage = append(garbage, &Item{Kind: kind, Path: p, Size: size})
+		}
+	}
+	return garbage, nil
+}
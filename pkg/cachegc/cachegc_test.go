@@ -0,0 +1,108 @@
+// Copyright (c) 2017-2026 Digital Asset (Switzerland) GmbH and/or its affiliates. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package cachegc
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"daml.com/x/assistant/pkg/assistantconfig"
+	"daml.com/x/assistant/pkg/packagelock"
+	"daml.com/x/assistant/pkg/schema"
+	"daml.com/x/assistant/pkg/testutil"
+	"daml.com/x/assistant/pkg/utils"
+	"github.com/goccy/go-yaml"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const installedSdkManifest = `apiVersion: digitalasset.com/v1
+kind: SdkManifest
+spec:
+  version: 1.2.3
+  edition: open-source
+  components:
+    meep-shell:
+      version: 4.5.6
+    legacy-tool:
+      uri: oci://example.com/components/legacy-tool:1.0.0
+`
+
+func TestCollect(t *testing.T) {
+	ctx := testutil.Context(t)
+	config := testutil.MkConfig(t)
+	t.Chdir(t.TempDir())
+
+	sdkDir := filepath.Join(config.InstalledSdkManifestsPath, "open-source")
+	require.NoError(t, utils.EnsureDirs(sdkDir))
+	require.NoError(t, os.WriteFile(filepath.Join(sdkDir, "1.2.3.yaml"), []byte(installedSdkManifest), 0o666))
+
+	live := config.CachePathForComponent("meep-shell", "4.5.6")
+	// legacy (non sha-pinned) uri components are cached under their repository, not their name
+	liveLegacy := config.CachePathForComponent("example.com/components/legacy-tool", "1.0.0")
+	deadComponent := config.CachePathForComponent("meep-shell", "0.0.1")
+	deadDar := filepath.Join(config.CachePath, "dars", "sha256", "abc")
+	for _, d := range []string{live, liveLegacy, deadComponent, deadDar} {
+		require.NoError(t, utils.EnsureDirs(d))
+		require.NoError(t, os.WriteFile(filepath.Join(d, "some-file"), []byte("meep"), 0o666))
+	}
+
+	report, err := New(config).Collect(ctx, Opts{DryRun: true})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{deadComponent, deadDar}, lo.Map(report.Items, func(i *Item, _ int) string { return i.Path }))
+	assert.Equal(t, int64(8), report.ReclaimedBytes)
+	assert.DirExists(t, deadComponent)
+	assert.DirExists(t, deadDar)
+
+	report, err = New(config).Collect(ctx, Opts{})
+	require.NoError(t, err)
+	assert.Len(t, report.Items, 2)
+	assert.DirExists(t, live)
+	assert.DirExists(t, liveLegacy)
+	assert.NoDirExists(t, deadComponent)
+	assert.NoDirExists(t, deadDar)
+}
+
+func TestCollectGit(t *testing.T) {
+	ctx := testutil.Context(t)
+	config := testutil.MkConfig(t)
+	t.Chdir(t.TempDir())
+
+	liveCommit := strings.Repeat("a", 40)
+	deadCommit := strings.Repeat("b", 40)
+	lockfile := filepath.Join(t.TempDir(), assistantconfig.DpmLockFileName)
+	lock := &packagelock.PackageLock{
+		ManifestMeta: schema.ManifestMeta{APIVersion: packagelock.PackageLockAPIVersion, Kind: packagelock.PackageLockKind},
+		Dars: []*packagelock.Dar{{
+			URI:    lo.Must(url.Parse("git+https://example.com/foo//dars/foo.dar@main")),
+			Commit: liveCommit,
+			Path:   "/somewhere/foo.dar",
+		}},
+	}
+	require.NoError(t, os.WriteFile(lockfile, lo.Must(yaml.Marshal(lock)), 0o666))
+
+	fooDir := filepath.Join(config.CachePath, "git", "example.com", "foo")
+	live := filepath.Join(fooDir, liveCommit)
+	liveMirror := filepath.Join(fooDir, "mirror.git")
+	deadCheckout := filepath.Join(fooDir, deadCommit)
+	// nothing checked out from bar is in use, so its mirror goes too
+	deadRepo := filepath.Join(config.CachePath, "git", "example.com", "bar")
+	for _, d := range []string{live, liveMirror, deadCheckout, filepath.Join(deadRepo, "mirror.git"), filepath.Join(deadRepo, liveCommit)} {
+		require.NoError(t, utils.EnsureDirs(d))
+		require.NoError(t, os.WriteFile(filepath.Join(d, "some-file"), []byte("meep"), 0o666))
+	}
+
+	report, err := New(config).Collect(ctx, Opts{Lockfiles: []string{lockfile}})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{deadCheckout, deadRepo}, lo.Map(report.Items, func(i *Item, _ int) string { return i.Path }))
+	assert.True(t, lo.EveryBy(report.Items, func(i *Item) bool { return i.Kind == KindGit }))
+	assert.DirExists(t, live)
+	assert.DirExists(t, liveMirror)
+	assert.NoDirExists(t, deadCheckout)
+	assert.NoDirExists(t, deadRepo)
+}
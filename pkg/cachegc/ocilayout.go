@@ -0,0 +1,199 @@
+// Copyright (c) 2017-2026 Digital Asset (Switzerland) GmbH and/or its affiliates. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package cachegc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"daml.com/x/assistant/pkg/cacheusage"
+	ociconsts "daml.com/x/assistant/pkg/oci"
+	"daml.com/x/assistant/pkg/utils"
+	"daml.com/x/assistant/pkg/utils/stringset"
+	"github.com/opencontainers/go-digest"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/content/oci"
+	"oras.land/oras-go/v2/errdef"
+)
+
+// ociLayoutSweep is the outcome of marking the blobs of the oci-layout cache
+type ociLayoutSweep struct {
+	garbage []*Item
+	// the index.json manifests to keep
+	keep []v1.Descriptor
+	// whether index.json needs rewriting
+	changed bool
+}
+
+// sweepOciLayout marks every manifest in the oci-layout's index.json that belongs to a live artifact,
+// along with everything reachable from it. All other blobs are garbage.
+func sweepOciLayout(ctx context.Context, root string, live *cacheusage.Usage) (*ociLayoutSweep, error) {
+	index, err := readOciLayoutIndex(root)
+	if os.IsNotExist(err) {
+		return &ociLayoutSweep{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	storage := oci.NewStorageFromFS(os.DirFS(root))
+
+	var roots []v1.Descriptor
+	for _, desc := range index.Manifests {
+		if live.ContainsDigest(desc.Digest.String()) {
+			roots = append(roots, desc)
+			continue
+		}
+
+		annotations, err := fetchAnnotations(ctx, storage, desc)
+		if errors.Is(err, errdef.ErrNotFound) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		name, hasName := utils.GetWithFallback(annotations, ociconsts.DescriptorNameAnnotation, ociconsts.LegacyNameAnnotation)
+		version, hasVersion := utils.GetWithFallback(annotations, v1.AnnotationVersion, ociconsts.LegacyVersionAnnotation)
+		if hasName && hasVersion && live.ContainsArtifact(name, version) {
+			roots = append(roots, desc)
+		}
+	}
+
+	reachable, err := markReachable(ctx, storage, roots)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ociLayoutSweep{}
+	for _, desc := range index.Manifests {
+		if reachable.Contains(desc.Digest.String()) {
+			result.keep = append(result.keep, desc)
+		} else {
+			result.changed = true
+		}
+	}
+
+	blobsDir := filepath.Join(root, v1.ImageBlobsDir)
+	algs, err := os.ReadDir(blobsDir)
+	if os.IsNotExist(err) {
+		return result, nil
+	} else if err != nil {
+		return nil, err
+	}
+	for _, alg := range algs {
+		if !alg.IsDir() {
+			continue
+		}
+		blobs, err := os.ReadDir(filepath.Join(blobsDir, alg.Name()))
+		if err != nil {
+			return nil, err
+		}
+		for _, b := range blobs {
+			d := digest.NewDigestFromEncoded(digest.Algorithm(alg.Name()), b.Name())
+			if reachable.Contains(d.String()) {
+				continue
+			}
+			info, err := b.Info()
+			if err != nil {
+				return nil, err
+			}
+			result.garbage = append(result.garbage, &Item{
+				Kind: KindBlob,
+				Path: filepath.Join(blobsDir, alg.Name(), b.Name()),
+				Size: info.Size(),
+			})
+		}
+	}
+
+	return result, nil
+}
+
+func markReachable(ctx context.Context, storage content.ReadOnlyStorage, roots []v1.Descriptor) (stringset.StringSet, error) {
+	reachable := make(stringset.StringSet)
+	queue := roots
+
+	for len(queue) > 0 {
+		desc := queue[0]
+		queue = queue[1:]
+
+		if reachable.Contains(desc.Digest.String()) {
+			continue
+		}
+		reachable.Add(desc.Digest.String())
+
+		successors, err := content.Successors(ctx, storage, desc)
+		if errors.Is(err, errdef.ErrNotFound) {
+			// partially pulled, nothing more to mark
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		queue = append(queue, successors...)
+	}
+
+	return reachable, nil
+}
+
+func fetchAnnotations(ctx context.Context, storage content.ReadOnlyStorage, desc v1.Descriptor) (map[string]string, error) {
+	b, err := content.FetchAll(ctx, storage, desc)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := struct {
+		Annotations map[string]string `json:"annotations"`
+	}{}
+	if err := json.Unmarshal(b, &manifest); err != nil {
+		return nil, err
+	}
+	return manifest.Annotations, nil
+}
+
+func readOciLayoutIndex(root string) (*v1.Index, error) {
+	b, err := os.ReadFile(filepath.Join(root, v1.ImageIndexFile))
+	if err != nil {
+		return nil, err
+	}
+
+	var index v1.Index
+	if err := json.Unmarshal(b, &index); err != nil {
+		return nil, err
+	}
+	return &index, nil
+}
+
+// writeOciLayoutIndex replaces index.json's manifests, going through a temp file so that
+// a concurrent reader never sees a truncated index
+func writeOciLayoutIndex(root string, manifests []v1.Descriptor) error {
+	index, err := readOciLayoutIndex(root)
+	if err != nil {
+		return err
+	}
+	if manifests == nil {
+		manifests = []v1.Descriptor{}
+	}
+	index.Manifests = manifests
+
+	b, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(root, v1.ImageIndexFile+".*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(b); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(root, v1.ImageIndexFile))
+}
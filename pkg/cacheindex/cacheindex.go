@@ -1,20 +1,31 @@
 package cacheindex
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
 
+	ociconsts "daml.com/x/assistant/pkg/oci"
 	"daml.com/x/assistant/pkg/utils"
 	"github.com/opencontainers/go-digest"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
 )
 
 const CacheIndexSchema = "cache-index/v1"
 
+// ComponentManifestFilename is the file that every component dir in the cache has
+const ComponentManifestFilename = "component.yaml"
+
 type CacheIndex struct {
 	AbsolutePath string
+
+	// ComponentsPath and OciLayoutPath are used to rebuild the index should it be corrupt
+	ComponentsPath string
+	OciLayoutPath  string
 }
 
 type CacheIndexContents struct {
@@ -31,54 +42,40 @@ type CacheIndexComponent struct {
 	Name string `json:"name"`
 }
 
-func (c *CacheIndex) Store(d digest.Digest, name, version string) error {
+func (c *CacheIndex) Store(ctx context.Context, d digest.Digest, name, version string) error {
 	if err := d.Validate(); err != nil {
 		return err
 	}
 
-	contents, err := c.read()
-	if err != nil {
-		return err
-	}
-
-	contents.Components[d.String()] = CacheIndexComponent{
-		Name:    name,
-		Version: version,
-	}
-
-	return c.write(contents)
+	return c.update(ctx, func(contents *CacheIndexContents) bool {
+		contents.Components[d.String()] = CacheIndexComponent{
+			Name:    name,
+			Version: version,
+		}
+		return true
+	})
 }
 
 // Init writes an empty cache index if one doesn't already exist.
-func (c *CacheIndex) Init() error {
+func (c *CacheIndex) Init(ctx context.Context) error {
 	if _, err := os.Stat(c.AbsolutePath); err == nil {
 		return nil
 	} else if !os.IsNotExist(err) {
 		return err
 	}
 
-	if err := utils.EnsureDirs(filepath.Dir(c.AbsolutePath)); err != nil {
+	return c.withLock(ctx, func() error {
+		_, err := c.readOrRecover()
 		return err
-	}
-
-	contents := &CacheIndexContents{
-		Schema:     CacheIndexSchema,
-		Components: map[string]CacheIndexComponent{},
-	}
-
-	return c.write(contents)
+	})
 }
 
-func (c *CacheIndex) Get(digest string) (name string, version string, ok bool, err error) {
+func (c *CacheIndex) Get(ctx context.Context, digest string) (name string, version string, ok bool, err error) {
 	if !strings.HasPrefix(digest, "sha256:") {
 		return "", "", false, fmt.Errorf("the digest to read from the cache index must be a sha256 digest with the 'sha256:' prefix")
 	}
 
-	if err := c.Init(); err != nil {
-		return "", "", false, err
-	}
-
-	contents, err := c.read()
+	contents, err := c.load(ctx)
 	if err != nil {
 		return "", "", false, err
 	}
@@ -91,7 +88,88 @@ func (c *CacheIndex) Get(digest string) (name string, version string, ok bool, e
 	return component.Name, component.Version, true, nil
 }
 
-// read assumes the index file already exists.
+// Entries returns all the components recorded in the cache index, keyed by digest
+func (c *CacheIndex) Entries(ctx context.Context) (map[string]CacheIndexComponent, error) {
+	contents, err := c.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return contents.Components, nil
+}
+
+// Delete removes the given digests from the cache index (if present)
+func (c *CacheIndex) Delete(ctx context.Context, digests ...string) error {
+	return c.update(ctx, func(contents *CacheIndexContents) bool {
+		n := len(contents.Components)
+		for _, d := range digests {
+			delete(contents.Components, d)
+		}
+		return len(contents.Components) != n
+	})
+}
+
+// load reads the index without taking the lock (writes are atomic, so readers never see a partial file),
+// unless it's missing or corrupt, in which case it's (re)created under the lock
+func (c *CacheIndex) load(ctx context.Context) (*CacheIndexContents, error) {
+	if contents, err := c.read(); err == nil {
+		return contents, nil
+	}
+
+	var contents *CacheIndexContents
+	err := c.withLock(ctx, func() (err error) {
+		contents, err = c.readOrRecover()
+		return err
+	})
+	return contents, err
+}
+
+// update performs a read-modify-write of the index under the lock.
+// The index is only written if modify returns true
+func (c *CacheIndex) update(ctx context.Context, modify func(contents *CacheIndexContents) bool) error {
+	return c.withLock(ctx, func() error {
+		contents, err := c.readOrRecover()
+		if err != nil {
+			return err
+		}
+		if !modify(contents) {
+			return nil
+		}
+		return c.write(contents)
+	})
+}
+
+// withLock guards action with a lockfile next to the index, so that concurrent dpm processes don't lose each other's writes
+func (c *CacheIndex) withLock(ctx context.Context, action func() error) error {
+	return utils.WithInstallLock(ctx, c.AbsolutePath+".lock", action)
+}
+
+// readOrRecover must be called with the lock held.
+// It writes an empty index if there's none yet, and rebuilds a corrupt one
+func (c *CacheIndex) readOrRecover() (*CacheIndexContents, error) {
+	contents, err := c.read()
+	if err == nil {
+		return contents, nil
+	}
+
+	if os.IsNotExist(err) {
+		contents = &CacheIndexContents{
+			Schema:     CacheIndexSchema,
+			Components: map[string]CacheIndexComponent{},
+		}
+	} else {
+		slog.Warn("cache index is corrupt, rebuilding it", "path", c.AbsolutePath, "err", err.Error())
+		contents, err = c.rebuild()
+		if err != nil {
+			return nil, fmt.Errorf("failed to rebuild corrupt cache index %q: %w", c.AbsolutePath, err)
+		}
+	}
+
+	if err := c.write(contents); err != nil {
+		return nil, err
+	}
+	return contents, nil
+}
+
 func (c *CacheIndex) read() (*CacheIndexContents, error) {
 	b, err := os.ReadFile(c.AbsolutePath)
 	if err != nil {
@@ -114,6 +192,7 @@ func (c *CacheIndex) read() (*CacheIndexContents, error) {
 	return &contents, nil
 }
 
+// write replaces the index via a temp file and rename, so that it's never left truncated
 func (c *CacheIndex) write(contents *CacheIndexContents) error {
 	if contents.Schema == "" {
 		contents.Schema = CacheIndexSchema
@@ -128,5 +207,131 @@ func (c *CacheIndex) write(contents *CacheIndexContents) error {
 		return err
 	}
 
-	return os.WriteFile(c.AbsolutePath, b, 0o644)
+	if err := utils.EnsureDirs(filepath.Dir(c.AbsolutePath)); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(c.AbsolutePath), filepath.Base(c.AbsolutePath)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(b); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.AbsolutePath)
+}
+
+// rebuild recovers the index from the component dirs on disk: each '<name>/<version>' dir is matched
+// against the name and version annotations of the manifests in the oci-layout cache.
+// Dirs without a matching manifest are left out, and will be looked up again the next time they're needed
+func (c *CacheIndex) rebuild() (*CacheIndexContents, error) {
+	contents := &CacheIndexContents{
+		Schema:     CacheIndexSchema,
+		Components: map[string]CacheIndexComponent{},
+	}
+
+	if c.ComponentsPath == "" {
+		return contents, nil
+	}
+	dirs, err := FindComponentDirs(c.ComponentsPath)
+	if err != nil || len(dirs) == 0 {
+		return contents, err
+	}
+
+	manifests, err := c.annotatedManifests()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, comp := range dirs {
+		for d, annotated := range manifests {
+			if annotated.Version != comp.Version {
+				continue
+			}
+			if annotated.Name == comp.Name || strings.HasSuffix(comp.Name, "/"+utils.UrlToFilePath(annotated.Name)) {
+				contents.Components[d] = CacheIndexComponent{Name: comp.Name, Version: comp.Version}
+			}
+		}
+	}
+	return contents, nil
+}
+
+// ComponentDir is a '<name>/<version>' dir under the components cache
+type ComponentDir struct {
+	Name    string
+	Version string
+	Path    string
+}
+
+// FindComponentDirs returns the dirs under root that contain a component.yaml.
+// Their name is the path (relative to root) of their parent, and their version their base name
+func FindComponentDirs(root string) ([]*ComponentDir, error) {
+	var dirs []*ComponentDir
+	err := filepath.WalkDir(root, func(p string, d os.DirEntry, err error) error {
+		if os.IsNotExist(err) && p == root {
+			return filepath.SkipAll
+		} else if err != nil {
+			return err
+		}
+		if d.IsDir() || d.Name() != ComponentManifestFilename {
+			return nil
+		}
+
+		dir := filepath.Dir(p)
+		rel, err := filepath.Rel(root, filepath.Dir(dir))
+		if err != nil || rel == "." {
+			return nil
+		}
+		dirs = append(dirs, &ComponentDir{
+			Name:    filepath.ToSlash(rel),
+			Version: filepath.Base(dir),
+			Path:    dir,
+		})
+		return filepath.SkipDir
+	})
+	return dirs, err
+}
+
+// annotatedManifests returns the name and version annotations of the manifests listed in the oci-layout cache's index.json
+func (c *CacheIndex) annotatedManifests() (map[string]CacheIndexComponent, error) {
+	result := map[string]CacheIndexComponent{}
+	if c.OciLayoutPath == "" {
+		return result, nil
+	}
+
+	b, err := os.ReadFile(filepath.Join(c.OciLayoutPath, v1.ImageIndexFile))
+	if os.IsNotExist(err) {
+		return result, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var index v1.Index
+	if err := json.Unmarshal(b, &index); err != nil {
+		return nil, err
+	}
+
+	for _, desc := range index.Manifests {
+		b, err := os.ReadFile(filepath.Join(c.OciLayoutPath, v1.ImageBlobsDir, desc.Digest.Algorithm().String(), desc.Digest.Encoded()))
+		if err != nil {
+			continue
+		}
+		var manifest struct {
+			Annotations map[string]string `json:"annotations"`
+		}
+		if err := json.Unmarshal(b, &manifest); err != nil {
+			continue
+		}
+
+		name, hasName := utils.GetWithFallback(manifest.Annotations, ociconsts.DescriptorNameAnnotation, ociconsts.LegacyNameAnnotation)
+		version, hasVersion := utils.GetWithFallback(manifest.Annotations, v1.AnnotationVersion, ociconsts.LegacyVersionAnnotation)
+		if hasName && hasVersion {
+			result[desc.Digest.String()] = CacheIndexComponent{Name: name, Version: version}
+		}
+	}
+	return result, nil
 }
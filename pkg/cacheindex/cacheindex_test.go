@@ -0,0 +1,77 @@
+package cacheindex
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	ociconsts "daml.com/x/assistant/pkg/oci"
+	"github.com/opencontainers/go-digest"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newIndex(t *testing.T) *CacheIndex {
+	cacheDir := t.TempDir()
+	return &CacheIndex{
+		AbsolutePath:   filepath.Join(cacheDir, "index.json"),
+		ComponentsPath: filepath.Join(cacheDir, "components"),
+		OciLayoutPath:  filepath.Join(cacheDir, "oci-layout"),
+	}
+}
+
+func TestConcurrentStores(t *testing.T) {
+	ctx := t.Context()
+	index := newIndex(t)
+
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, index.Store(ctx, digest.FromString(fmt.Sprint(i)), "meep", fmt.Sprintf("1.0.%d", i)))
+		}()
+	}
+	wg.Wait()
+
+	entries, err := index.Entries(ctx)
+	require.NoError(t, err)
+	assert.Len(t, entries, 20)
+}
+
+func TestRebuildCorruptIndex(t *testing.T) {
+	ctx := t.Context()
+	index := newIndex(t)
+
+	// a cached component, and the manifest it was pulled by
+	require.NoError(t, os.MkdirAll(filepath.Join(index.ComponentsPath, "example.com", "meep", "1.2.3"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(index.ComponentsPath, "example.com", "meep", "1.2.3", "component.yaml"), nil, 0o644))
+
+	manifest, err := json.Marshal(v1.Manifest{Annotations: map[string]string{
+		ociconsts.DescriptorNameAnnotation: "meep",
+		v1.AnnotationVersion:               "1.2.3",
+	}})
+	require.NoError(t, err)
+	d := digest.FromBytes(manifest)
+	require.NoError(t, os.MkdirAll(filepath.Join(index.OciLayoutPath, "blobs", "sha256"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(index.OciLayoutPath, "blobs", "sha256", d.Encoded()), manifest, 0o644))
+	ociIndex, err := json.Marshal(v1.Index{Manifests: []v1.Descriptor{{Digest: d, MediaType: v1.MediaTypeImageManifest}}})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(index.OciLayoutPath, "index.json"), ociIndex, 0o644))
+
+	require.NoError(t, os.WriteFile(index.AbsolutePath, []byte(`{"schema": "cache-ind`), 0o644))
+
+	name, version, ok, err := index.Get(ctx, d.String())
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "example.com/meep", name)
+	assert.Equal(t, "1.2.3", version)
+
+	// and the rebuilt index was persisted
+	_, err = index.read()
+	assert.NoError(t, err)
+}
@@ -0,0 +1,136 @@
+// Copyright (c) 2017-2026 Digital Asset (Switzerland) GmbH and/or its affiliates. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package cacheinspect lists, verifies and removes the components cached in dpm-home,
+// as recorded in the cache index (and, for components installed by version, as found on disk).
+package cacheinspect
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"daml.com/x/assistant/pkg/assistantconfig"
+	"daml.com/x/assistant/pkg/cacheindex"
+	"daml.com/x/assistant/pkg/cacheusage"
+	"daml.com/x/assistant/pkg/utils"
+	"daml.com/x/assistant/pkg/utils/stringset"
+	"github.com/samber/lo"
+)
+
+type Entry struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	// the OCI manifest digest the component was pulled by. Empty for components installed by version
+	Digest string `json:"digest,omitempty"`
+	Path   string `json:"path"`
+	// size on disk in bytes
+	Size int64 `json:"size"`
+	// the installed sdks, daml.yamls, multi-package.yamls and lockfiles that reference this entry
+	ReferencedBy []string `json:"referenced-by"`
+}
+
+// List returns the cached components, sorted by name and version.
+// The given lockfiles are taken into account when determining what references each entry
+func List(ctx context.Context, config *assistantconfig.Config, lockfiles []string) ([]*Entry, error) {
+	usage, err := cacheusage.Compute(ctx, config, lockfiles)
+	if err != nil {
+		return nil, err
+	}
+
+	indexed, err := config.CacheIndex.Entries(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*Entry
+	seen := make(stringset.StringSet)
+	for d, comp := range indexed {
+		p := config.CachePathForComponent(comp.Name, comp.Version)
+		entries = append(entries, &Entry{
+			Name:    comp.Name,
+			Version: comp.Version,
+			Digest:  d,
+			Path:    p,
+		})
+		seen.Add(p)
+	}
+
+	onDisk, err := cacheindex.FindComponentDirs(filepath.Join(config.CachePath, "components"))
+	if err != nil {
+		return nil, err
+	}
+	for _, dir := range onDisk {
+		if !seen.Contains(dir.Path) {
+			entries = append(entries, &Entry{Name: dir.Name, Version: dir.Version, Path: dir.Path})
+		}
+	}
+
+	for _, e := range entries {
+		if ok, err := utils.DirExists(e.Path); err != nil {
+			return nil, err
+		} else if ok {
+			if e.Size, err = utils.DiskUsage(e.Path); err != nil {
+				return nil, err
+			}
+		}
+		e.ReferencedBy = usage.Referrers(lo.Compact([]string{e.Path, e.Digest})...)
+	}
+
+	slices.SortFunc(entries, func(a, b *Entry) int {
+		return strings.Compare(a.Name+"\x00"+a.Version+"\x00"+a.Digest, b.Name+"\x00"+b.Version+"\x00"+b.Digest)
+	})
+	return entries, nil
+}
+
+// Remove deletes the cached components with the given name (and version, if non-empty), along with their cache index entries.
+// Unless force is set, it refuses to remove components that are still referenced
+func Remove(ctx context.Context, config *assistantconfig.Config, name, version string, force bool) (removed []*Entry, err error) {
+	err = utils.WithInstallLock(ctx, config.InstallLocalFilePath, func() error {
+		removed, err = remove(ctx, config, name, version, force)
+		return err
+	})
+	return
+}
+
+func remove(ctx context.Context, config *assistantconfig.Config, name, version string, force bool) ([]*Entry, error) {
+	entries, err := List(ctx, config, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := lo.Filter(entries, func(e *Entry, _ int) bool {
+		return (e.Name == name || e.Name == utils.UrlToFilePath(name)) && (version == "" || e.Version == version)
+	})
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("no cached component matches %q", lo.Ternary(version == "", name, name+":"+version))
+	}
+
+	if !force {
+		for _, e := range matches {
+			if len(e.ReferencedBy) > 0 {
+				return nil, fmt.Errorf("cached component %s:%s is still referenced by %s (use --force to remove it anyway)",
+					e.Name, e.Version, strings.Join(e.ReferencedBy, ", "))
+			}
+		}
+	}
+
+	var digests []string
+	for _, e := range matches {
+		if err := os.RemoveAll(e.Path); err != nil {
+			return nil, err
+		}
+		if e.Digest != "" {
+			digests = append(digests, e.Digest)
+		}
+	}
+	if len(digests) > 0 {
+		if err := config.CacheIndex.Delete(ctx, digests...); err != nil {
+			return nil, err
+		}
+	}
+	return matches, nil
+}
@@ -0,0 +1,84 @@
+// Copyright (c) 2017-2026 Digital Asset (Switzerland) GmbH and/or its affiliates. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package cacheinspect
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"daml.com/x/assistant/pkg/testutil"
+	"daml.com/x/assistant/pkg/utils"
+	"github.com/opencontainers/go-digest"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const installedSdkManifest = `apiVersion: digitalasset.com/v1
+kind: SdkManifest
+spec:
+  version: 1.2.3
+  edition: open-source
+  components:
+    meep:
+      version: 4.5.6
+`
+
+func TestListAndRemove(t *testing.T) {
+	ctx := testutil.Context(t)
+	config := testutil.MkConfig(t)
+	t.Chdir(t.TempDir())
+
+	sdkDir := filepath.Join(config.InstalledSdkManifestsPath, "open-source")
+	require.NoError(t, utils.EnsureDirs(sdkDir))
+	require.NoError(t, os.WriteFile(filepath.Join(sdkDir, "1.2.3.yaml"), []byte(installedSdkManifest), 0o666))
+
+	d := digest.FromString("sheep")
+	require.NoError(t, config.CacheIndex.Store(ctx, d, "example.com/sheep", "1.0.0"))
+	for _, p := range []string{config.CachePathForComponent("meep", "4.5.6"), config.CachePathForComponent("example.com/sheep", "1.0.0")} {
+		require.NoError(t, utils.EnsureDirs(p))
+		require.NoError(t, os.WriteFile(filepath.Join(p, "component.yaml"), []byte("meep"), 0o666))
+	}
+
+	entries, err := List(ctx, config, nil)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "example.com/sheep", entries[0].Name)
+	assert.Equal(t, d.String(), entries[0].Digest)
+	assert.Empty(t, entries[0].ReferencedBy)
+	assert.Equal(t, "meep", entries[1].Name)
+	assert.Empty(t, entries[1].Digest)
+	assert.Equal(t, []string{"sdk 1.2.3 (open-source)"}, entries[1].ReferencedBy)
+	assert.Equal(t, int64(4), entries[1].Size)
+
+	_, err = Remove(ctx, config, "meep", "", false)
+	assert.ErrorContains(t, err, "still referenced by sdk 1.2.3 (open-source)")
+
+	removed, err := Remove(ctx, config, "example.com/sheep", "1.0.0", false)
+	require.NoError(t, err)
+	require.Len(t, removed, 1)
+	assert.NoDirExists(t, removed[0].Path)
+	_, _, ok, err := config.CacheIndex.Get(ctx, d.String())
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestVerify(t *testing.T) {
+	ctx := testutil.Context(t)
+	config := testutil.MkConfig(t)
+
+	blobsDir := filepath.Join(config.OciLayoutCache, "blobs", "sha256")
+	require.NoError(t, utils.EnsureDirs(blobsDir))
+	good, bad := digest.FromString("good"), digest.FromString("bad")
+	require.NoError(t, os.WriteFile(filepath.Join(blobsDir, good.Encoded()), []byte("good"), 0o666))
+	require.NoError(t, os.WriteFile(filepath.Join(blobsDir, bad.Encoded()), []byte("tampered"), 0o666))
+
+	require.NoError(t, config.CacheIndex.Store(ctx, digest.FromString("gone"), "gone", "1.0.0"))
+
+	results, err := Verify(ctx, config)
+	require.NoError(t, err)
+	failed := lo.FilterMap(results, func(r *VerifyResult, _ int) (string, bool) { return r.Digest, !r.OK })
+	assert.ElementsMatch(t, []string{bad.String(), digest.FromString("gone").String()}, failed)
+}
@@ -0,0 +1,220 @@
+// Copyright (c) 2017-2026 Digital Asset (Switzerland) GmbH and/or its affiliates. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package cacheinspect
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"daml.com/x/assistant/pkg/assistantconfig"
+	"daml.com/x/assistant/pkg/ociindex"
+	"daml.com/x/assistant/pkg/simpleplatform"
+	"github.com/opencontainers/go-digest"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2/content/file"
+)
+
+type VerifyKind string
+
+const (
+	VerifyComponent VerifyKind = "component"
+	VerifyBlob      VerifyKind = "blob"
+)
+
+type VerifyResult struct {
+	Kind    VerifyKind `json:"kind"`
+	Name    string     `json:"name,omitempty"`
+	Version string     `json:"version,omitempty"`
+	Digest  string     `json:"digest"`
+	Path    string     `json:"path"`
+	OK      bool       `json:"ok"`
+	// why verification failed, or why it could only be partially performed
+	Problems []string `json:"problems,omitempty"`
+}
+
+// Verify recomputes the digests of every blob in the oci-layout cache, and of the files
+// of every component in the cache index, against the OCI manifest the component was pulled by.
+// Directory layers can't be re-hashed once unpacked, so for those only their presence is checked.
+func Verify(ctx context.Context, config *assistantconfig.Config) ([]*VerifyResult, error) {
+	indexed, err := config.CacheIndex.Entries(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var results []*VerifyResult
+	for d, comp := range indexed {
+		r := &VerifyResult{
+			Kind:    VerifyComponent,
+			Name:    comp.Name,
+			Version: comp.Version,
+			Digest:  d,
+			Path:    config.CachePathForComponent(comp.Name, comp.Version),
+		}
+		r.Problems, err = verifyComponent(config.OciLayoutCache, digest.Digest(d), r.Path)
+		if err != nil {
+			return nil, err
+		}
+		r.OK = len(r.Problems) == 0
+		results = append(results, r)
+	}
+	slices.SortFunc(results, func(a, b *VerifyResult) int {
+		return strings.Compare(a.Name+"\x00"+a.Version, b.Name+"\x00"+b.Version)
+	})
+
+	blobs, err := verifyBlobs(config.OciLayoutCache)
+	if err != nil {
+		return nil, err
+	}
+	return append(results, blobs...), nil
+}
+
+func verifyComponent(ociLayoutRoot string, d digest.Digest, dir string) ([]string, error) {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return []string{"component dir is missing"}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	manifest, problem, err := fetchComponentManifest(ociLayoutRoot, d)
+	if err != nil || problem != "" {
+		return nonEmpty(problem), err
+	}
+
+	var problems []string
+	for _, layer := range manifest.Layers {
+		title := layer.Annotations[v1.AnnotationTitle]
+		if title == "" {
+			continue
+		}
+		p := filepath.Join(dir, title)
+
+		info, err := os.Stat(p)
+		if os.IsNotExist(err) {
+			problems = append(problems, fmt.Sprintf("%q is missing", title))
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if layer.Annotations[file.AnnotationUnpack] == "true" || info.IsDir() {
+			continue
+		}
+
+		actual, err := digestFile(p, layer.Digest.Algorithm())
+		if err != nil {
+			return nil, err
+		}
+		if actual != layer.Digest {
+			problems = append(problems, fmt.Sprintf("%q has digest %s, expected %s", title, actual, layer.Digest))
+		}
+	}
+	return problems, nil
+}
+
+// fetchComponentManifest returns the (current platform's) image manifest for the given digest from the oci-layout cache.
+// A non-empty problem is returned if it can't be found there
+func fetchComponentManifest(ociLayoutRoot string, d digest.Digest) (*v1.Manifest, string, error) {
+	desc := v1.Descriptor{Digest: d}
+	for {
+		b, err := readBlob(ociLayoutRoot, desc.Digest)
+		if os.IsNotExist(err) {
+			return nil, fmt.Sprintf("manifest %s isn't in the oci-layout cache, so the component's files can't be verified", desc.Digest), nil
+		} else if err != nil {
+			return nil, err.Error(), nil
+		}
+
+		var m struct {
+			v1.Manifest
+			Manifests []v1.Descriptor `json:"manifests"`
+		}
+		if err := json.Unmarshal(b, &m); err != nil {
+			return nil, "", err
+		}
+		if len(m.Manifests) == 0 {
+			return &m.Manifest, "", nil
+		}
+
+		target, err := ociindex.FindTargetPlatform(m.Manifests, simpleplatform.CurrentPlatform())
+		if err != nil {
+			return nil, err.Error(), nil
+		}
+		desc = *target
+	}
+}
+
+// readBlob reads a blob from the oci-layout cache, failing if its content doesn't match its digest
+func readBlob(ociLayoutRoot string, d digest.Digest) ([]byte, error) {
+	if err := d.Validate(); err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(filepath.Join(ociLayoutRoot, v1.ImageBlobsDir, d.Algorithm().String(), d.Encoded()))
+	if err != nil {
+		return nil, err
+	}
+	if d.Algorithm().FromBytes(b) != d {
+		return nil, fmt.Errorf("blob %s in the oci-layout cache is corrupt", d)
+	}
+	return b, nil
+}
+
+func digestFile(p string, alg digest.Algorithm) (digest.Digest, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return alg.FromReader(f)
+}
+
+// verifyBlobs re-hashes every blob in the oci-layout cache
+func verifyBlobs(ociLayoutRoot string) ([]*VerifyResult, error) {
+	blobsDir := filepath.Join(ociLayoutRoot, v1.ImageBlobsDir)
+	algs, err := os.ReadDir(blobsDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var results []*VerifyResult
+	for _, alg := range algs {
+		if !alg.IsDir() {
+			continue
+		}
+		algorithm := digest.Algorithm(alg.Name())
+		if !algorithm.Available() {
+			continue
+		}
+		blobs, err := os.ReadDir(filepath.Join(blobsDir, alg.Name()))
+		if err != nil {
+			return nil, err
+		}
+		for _, b := range blobs {
+			p := filepath.Join(blobsDir, alg.Name(), b.Name())
+			expected := digest.NewDigestFromEncoded(algorithm, b.Name())
+			r := &VerifyResult{Kind: VerifyBlob, Digest: expected.String(), Path: p}
+
+			actual, err := digestFile(p, algorithm)
+			if err != nil {
+				return nil, err
+			}
+			if actual != expected {
+				r.Problems = []string{fmt.Sprintf("content has digest %s", actual)}
+			}
+			r.OK = len(r.Problems) == 0
+			results = append(results, r)
+		}
+	}
+	return results, nil
+}
+
+func nonEmpty(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return []string{s}
+}
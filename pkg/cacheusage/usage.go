@@ -0,0 +1,509 @@
+// Copyright (c) 2017-2026 Digital Asset (Switzerland) GmbH and/or its affiliates. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package cacheusage determines which of the components, dars, git checkouts and OCI manifests in dpm-home's cache
+// are still referenced by an installed sdk, a (known) project or a lockfile, and by which.
+package cacheusage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"daml.com/x/assistant/pkg/assistantconfig"
+	"daml.com/x/assistant/pkg/damlpackage"
+	"daml.com/x/assistant/pkg/darmanifest"
+	"daml.com/x/assistant/pkg/gitsource"
+	"daml.com/x/assistant/pkg/httpdar"
+	"daml.com/x/assistant/pkg/multipackage"
+	"daml.com/x/assistant/pkg/ocilister"
+	"daml.com/x/assistant/pkg/packagelock"
+	"daml.com/x/assistant/pkg/sdkmanifest"
+	"daml.com/x/assistant/pkg/utils/stringset"
+	"github.com/Masterminds/semver/v3"
+	"github.com/opencontainers/go-digest"
+	"github.com/samber/lo"
+	"oras.land/oras-go/v2/registry"
+)
+
+const sdkManifestArtifactName = "assembly"
+
+// Usage is everything in dpm-home's cache that is still referenced by
+// an installed sdk, a (known) project or a lockfile
+type Usage struct {
+	config *assistantconfig.Config
+
+	// absolute paths of cached component and dar dirs, and of git checkouts and mirrors
+	dirs stringset.StringSet
+	// OCI manifest digests
+	digests stringset.StringSet
+	// version -> artifact names, as found in the OCI name and version annotations
+	artifacts map[string]stringset.StringSet
+	// dir or digest -> what references it (e.g. an installed sdk, or a daml.yaml)
+	referrers map[string]stringset.StringSet
+
+	// what's currently being added
+	source string
+
+	// known projects that no longer exist
+	GoneProjects []string
+}
+
+// Compute determines what's in use by the installed sdks, the current project,
+// the given lockfiles (dpm.lock or multi-package.lock) and the projects dpm remembers installing for
+func Compute(ctx context.Context, config *assistantconfig.Config, lockfiles []string) (*Usage, error) {
+	u := &Usage{
+		config:    config,
+		dirs:      make(stringset.StringSet),
+		digests:   make(stringset.StringSet),
+		artifacts: make(map[string]stringset.StringSet),
+		referrers: make(map[string]stringset.StringSet),
+	}
+
+	if err := u.addInstalledSdks(ctx); err != nil {
+		return nil, err
+	}
+
+	// the current project (if any) is always included, even if dpm hasn't seen it before
+	var projects []string
+	if p, ok, err := assistantconfig.GetMultiPackageAbsolutePath(); err != nil {
+		return nil, err
+	} else if ok {
+		projects = append(projects, filepath.Dir(p))
+	}
+	if p, ok, err := assistantconfig.GetDamlPackageAbsolutePath(); err != nil {
+		return nil, err
+	} else if ok {
+		projects = append(projects, filepath.Dir(p))
+	}
+
+	for _, lockfile := range lockfiles {
+		abs, err := filepath.Abs(lockfile)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := os.Stat(abs); err != nil {
+			return nil, fmt.Errorf("invalid lockfile %q: %w", lockfile, err)
+		}
+		if err := u.addLockfile(ctx, abs); err != nil {
+			return nil, err
+		}
+		projects = append(projects, filepath.Dir(abs))
+	}
+
+	for _, p := range projects {
+		if _, err := u.addProject(ctx, p); err != nil {
+			return nil, err
+		}
+	}
+
+	known, err := config.KnownProjects.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range known {
+		ok, err := u.addProject(ctx, p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to determine what known project %q uses: %w", p, err)
+		}
+		if !ok {
+			slog.Debug("known project no longer exists", "path", p)
+			u.GoneProjects = append(u.GoneProjects, p)
+		}
+	}
+
+	return u, nil
+}
+
+// ContainsDigest reports whether the OCI manifest with the given digest is in use
+func (u *Usage) ContainsDigest(d string) bool {
+	return u.digests.Contains(d)
+}
+
+// ContainsArtifact errs on the side of keeping things: third-party components are cached under their
+// fully-qualified '<registry>/<repo>' name, but might have been published with a shorter name annotation
+func (u *Usage) ContainsArtifact(name, version string) bool {
+	names, ok := u.artifacts[version]
+	if !ok {
+		return false
+	}
+	for n := range names {
+		if n == name || strings.HasSuffix(n, "/"+name) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsDirOrAncestor reports whether p is a dir in use, or contains one
+func (u *Usage) IsDirOrAncestor(p string) (live bool, ancestor bool) {
+	if u.dirs.Contains(p) {
+		return true, false
+	}
+	prefix := p + string(filepath.Separator)
+	for d := range u.dirs {
+		if strings.HasPrefix(d, prefix) {
+			return false, true
+		}
+	}
+	return false, false
+}
+
+// Referrers returns what references the given cached dir or OCI manifest digest, sorted
+func (u *Usage) Referrers(keys ...string) []string {
+	result := make(stringset.StringSet)
+	for _, k := range keys {
+		for r := range u.referrers[k] {
+			result.Add(r)
+		}
+	}
+	refs := lo.Keys(result)
+	slices.Sort(refs)
+	return refs
+}
+
+func (u *Usage) addDir(dir string) {
+	u.dirs.Add(dir)
+	u.addReferrer(dir)
+}
+
+func (u *Usage) addDigest(d string) {
+	u.digests.Add(d)
+	u.addReferrer(d)
+}
+
+func (u *Usage) addReferrer(key string) {
+	if u.source == "" {
+		return
+	}
+	if _, ok := u.referrers[key]; !ok {
+		u.referrers[key] = make(stringset.StringSet)
+	}
+	u.referrers[key].Add(u.source)
+}
+
+func (u *Usage) addArtifact(name, version string) {
+	if _, ok := u.artifacts[version]; !ok {
+		u.artifacts[version] = make(stringset.StringSet)
+	}
+	u.artifacts[version].Add(name)
+}
+
+func (u *Usage) addInstalledSdks(ctx context.Context) error {
+	var errs []error
+
+	for _, edition := range []sdkmanifest.Edition{sdkmanifest.OpenSource, sdkmanifest.Enterprise, sdkmanifest.Private} {
+		dir := filepath.Join(u.config.InstalledSdkManifestsPath, edition.String())
+		entries, err := os.ReadDir(dir)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		for _, e := range entries {
+			if e.IsDir() || !strings.HasSuffix(e.Name(), ".yaml") {
+				continue
+			}
+			p := filepath.Join(dir, e.Name())
+			m, err := sdkmanifest.ReadSdkManifest(p)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to read installed sdk manifest %q: %w", p, err))
+				continue
+			}
+
+			version := m.Spec.Version.Value().String()
+			u.source = fmt.Sprintf("sdk %s (%s)", version, edition)
+			u.addArtifact(sdkManifestArtifactName, version)
+			for _, comp := range m.Spec.Components {
+				if err := u.addComponent(ctx, comp); err != nil {
+					errs = append(errs, err)
+				}
+			}
+			if m.Spec.Assistant != nil {
+				if err := u.addComponent(ctx, m.Spec.Assistant); err != nil {
+					errs = append(errs, err)
+				}
+			}
+		}
+	}
+
+	u.source = ""
+	return errors.Join(errs...)
+}
+
+func (u *Usage) addComponent(ctx context.Context, comp *sdkmanifest.Component) error {
+	switch {
+	case comp.LocalPath != nil:
+		return nil
+	case comp.Git != nil:
+		src, err := gitsource.Parse(*comp.Git)
+		if err != nil {
+			return fmt.Errorf("invalid git source for component %q: %w", comp.Name, err)
+		}
+		return u.addGit(src, "")
+	case comp.Uri != nil:
+		uri, _, _ := ocilister.SplitRange(*comp.Uri)
+		ref, err := registry.ParseReference(strings.TrimPrefix(uri, "oci://"))
+		if err != nil {
+			return fmt.Errorf("invalid uri for component %q: %w", comp.Name, err)
+		}
+		d, err := ref.Digest()
+		if err != nil {
+			if ref.Reference == "" {
+				// a semver range that's yet to be installed (or else is kept by the lockfile)
+				return nil
+			}
+			// legacy (non sha-pinned) uri, cached under its repository rather than its name (see the assembler's legacyUriComponentLookup)
+			version, err := semver.StrictNewVersion(ref.Reference)
+			if err != nil {
+				return fmt.Errorf("failed to parse %q as strict semantic version in %q: %w", ref.Reference, *comp.Uri, err)
+			}
+			u.addDir(u.config.CachePathForComponent(fmt.Sprintf("%s/%s", ref.Registry, ref.Repository), version.String()))
+			u.addArtifact(comp.Name, version.String())
+			return nil
+		}
+
+		u.addDigest(d.String())
+		_, version, ok, err := u.config.CacheIndex.Get(ctx, d.String())
+		if err != nil {
+			return err
+		}
+		if ok {
+			u.addDir(u.config.CachePathForComponent(comp.Name, version))
+			u.addArtifact(comp.Name, version)
+		}
+		return nil
+	case comp.Version != nil:
+		version := comp.Version.Value().String()
+		u.addDir(u.config.CachePathForComponent(comp.Name, version))
+		u.addArtifact(comp.Name, version)
+		return nil
+	}
+	return nil
+}
+
+// addProject marks everything used by the daml package or multi-package (and all its packages) in dir.
+// It returns false if dir is no longer a project
+func (u *Usage) addProject(ctx context.Context, dir string) (bool, error) {
+	found := false
+
+	multiPackagePath := filepath.Join(dir, assistantconfig.DamlMultiPackageFilename)
+	if _, err := os.Stat(multiPackagePath); err == nil {
+		found = true
+		m, err := multipackage.Read(multiPackagePath)
+		if err != nil {
+			return true, fmt.Errorf("failed to read %q: %w", multiPackagePath, err)
+		}
+		u.source = multiPackagePath
+		defer func() { u.source = "" }()
+		for _, comp := range m.Components {
+			if err := u.addComponent(ctx, comp); err != nil {
+				return true, err
+			}
+		}
+		if err := u.addLockfile(ctx, filepath.Join(dir, assistantconfig.DpmMultiPackageLockFileName)); err != nil {
+			return true, err
+		}
+		for _, p := range m.AbsolutePackages() {
+			if _, err := u.addPackage(ctx, p); err != nil {
+				return true, err
+			}
+		}
+	} else if !os.IsNotExist(err) {
+		return false, err
+	}
+
+	ok, err := u.addPackage(ctx, dir)
+	return found || ok, err
+}
+
+func (u *Usage) addPackage(ctx context.Context, dir string) (bool, error) {
+	damlYamlPath := filepath.Join(dir, assistantconfig.DamlPackageFilename)
+	if _, err := os.Stat(damlYamlPath); os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	p, err := damlpackage.Read(damlYamlPath)
+	if err != nil {
+		return true, fmt.Errorf("failed to read %q: %w", damlYamlPath, err)
+	}
+
+	u.source = damlYamlPath
+	defer func() { u.source = "" }()
+
+	for _, comp := range p.Components {
+		if err := u.addComponent(ctx, comp); err != nil {
+			return true, err
+		}
+	}
+
+	deps := append(
+		lo.Values(p.ParsedDarDependencies.Dependencies),
+		lo.Values(p.ParsedDarDependencies.DataDependencies)...,
+	)
+	for _, d := range deps {
+		if d.Git != nil {
+			if err := u.addGit(d.Git, ""); err != nil {
+				return true, err
+			}
+			continue
+		}
+		if httpdar.IsHttp(d) && d.Digest != "" {
+			u.addDigest(d.Digest)
+			u.addDir(httpdar.Dir(u.config, digest.Digest(d.Digest)))
+			continue
+		}
+		if d.FullUrl.Scheme != "oci" {
+			continue
+		}
+		_, ref, err := d.GetOciRemote()
+		if err != nil {
+			return true, err
+		}
+		if dgst, err := ref.Digest(); err == nil {
+			u.addDigest(dgst.String())
+			u.addDir(u.config.CachePathForDar(ref))
+			u.addTransitiveDars(u.config.CachePathForDar(ref), map[string]bool{})
+		}
+	}
+
+	return true, u.addLockfile(ctx, filepath.Join(dir, assistantconfig.DpmLockFileName))
+}
+
+// addTransitiveDars marks the installed dars that the installed dar in darDir (transitively) depends on.
+// Dars that aren't installed (or can't be read) are skipped
+func (u *Usage) addTransitiveDars(darDir string, seen map[string]bool) {
+	m, err := darmanifest.ReadDarManifest(filepath.Join(darDir, assistantconfig.DarManifestName))
+	if err != nil {
+		return
+	}
+	for _, uri := range m.Spec.Dependencies {
+		ref, err := registry.ParseReference(strings.TrimPrefix(uri, "oci://"))
+		if err != nil || seen[uri] {
+			continue
+		}
+		seen[uri] = true
+		if dgst, err := ref.Digest(); err == nil {
+			u.addDigest(dgst.String())
+			u.addDir(u.config.CachePathForDar(&ref))
+			u.addTransitiveDars(u.config.CachePathForDar(&ref), seen)
+		}
+	}
+}
+
+func (u *Usage) addLockfile(ctx context.Context, lockfilePath string) error {
+	lock, err := packagelock.ReadPackageLock(lockfilePath)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to read lockfile %q: %w", lockfilePath, err)
+	}
+
+	prevSource := u.source
+	u.source = lockfilePath
+	defer func() { u.source = prevSource }()
+
+	darsRoot := filepath.Join(u.config.CachePath, "dars")
+	for _, d := range lock.Dars {
+		if d.URI != nil && d.URI.Scheme == "builtin" {
+			continue
+		}
+		if d.Commit != "" {
+			if err := u.addLockedGit(d.URI, d.Commit); err != nil {
+				return err
+			}
+		}
+		if d.Digest != "" {
+			u.addDigest(d.Digest)
+			u.addDir(u.config.CachePathForDar(&registry.Reference{Reference: d.Digest}))
+		}
+		if top, ok := topLevelDir(darsRoot, d.Path); ok {
+			u.addDir(top)
+		}
+	}
+
+	if lock.SdkVersion.Digest != "" {
+		u.addDigest(lock.SdkVersion.Digest)
+	}
+	for _, c := range lock.Components {
+		if c.Commit != "" {
+			if err := u.addLockedGit(c.URI, c.Commit); err != nil {
+				return err
+			}
+		}
+		if c.Digest == "" {
+			continue
+		}
+		u.addDigest(c.Digest)
+		_, version, ok, err := u.config.CacheIndex.Get(ctx, c.Digest)
+		if err != nil {
+			return err
+		}
+		if ok {
+			u.addDir(u.config.CachePathForComponent(c.Name, version))
+			u.addArtifact(c.Name, version)
+		}
+	}
+	return nil
+}
+
+// addGit marks the checkout of the git source at commit, along with its repo's mirror.
+// If commit is "", the source's rev is resolved from the mirror, as it would be without a lockfile
+func (u *Usage) addGit(src *gitsource.Source, commit string) error {
+	if commit == "" && src.IsCommit() {
+		commit = src.Rev
+	} else if commit == "" {
+		resolved, ok, err := gitsource.ResolveCached(u.config, src)
+		if err != nil {
+			return fmt.Errorf("failed to resolve %s: %w", src, err)
+		} else if !ok {
+			// yet to be fetched
+			return nil
+		}
+		commit = resolved
+	}
+	for _, p := range gitsource.CachePaths(u.config, src, commit) {
+		u.addDir(p)
+	}
+	return nil
+}
+
+func (u *Usage) addLockedGit(uri *url.URL, commit string) error {
+	if uri == nil || !gitsource.IsGit(uri.String()) {
+		return nil
+	}
+	src, err := gitsource.Parse(uri.String())
+	if err != nil {
+		return err
+	}
+	return u.addGit(src, commit)
+}
+
+// topLevelDir returns the dir directly under root (or under root/sha256) that contains p
+func topLevelDir(root, p string) (string, bool) {
+	if p == "" {
+		return "", false
+	}
+	rel, err := filepath.Rel(root, p)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return "", false
+	}
+
+	parts := strings.Split(rel, string(filepath.Separator))
+	if parts[0] == "sha256" {
+		if len(parts) < 2 {
+			return "", false
+		}
+		return filepath.Join(root, parts[0], parts[1]), true
+	}
+	return filepath.Join(root, parts[0]), true
+}
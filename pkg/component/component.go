@@ -7,9 +7,14 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"regexp"
+	"strings"
 
+	"daml.com/x/assistant/pkg/assistantconfig"
 	"daml.com/x/assistant/pkg/resolution"
 	"daml.com/x/assistant/pkg/schema"
+	"daml.com/x/assistant/pkg/utils"
+	"github.com/Masterminds/semver/v3"
 	"github.com/goccy/go-yaml"
 	"github.com/samber/lo"
 )
@@ -32,9 +37,96 @@ type Spec struct {
 	DependencyPaths map[string]string `yaml:"dependency-paths"`
 	NativeCommands  []NativeCommand   `yaml:"commands"`
 	JarCommands     []JarCommand      `yaml:"jar-commands"`
+	Groups          []Group           `yaml:"groups"`
 	Exports         Exports           `yaml:"exports"`
 }
 
+// Group is a namespace that commands (and other groups) can be nested under, e.g. canton in `dpm canton sandbox`
+type Group struct {
+	Name    string   `yaml:"name"`
+	Desc    *string  `yaml:"desc"`
+	Aliases []string `yaml:"aliases"`
+	// the group this one is nested under, if any
+	Group string `yaml:"group,omitempty"`
+}
+
+func (g *Group) UnmarshalYAML(data []byte) error {
+	type Alias Group
+	alias := Alias{}
+	if err := yaml.UnmarshalWithOptions(data, &alias, yaml.Strict()); err != nil {
+		return err
+	}
+	if alias.Name == "" {
+		return fmt.Errorf("%w: group 'name'", ErrMissingComponentField)
+	}
+	*g = Group(alias)
+	return nil
+}
+
+// GroupPath returns the groups that the given group is nested under, outermost first, and the group itself
+func (s *Spec) GroupPath(name string) []*Group {
+	var path []*Group
+	// bounded, in case of a cycle (which validateGroups rejects)
+	for name != "" && len(path) < len(s.Groups) {
+		_, i, ok := lo.FindIndexOf(s.Groups, func(g Group) bool { return g.Name == name })
+		if !ok {
+			break
+		}
+		path = append([]*Group{&s.Groups[i]}, path...)
+		name = s.Groups[i].Group
+	}
+	return path
+}
+
+func (s *Spec) validateGroups() error {
+	var errs []error
+	for name, count := range lo.CountValues(lo.Map(s.Groups, func(g Group, _ int) string { return g.Name })) {
+		if count > 1 {
+			errs = append(errs, fmt.Errorf("group %q is defined %d times", name, count))
+		}
+	}
+
+	exists := func(name string) bool {
+		return lo.ContainsBy(s.Groups, func(g Group) bool { return g.Name == name })
+	}
+	for _, g := range s.Groups {
+		if g.Group != "" && !exists(g.Group) {
+			errs = append(errs, fmt.Errorf("group %q is nested under unknown group %q", g.Name, g.Group))
+			continue
+		}
+		// a path that stops short of the top-level, at a group that exists, loops back on itself
+		if path := s.GroupPath(g.Name); len(path) > 0 && path[0].Group != "" && exists(path[0].Group) {
+			errs = append(errs, fmt.Errorf("group %q is nested under itself", g.Name))
+		}
+	}
+	for _, c := range s.AllCommands() {
+		if c.GetGroup() != "" && !exists(c.GetGroup()) {
+			errs = append(errs, fmt.Errorf("command %q is in unknown group %q", c.GetName(), c.GetGroup()))
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidComponentManifest, err)
+	}
+	return nil
+}
+
+// validateEnv checks that the commands' env doesn't set the env vars their dependency-paths are set in
+func (s *Spec) validateEnv() error {
+	var errs []error
+	for _, c := range s.AllCommands() {
+		for dep, envVar := range s.DependencyPaths {
+			if _, ok := c.GetEnvironment().Env[envVar]; ok {
+				errs = append(errs, fmt.Errorf("command %q can't set env var %q: it's the path of dependency %q", c.GetName(), envVar, dep))
+			}
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidComponentManifest, err)
+	}
+	return nil
+}
+
 const (
 	ExportConflictStrategyExtend = "extend"
 	ExportConflictStrategyFail   = "fail"
@@ -94,6 +186,122 @@ type Command interface {
 	GetPath() string
 	GetAliases() []string
 	GetDesc() *string
+	GetEnvironment() *Environment
+	// GetGroup returns the name of the group the command is nested under, "" for a top-level command
+	GetGroup() string
+	// GetCompletion returns how the command's args are completed, nil if they aren't
+	GetCompletion() *Completion
+}
+
+const (
+	// WorkingDirCwd runs the command in dpm's own working dir (the default)
+	WorkingDirCwd = "cwd"
+	// WorkingDirPackage runs the command in the root of the daml package in scope
+	WorkingDirPackage = "package"
+	// WorkingDirComponent runs the command in the root of its component
+	WorkingDirComponent = "component"
+)
+
+var WorkingDirs = []string{WorkingDirCwd, WorkingDirPackage, WorkingDirComponent}
+
+// reservedEnvVars are the env vars dpm sets for every command, so they can't be set through its env
+var reservedEnvVars = []string{
+	assistantconfig.DpmPathInjectedEnvVar,
+	assistantconfig.ResolutionFilePathEnvVar,
+	assistantconfig.DpmSdkVersionEnvVar,
+	assistantconfig.DamlPackageEnvVar,
+}
+
+// componentPathRegex matches ${component:<name>}, which env and path-prepends expand to the root of the named component
+var componentPathRegex = regexp.MustCompile(`\$\{component:([^}]*)\}`)
+
+// Environment is the environment and working dir a command's process runs with
+type Environment struct {
+	// env vars to set, which may refer to the root of any component in the assembly as ${component:<name>}
+	Env map[string]string `yaml:"env,omitempty"`
+	// dirs to prepend to PATH, relative to the component's root (or to another component's, via ${component:<name>})
+	PathPrepends []string `yaml:"path-prepends,omitempty"`
+	// one of WorkingDirs, WorkingDirCwd by default
+	WorkingDir string `yaml:"working-dir,omitempty"`
+}
+
+func (e *Environment) validate() error {
+	var errs []error
+	for k, v := range e.Env {
+		if !utils.IsValidEnvVarIdentifier(k) {
+			errs = append(errs, fmt.Errorf("invalid env var name %q", k))
+		}
+		if strings.EqualFold(k, "PATH") {
+			errs = append(errs, fmt.Errorf("env var %q can't be set: use 'path-prepends' instead", k))
+		}
+		if lo.Contains(reservedEnvVars, k) {
+			errs = append(errs, fmt.Errorf("env var %q can't be set: it's set by dpm", k))
+		}
+		errs = append(errs, validateComponentRefs(v))
+	}
+	for _, p := range e.PathPrepends {
+		if p == "" {
+			errs = append(errs, fmt.Errorf("'path-prepends' can't contain empty paths"))
+		}
+		errs = append(errs, validateComponentRefs(p))
+	}
+	if e.WorkingDir != "" && !lo.Contains(WorkingDirs, e.WorkingDir) {
+		errs = append(errs, fmt.Errorf("unknown 'working-dir' %q. Must be one of %q", e.WorkingDir, WorkingDirs))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidComponentManifest, err)
+	}
+	return nil
+}
+
+func validateComponentRefs(s string) error {
+	for _, m := range componentPathRegex.FindAllStringSubmatch(s, -1) {
+		if m[1] == "" {
+			return fmt.Errorf("%q refers to a component without naming it", s)
+		}
+	}
+	return nil
+}
+
+// ExpandComponentPaths replaces every ${component:<name>} in s with the root of the named component
+func ExpandComponentPaths(s string, componentPaths map[string]string) (string, error) {
+	var errs []error
+	expanded := componentPathRegex.ReplaceAllStringFunc(s, func(ref string) string {
+		name := componentPathRegex.FindStringSubmatch(ref)[1]
+		p, ok := componentPaths[name]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%q refers to component %q, which wasn't included in the assembly", s, name))
+		}
+		return p
+	})
+	return expanded, errors.Join(errs...)
+}
+
+// Completion is how dpm completes a command's args in the shell: from static lists of flags and subcommands,
+// or by proxying to the command itself, if it implements cobra's __complete protocol
+type Completion struct {
+	Flags       []string `yaml:"flags,omitempty"`
+	Subcommands []string `yaml:"subcommands,omitempty"`
+	Cobra       bool     `yaml:"cobra,omitempty"`
+}
+
+func (c *Completion) validate() error {
+	if c == nil {
+		return nil
+	}
+	var errs []error
+	if c.Cobra && len(c.Flags)+len(c.Subcommands) > 0 {
+		errs = append(errs, fmt.Errorf("'completion' takes either 'cobra' or static 'flags' and 'subcommands', not both"))
+	}
+	for _, f := range c.Flags {
+		if !strings.HasPrefix(f, "-") {
+			errs = append(errs, fmt.Errorf("completion flag %q must start with '-'", f))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidComponentManifest, err)
+	}
+	return nil
 }
 
 type JarCommand struct {
@@ -101,8 +309,14 @@ type JarCommand struct {
 	Path    string   `yaml:"path"`
 	Desc    *string  `yaml:"desc"`
 	Aliases []string `yaml:"aliases"`
+	Group   string   `yaml:"group,omitempty"`
 	JarArgs []string `yaml:"jar-args"`
 	JvmArgs []string `yaml:"jvm-args"`
+	// the range of java versions the jar runs on (e.g. ">=17, <22"), checked before it's run
+	JavaVersion string      `yaml:"java-version,omitempty"`
+	Completion  *Completion `yaml:"completion,omitempty"`
+
+	Environment `yaml:",inline"`
 }
 
 func (cmd *JarCommand) GetDesc() *string {
@@ -121,12 +335,28 @@ func (cmd *JarCommand) GetAliases() []string {
 	return cmd.Aliases
 }
 
+func (cmd *JarCommand) GetEnvironment() *Environment {
+	return &cmd.Environment
+}
+
+func (cmd *JarCommand) GetGroup() string {
+	return cmd.Group
+}
+
+func (cmd *JarCommand) GetCompletion() *Completion {
+	return cmd.Completion
+}
+
 type NativeCommand struct {
-	Name     string   `yaml:"name"`
-	Path     string   `yaml:"path"`
-	Desc     *string  `yaml:"desc"`
-	Aliases  []string `yaml:"aliases"`
-	ExecArgs []string `yaml:"exec-args"`
+	Name       string      `yaml:"name"`
+	Path       string      `yaml:"path"`
+	Desc       *string     `yaml:"desc"`
+	Aliases    []string    `yaml:"aliases"`
+	Group      string      `yaml:"group,omitempty"`
+	ExecArgs   []string    `yaml:"exec-args"`
+	Completion *Completion `yaml:"completion,omitempty"`
+
+	Environment `yaml:",inline"`
 }
 
 func (cmd *NativeCommand) GetDesc() *string {
@@ -145,6 +375,18 @@ func (cmd *NativeCommand) GetAliases() []string {
 	return cmd.Aliases
 }
 
+func (cmd *NativeCommand) GetEnvironment() *Environment {
+	return &cmd.Environment
+}
+
+func (cmd *NativeCommand) GetGroup() string {
+	return cmd.Group
+}
+
+func (cmd *NativeCommand) GetCompletion() *Completion {
+	return cmd.Completion
+}
+
 func (cmd *JarCommand) isComponentCommand() bool {
 	return true
 }
@@ -160,6 +402,17 @@ func (cmd *JarCommand) UnmarshalYAML(data []byte) error {
 	if alias.Name == "" {
 		return fmt.Errorf("%w: 'name'", ErrMissingComponentField)
 	}
+	if alias.JavaVersion != "" {
+		if _, err := semver.NewConstraint(alias.JavaVersion); err != nil {
+			return fmt.Errorf("%w: command %q has an invalid 'java-version' %q: %w", ErrInvalidComponentManifest, alias.Name, alias.JavaVersion, err)
+		}
+	}
+	if err := alias.Environment.validate(); err != nil {
+		return fmt.Errorf("command %q: %w", alias.Name, err)
+	}
+	if err := alias.Completion.validate(); err != nil {
+		return fmt.Errorf("command %q: %w", alias.Name, err)
+	}
 	*cmd = JarCommand(alias)
 	return nil
 }
@@ -179,6 +432,12 @@ func (cmd *NativeCommand) UnmarshalYAML(data []byte) error {
 	if alias.Name == "" {
 		return fmt.Errorf("%w: 'name'", ErrMissingComponentField)
 	}
+	if err := alias.Environment.validate(); err != nil {
+		return fmt.Errorf("command %q: %w", alias.Name, err)
+	}
+	if err := alias.Completion.validate(); err != nil {
+		return fmt.Errorf("command %q: %w", alias.Name, err)
+	}
 	*cmd = NativeCommand(alias)
 	return nil
 }
@@ -210,11 +469,19 @@ func ReadComponentContents(contents []byte) (*Component, error) {
 		return nil, fmt.Errorf("%w: 'spec'", ErrMissingComponentField)
 	}
 
+	if err := c.Spec.validateGroups(); err != nil {
+		return nil, err
+	}
+	if err := c.Spec.validateEnv(); err != nil {
+		return nil, err
+	}
+
 	return &c, nil
 }
 
 var _ yaml.BytesUnmarshaler = (*Exports)(nil)
 var _ yaml.BytesUnmarshaler = (*NativeCommand)(nil)
 var _ yaml.BytesUnmarshaler = (*JarCommand)(nil)
+var _ yaml.BytesUnmarshaler = (*Group)(nil)
 var _ Command = (*JarCommand)(nil)
 var _ Command = (*NativeCommand)(nil)
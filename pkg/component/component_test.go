@@ -21,3 +21,85 @@ func TestReadComponentContents(t *testing.T) {
 		assert.ErrorIs(t, err, ErrInvalidComponentManifest)
 	}
 }
+
+func TestCommandEnvironment(t *testing.T) {
+	c, err := ReadComponentContents(testdata.Environment)
+	require.NoError(t, err)
+
+	native := c.Spec.NativeCommands[0].GetEnvironment()
+	assert.Equal(t, map[string]string{"CANTON_LIB": "${component:canton}/lib", "LOG_LEVEL": "info"}, native.Env)
+	assert.Equal(t, []string{"bin", "${component:canton}/bin"}, native.PathPrepends)
+	assert.Equal(t, WorkingDirPackage, native.WorkingDir)
+	assert.Equal(t, WorkingDirComponent, c.Spec.JarCommands[0].GetEnvironment().WorkingDir)
+
+	_, err = ReadComponentContents(testdata.InvalidEnvironment)
+	assert.ErrorIs(t, err, ErrInvalidComponentManifest)
+	for _, msg := range []string{`"PATH" can't be set`, `"DPM_SDK_VERSION" can't be set`, `invalid env var name "not a name"`, "without naming it", `unknown 'working-dir' "home"`} {
+		assert.ErrorContains(t, err, msg)
+	}
+
+	_, err = ReadComponentContents(testdata.EnvDependencyPath)
+	assert.ErrorIs(t, err, ErrInvalidComponentManifest)
+	assert.ErrorContains(t, err, `"JAVA_HOME": it's the path of dependency "jdk"`)
+}
+
+func TestExpandComponentPaths(t *testing.T) {
+	paths := map[string]string{"canton": "/dpm/cache/components/canton/3.4.0"}
+
+	s, err := ExpandComponentPaths("${component:canton}/lib:${component:canton}/ext", paths)
+	require.NoError(t, err)
+	assert.Equal(t, "/dpm/cache/components/canton/3.4.0/lib:/dpm/cache/components/canton/3.4.0/ext", s)
+
+	_, err = ExpandComponentPaths("${component:damlc}/lib", paths)
+	assert.ErrorContains(t, err, `component "damlc"`)
+}
+
+func TestJavaVersion(t *testing.T) {
+	c, err := ReadComponentContents(testdata.JavaVersion)
+	require.NoError(t, err)
+	assert.Equal(t, ">=17, <22", c.Spec.JarCommands[0].JavaVersion)
+
+	_, err = ReadComponentContents(testdata.InvalidJavaVersion)
+	assert.ErrorIs(t, err, ErrInvalidComponentManifest)
+	assert.ErrorContains(t, err, `invalid 'java-version' "seventeen"`)
+}
+
+func TestGroups(t *testing.T) {
+	c, err := ReadComponentContents(testdata.Groups)
+	require.NoError(t, err)
+
+	names := func(groups []*Group) (names []string) {
+		for _, g := range groups {
+			names = append(names, g.Name)
+		}
+		return
+	}
+	assert.Equal(t, []string{"canton"}, names(c.Spec.GroupPath(c.Spec.NativeCommands[0].GetGroup())))
+	assert.Equal(t, []string{"canton", "admin"}, names(c.Spec.GroupPath(c.Spec.NativeCommands[1].GetGroup())))
+	assert.Equal(t, []string{"canton"}, names(c.Spec.GroupPath(c.Spec.JarCommands[0].GetGroup())))
+	assert.Empty(t, c.Spec.GroupPath(c.Spec.JarCommands[1].GetGroup()))
+
+	_, err = ReadComponentContents(testdata.InvalidGroups)
+	assert.ErrorIs(t, err, ErrInvalidComponentManifest)
+	for _, msg := range []string{
+		`group "canton" is defined 2 times`,
+		`group "chicken" is nested under itself`,
+		`group "egg" is nested under itself`,
+		`group "orphan" is nested under unknown group "nowhere"`,
+		`command "sandbox" is in unknown group "cantonn"`,
+	} {
+		assert.ErrorContains(t, err, msg)
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	c, err := ReadComponentContents(testdata.Completion)
+	require.NoError(t, err)
+	assert.Equal(t, &Completion{Flags: []string{"--config", "--port"}, Subcommands: []string{"daemon", "sandbox"}}, c.Spec.NativeCommands[0].GetCompletion())
+	assert.Equal(t, &Completion{Cobra: true}, c.Spec.JarCommands[0].GetCompletion())
+
+	_, err = ReadComponentContents(testdata.InvalidCompletion)
+	assert.ErrorIs(t, err, ErrInvalidComponentManifest)
+	assert.ErrorContains(t, err, "either 'cobra' or static 'flags' and 'subcommands'")
+	assert.ErrorContains(t, err, `completion flag "config" must start with '-'`)
+}
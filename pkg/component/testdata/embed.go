@@ -19,3 +19,30 @@ var UnknownField []byte
 
 //go:embed unknown-strategy-type.yaml
 var UnknownExportStrategy []byte
+
+//go:embed environment.yaml
+var Environment []byte
+
+//go:embed invalid-environment.yaml
+var InvalidEnvironment []byte
+
+//go:embed env-dependency-path.yaml
+var EnvDependencyPath []byte
+
+//go:embed java-version.yaml
+var JavaVersion []byte
+
+//go:embed invalid-java-version.yaml
+var InvalidJavaVersion []byte
+
+//go:embed groups.yaml
+var Groups []byte
+
+//go:embed invalid-groups.yaml
+var InvalidGroups []byte
+
+//go:embed completion.yaml
+var Completion []byte
+
+//go:embed invalid-completion.yaml
+var InvalidCompletion []byte
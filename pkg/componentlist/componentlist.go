@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"strings"
 
+	"daml.com/x/assistant/pkg/gitsource"
+	"daml.com/x/assistant/pkg/ocilister"
 	"daml.com/x/assistant/pkg/sdkmanifest"
 	"daml.com/x/assistant/pkg/yamledit"
 	"github.com/Masterminds/semver/v3"
@@ -12,7 +14,7 @@ import (
 	"oras.land/oras-go/v2/registry"
 )
 
-var SchemaError = fmt.Errorf(`component must be one of "<name>:<version>", "oci://<reference>" or {name: "<name>", path: "<path to component directory>"}`)
+var SchemaError = fmt.Errorf(`component must be one of "<name>:<version or semver range>", "oci://<reference>", "git+https://<host>/<repo>[//<subdir>]@<rev>" or {name: "<name>", path: "<path to component directory>"}`)
 
 type ComponentList []*ComponentEntry
 
@@ -105,13 +107,22 @@ func fromFileBasedComponent(c *FileBased) (string, *sdkmanifest.Component, error
 
 func fromStringBasedComponent(c string) (string, *sdkmanifest.Component, error) {
 	if strings.HasPrefix(c, "oci://") { // oci://whatever.dev/foo/bar/comp:1.2.3
-		u, err := registry.ParseReference(strings.TrimPrefix(c, "oci://"))
+		withoutRange, _, _ := ocilister.SplitRange(c)
+		u, err := registry.ParseReference(strings.TrimPrefix(withoutRange, "oci://"))
 		if err != nil {
 			return "", nil, fmt.Errorf("couldn't parse component url %q: %w", c, err)
 		}
 		name := fmt.Sprintf("%s/%s", u.Registry, u.Repository)
 
 		return name, &sdkmanifest.Component{Name: name, Uri: &c}, nil
+	} else if gitsource.IsGit(c) { // git+https://github.com/foo/bar//components/comp@v1.2.3
+		src, err := gitsource.Parse(c)
+		if err != nil {
+			return "", nil, err
+		}
+		name := src.Name()
+
+		return name, &sdkmanifest.Component{Name: name, Git: &c}, nil
 	} else if strings.Contains(c, "@") && !strings.Contains(c, "/") {
 		return "", nil, fmt.Errorf("invalid uri: currently, opt-in components that have '@sha256' must have fully-qualified uri beginning with 'oci://'")
 	} else if strings.Contains(c, ":") && !strings.Contains(c, "/") {
@@ -119,6 +130,11 @@ func fromStringBasedComponent(c string) (string, *sdkmanifest.Component, error)
 		parts := strings.Split(c, ":")
 		name, version := parts[0], parts[1]
 
+		// e.g. "damlc:^1.2"
+		if ocilister.IsRange(version) {
+			return name, &sdkmanifest.Component{Name: name, Range: version}, nil
+		}
+
 		semVer, err := semver.StrictNewVersion(version)
 		if err != nil {
 			return "", nil, fmt.Errorf("couldn't parse component's %q tag as semver (for floaty-tags, use fully-qualified 'oci://' URIs): %w", c, err)
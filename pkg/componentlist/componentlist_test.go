@@ -36,3 +36,43 @@ func TestComponentList(t *testing.T) {
 	uri = "oci://127.0.0.1:8080/foo/baz:1.2.3"
 	assert.Equal(t, &sdkmanifest.Component{Name: "127.0.0.1:8080/foo/baz", Uri: &uri}, cs["127.0.0.1:8080/foo/baz"])
 }
+
+func TestComponentListRanges(t *testing.T) {
+	m := Manifest{}
+	require.NoError(t, yaml.Unmarshal([]byte(`components:
+  - damlc:^3.4
+  - "daml-script:>=1.0 <2.0"
+  - oci://example.com/a/b/foo:~1.2.3@sha256:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08
+`), &m))
+
+	cs, err := m.Components.ToMap(nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, &sdkmanifest.Component{Name: "damlc", Range: "^3.4"}, cs["damlc"])
+	assert.Equal(t, &sdkmanifest.Component{Name: "daml-script", Range: ">=1.0 <2.0"}, cs["daml-script"])
+
+	uri := "oci://example.com/a/b/foo:~1.2.3@sha256:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	assert.Equal(t, &sdkmanifest.Component{Name: "example.com/a/b/foo", Uri: &uri}, cs["example.com/a/b/foo"])
+}
+
+func TestComponentListGit(t *testing.T) {
+	m := Manifest{}
+	require.NoError(t, yaml.Unmarshal([]byte(`components:
+  - git+https://github.com/example/tools.git//components/foo@v1.2.3
+  - git+https://github.com/example/bar@main
+`), &m))
+
+	cs, err := m.Components.ToMap(nil)
+	require.NoError(t, err)
+
+	git := "git+https://github.com/example/tools.git//components/foo@v1.2.3"
+	assert.Equal(t, &sdkmanifest.Component{Name: "github.com/example/tools/components/foo", Git: &git}, cs["github.com/example/tools/components/foo"])
+	git = "git+https://github.com/example/bar@main"
+	assert.Equal(t, &sdkmanifest.Component{Name: "github.com/example/bar", Git: &git}, cs["github.com/example/bar"])
+
+	require.NoError(t, yaml.Unmarshal([]byte(`components:
+  - git+https://github.com/example/bar
+`), &m))
+	_, err = m.Components.ToMap(nil)
+	assert.ErrorIs(t, err, SchemaError)
+}
@@ -7,12 +7,16 @@ import (
 	"fmt"
 	"maps"
 	"os"
+	"slices"
 	"strings"
 
 	"daml.com/x/assistant/pkg/componentlist"
+	"daml.com/x/assistant/pkg/hooks"
 	"daml.com/x/assistant/pkg/sdkmanifest"
 	"daml.com/x/assistant/pkg/yamledit"
 	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
 )
 
 type ParsedDarDependencies struct {
@@ -23,6 +27,10 @@ type ParsedDarDependencies struct {
 type DamlPackage struct {
 	SdkVersion string `yaml:"sdk-version"`
 
+	// the package's name and version, which name the dar damlc builds
+	Name    string `yaml:"name,omitempty"`
+	Version string `yaml:"version,omitempty"`
+
 	ComponentsList componentlist.ComponentList       `yaml:"components,omitempty"`
 	Components     map[string]*sdkmanifest.Component `yaml:"-"`
 
@@ -34,6 +42,9 @@ type DamlPackage struct {
 	ArtifactLocations     ArtifactLocations      `yaml:"artifact-locations,omitempty"`
 	ParsedDarDependencies *ParsedDarDependencies `yaml:"-"`
 
+	// shell commands to run before and after SDK commands
+	Hooks hooks.Hooks `yaml:"hooks,omitempty"`
+
 	// absolute path to daml.yaml
 	AbsolutePath string `yaml:"-"`
 }
@@ -47,7 +58,8 @@ func Read(absoluteFilePath string) (*DamlPackage, error) {
 }
 
 func ReadFromContents(contents []byte, absoluteFilePath string) (*DamlPackage, error) {
-	expanded, err := expandEnv(contents)
+	hooksNode, rest := splitHooks(contents)
+	expanded, err := expandEnv(rest)
 	if err != nil {
 		return nil, err
 	}
@@ -56,6 +68,11 @@ func ReadFromContents(contents []byte, absoluteFilePath string) (*DamlPackage, e
 	if err := yaml.UnmarshalWithOptions(expanded, &obj); err != nil {
 		return nil, err
 	}
+	if hooksNode != nil {
+		if err := yaml.NodeToValue(hooksNode, &obj.Hooks); err != nil {
+			return nil, fmt.Errorf("invalid 'hooks': %w", err)
+		}
+	}
 
 	obj.AbsolutePath = absoluteFilePath
 
@@ -111,6 +128,37 @@ func ReadFromContents(contents []byte, absoluteFilePath string) (*DamlPackage, e
 	return &obj, nil
 }
 
+// splitHooks returns the (top-level) hooks of a daml.yaml, and the rest of it with their lines blanked out.
+// The hooks aren't env-expanded like the rest, as the shell expands their env vars (including the ones dpm injects) when they run
+func splitHooks(contents []byte) (ast.Node, []byte) {
+	f, err := parser.ParseBytes(contents, 0)
+	if err != nil || len(f.Docs) == 0 {
+		return nil, contents
+	}
+	m, ok := f.Docs[0].Body.(*ast.MappingNode)
+	if !ok || m.IsFlowStyle {
+		return nil, contents
+	}
+
+	i := slices.IndexFunc(m.Values, func(mv *ast.MappingValueNode) bool {
+		return mv.Key.GetToken().Value == "hooks"
+	})
+	if i < 0 {
+		return nil, contents
+	}
+	lines := strings.SplitAfter(string(contents), "\n")
+	// 0-based, up to the next top-level field
+	start, end := m.Values[i].Key.GetToken().Position.Line-1, len(lines)
+	if i+1 < len(m.Values) {
+		end = m.Values[i+1].Key.GetToken().Position.Line - 1
+	}
+	for l := start; l < end && l < len(lines); l++ {
+		// keeps the line endings, so that the line numbers in errors still match
+		lines[l] = lines[l][len(strings.TrimRight(lines[l], "\r\n")):]
+	}
+	return m.Values[i].Value, []byte(strings.Join(lines, ""))
+}
+
 func expandEnv(contents []byte) ([]byte, error) {
 	var undefinedVars []string
 
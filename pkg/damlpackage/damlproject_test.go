@@ -6,7 +6,9 @@ import (
 	"testing"
 
 	"daml.com/x/assistant/pkg/assistantconfig"
+	"daml.com/x/assistant/pkg/hooks"
 	"daml.com/x/assistant/pkg/testutil"
+	"github.com/samber/lo"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -65,3 +67,117 @@ override-components:
 	})
 
 }
+
+func TestDarDependencyRanges(t *testing.T) {
+	p := &DamlPackage{}
+	parsed, err := p.parseLocations([]*RawDependency{
+		{ValueOnly: lo.ToPtr("oci://example.com/dars/foo:^1.4")},
+		{ValueOnly: lo.ToPtr("oci://example.com/dars/bar:>=1.0 <2.0@sha256:abcd")},
+		{ValueOnly: lo.ToPtr("oci://example.com/dars/baz:1.2.3")},
+	}, nil)
+	require.NoError(t, err)
+
+	foo := parsed["oci://example.com/dars/foo:^1.4"]
+	assert.Equal(t, "oci://example.com/dars/foo", foo.FullUrl.String())
+	assert.Equal(t, "^1.4", foo.Range)
+	assert.Equal(t, "oci://example.com/dars/foo:^1.4", foo.StringWithAlias())
+
+	bar := parsed["oci://example.com/dars/bar:>=1.0 <2.0@sha256:abcd"]
+	assert.Equal(t, "oci://example.com/dars/bar@sha256:abcd", bar.FullUrl.String())
+	assert.Equal(t, ">=1.0 <2.0", bar.Range)
+	assert.Equal(t, "oci://example.com/dars/bar:>=1.0 <2.0@sha256:abcd", bar.StringWithAlias())
+
+	assert.Empty(t, parsed["oci://example.com/dars/baz:1.2.3"].Range)
+}
+
+func TestHttpDarDependencies(t *testing.T) {
+	sha := "sha256:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	p := &DamlPackage{}
+	locations := ArtifactLocations{
+		"@partner":  {Alias: "@partner", Url: "https://releases.example.com/dars", Auth: "/auth.json"},
+		"@internal": {Alias: "@internal", Url: "http://localhost:8080", Insecure: true},
+	}
+	parsed, err := p.parseLocations([]*RawDependency{
+		{ValueOnly: lo.ToPtr("https://example.com/foo-1.0.0.dar@" + sha)},
+		{ValueOnly: lo.ToPtr("https://example.com/bar-1.0.0.dar")},
+		{ValueOnly: lo.ToPtr("@partner/baz-2.0.0.dar@" + sha)},
+		{ValueOnly: lo.ToPtr("@internal/qux-1.0.0.dar@" + sha)},
+	}, locations)
+	require.NoError(t, err)
+
+	foo := parsed["https://example.com/foo-1.0.0.dar@"+sha]
+	assert.Equal(t, "https://example.com/foo-1.0.0.dar", foo.FullUrl.String())
+	assert.Equal(t, sha, foo.Digest)
+	assert.Equal(t, "https://example.com/foo-1.0.0.dar@"+sha, foo.String())
+	assert.Nil(t, foo.Location)
+
+	assert.Empty(t, parsed["https://example.com/bar-1.0.0.dar"].Digest)
+
+	baz := parsed["@partner/baz-2.0.0.dar@"+sha]
+	assert.Equal(t, "https://releases.example.com/dars/baz-2.0.0.dar", baz.FullUrl.String())
+	assert.Equal(t, "/auth.json", baz.Location.Auth)
+	assert.Equal(t, "@partner/baz-2.0.0.dar@"+sha, baz.StringWithAlias())
+
+	assert.Equal(t, "http://localhost:8080/qux-1.0.0.dar", parsed["@internal/qux-1.0.0.dar@"+sha].FullUrl.String())
+
+	_, err = p.parseLocations([]*RawDependency{{ValueOnly: lo.ToPtr("http://example.com/foo.dar")}}, nil)
+	assert.ErrorContains(t, err, "must use https")
+	_, err = p.parseLocations([]*RawDependency{{ValueOnly: lo.ToPtr("@internal/foo.dar")}}, locations)
+	assert.ErrorContains(t, err, "must use https", "not even via an insecure artifact-location")
+
+	_, err = p.parseLocations([]*RawDependency{{ValueOnly: lo.ToPtr("https://example.com/foo.dar@sha256:abcd")}}, nil)
+	assert.ErrorContains(t, err, "invalid sha256 digest")
+}
+
+func TestGitDarDependencies(t *testing.T) {
+	p := &DamlPackage{}
+	locations := ArtifactLocations{
+		"@partner": {Alias: "@partner", Url: "git+https://github.com/partner", Auth: "/auth.json"},
+	}
+	parsed, err := p.parseLocations([]*RawDependency{
+		{ValueOnly: lo.ToPtr("git+https://github.com/example/foo//dars/foo-1.0.0.dar@v1.0.0")},
+		{ValueOnly: lo.ToPtr("@partner/bar//bar.dar@main")},
+	}, locations)
+	require.NoError(t, err)
+
+	foo := parsed["git+https://github.com/example/foo//dars/foo-1.0.0.dar@v1.0.0"]
+	assert.Equal(t, "git+https://github.com/example/foo//dars/foo-1.0.0.dar@v1.0.0", foo.String())
+	assert.Equal(t, "https://github.com/example/foo", foo.Git.Repo)
+	assert.Equal(t, "dars/foo-1.0.0.dar", foo.Git.Subdir)
+	assert.Equal(t, "v1.0.0", foo.Git.Rev)
+
+	bar := parsed["@partner/bar//bar.dar@main"]
+	assert.Equal(t, "https://github.com/partner/bar", bar.Git.Repo)
+	assert.Equal(t, "/auth.json", bar.Location.Auth)
+	assert.Equal(t, "@partner/bar//bar.dar@main", bar.StringWithAlias())
+
+	_, err = p.parseLocations([]*RawDependency{{ValueOnly: lo.ToPtr("git+https://github.com/example/foo//foo.dar")}}, nil)
+	assert.ErrorContains(t, err, "must be pinned")
+}
+
+func TestHooksArentEnvExpanded(t *testing.T) {
+	t.Setenv("TEST_DPM_VERSION", "1.0.0")
+	p, err := ReadFromContents(makeDamlYaml(
+		"name: foo",
+		"hooks:",
+		"  pre-build: echo $DAML_PACKAGE",
+		"  post-build:",
+		"    - echo ${TEST_DPM_UNSET}",
+		"",
+		"# the version",
+		"version: ${TEST_DPM_VERSION}",
+	), "")
+	require.NoError(t, err)
+	assert.Equal(t, hooks.Hooks{
+		"pre-build":  {"echo $DAML_PACKAGE"},
+		"post-build": {"echo ${TEST_DPM_UNSET}"},
+	}, p.Hooks)
+	assert.Equal(t, "1.0.0", p.Version)
+
+	// everywhere else, they still have to be set
+	_, err = ReadFromContents(makeDamlYaml("name: ${TEST_DPM_UNSET}", "hooks:", "  pre-build: echo"), "")
+	assert.ErrorContains(t, err, "TEST_DPM_UNSET")
+
+	_, err = ReadFromContents(makeDamlYaml("hooks:", "  build: echo"), "")
+	assert.ErrorContains(t, err, `invalid hook "build"`)
+}
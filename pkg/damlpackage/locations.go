@@ -1,6 +1,7 @@
 package damlpackage
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/url"
@@ -9,7 +10,11 @@ import (
 	"strings"
 
 	"daml.com/x/assistant/pkg/assistantconfig/assistantremote"
+	"daml.com/x/assistant/pkg/gitsource"
+	"daml.com/x/assistant/pkg/ocilister"
 	"daml.com/x/assistant/pkg/utils"
+	"github.com/opencontainers/go-digest"
+	"github.com/samber/lo"
 	"oras.land/oras-go/v2/registry"
 	"oras.land/oras-go/v2/registry/remote/auth"
 )
@@ -28,24 +33,59 @@ type ArtifactLocation struct {
 
 type ParsedDarDependency struct {
 	// the fully-qualified URL for the artifact e.g. oci://example.com/foo/bar/baz:1.2.3
+	// (without the tag, if it's a semver range)
 	FullUrl *url.URL
 
+	// the semver range (e.g. ^1.4) the dependency's tag is, if any.
+	// It's resolved to the highest matching version by GetResolvedOciRemote
+	Range string
+
 	// can be nil when the corresponding dependency is already fully qualified and doesn't rely on an artifact-location
 	Location *ArtifactLocation
 
 	MainPackageId *string
 
+	// the sha256 digest of the dar file an http(s) dependency is pinned to inline
+	// (e.g. https://example.com/foo-1.0.0.dar@sha256:...), if any
+	Digest string
+
+	// the repo, path to the dar and rev of a git+https dependency, if it is one
+	Git *gitsource.Source
+
+	// the name of the package of the multi-package this is a workspace:<package> dependency on, if it is one
+	Workspace string
+
 	// the index of this dependency in the list in daml.yaml.
 	// useful when trying to update the dep as part of `dpm update`.
 	Index int
 }
 
+// String returns the fully-qualified URL, including the semver range (if any)
+func (d *ParsedDarDependency) String() string {
+	if d.FullUrl == nil {
+		return ""
+	}
+	u := d.FullUrl.String()
+	if d.Digest != "" {
+		return u + "@" + d.Digest
+	}
+	if d.Range == "" {
+		return u
+	}
+	name, digest, hasDigest := strings.Cut(u, "@")
+	u = name + ":" + d.Range
+	if hasDigest {
+		u += "@" + digest
+	}
+	return u
+}
+
 // StringWithAlias will reconstruct the original '@<alias>/<rest of uri>' for oci-based dars
 func (d *ParsedDarDependency) StringWithAlias() string {
 	if d.FullUrl == nil {
 		return ""
 	}
-	u := d.FullUrl.String()
+	u := d.String()
 
 	if d.Location == nil || !strings.HasPrefix(u, d.Location.Url) {
 		return u
@@ -79,6 +119,25 @@ func (d *ParsedDarDependency) GetOciRemote() (*assistantremote.Remote, *registry
 	return assistantRemote, &ref, nil
 }
 
+// GetResolvedOciRemote is GetOciRemote, but with the dependency's semver range (if any)
+// resolved to the highest matching version in the registry, unless it's already pinned to a digest
+func (d *ParsedDarDependency) GetResolvedOciRemote(ctx context.Context) (*assistantremote.Remote, *registry.Reference, error) {
+	client, ref, err := d.GetOciRemote()
+	if err != nil || d.Range == "" {
+		return client, ref, err
+	}
+	if _, err := ref.Digest(); err == nil {
+		return client, ref, nil
+	}
+
+	_, tag, err := ocilister.ResolveRange(ctx, client, ref.Repository, d.Range)
+	if err != nil {
+		return nil, nil, err
+	}
+	ref.Reference = tag
+	return client, ref, nil
+}
+
 var regex = regexp.MustCompile(`^(@[a-zA-Z0-9_-]+)/`)
 
 func (p *DamlPackage) parseLocations(rawDeps []*RawDependency, artifactLocations ArtifactLocations) (map[string]*ParsedDarDependency, error) {
@@ -93,22 +152,55 @@ func (p *DamlPackage) parseLocations(rawDeps []*RawDependency, artifactLocations
 			continue
 		}
 
-		if strings.HasPrefix(d, "oci://") {
+		if strings.HasPrefix(d, WorkspacePrefix) {
+			name := strings.TrimPrefix(d, WorkspacePrefix)
+			if name == "" {
+				errs = append(errs, fmt.Errorf("error parsing dependency %q: must be of the form '%s<package name>'", d, WorkspacePrefix))
+				continue
+			}
 			u, err := url.Parse(d)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("couldn't parse dependency %q: %w", d, err))
+				continue
+			}
+			parsedLocations[d] = &ParsedDarDependency{
+				FullUrl:       u,
+				Workspace:     name,
+				MainPackageId: rawDep.GetMainPackageId(),
+				Index:         i,
+			}
+		} else if gitsource.IsGit(d) {
+			parsed, err := parseGitDependency(d, nil)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			parsed.MainPackageId = rawDep.GetMainPackageId()
+			parsed.Index = i
+			parsedLocations[d] = parsed
+		} else if strings.HasPrefix(d, "oci://") {
+			rawUrl, semverRange, _ := ocilister.SplitRange(d)
+			u, err := url.Parse(rawUrl)
 			if err != nil {
 				errs = append(errs, fmt.Errorf("couldn't parse dependency url %q: %w", d, err))
 				continue
 			}
 			parsedLocations[d] = &ParsedDarDependency{
 				FullUrl:       u,
+				Range:         semverRange,
 				MainPackageId: rawDep.GetMainPackageId(),
 				Index:         i,
 			}
-		} else if strings.HasPrefix(d, "http://") || strings.HasPrefix(d, "https://") {
-			// TODO
-			errs = append(errs, fmt.Errorf("couldn't parse dependency %q: http dependencies not yet supported", d))
-			continue
-		} else if strings.HasSuffix(d, ".dar") {
+		} else if isHttp(d) {
+			parsed, err := parseHttpDependency(d, nil)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			parsed.MainPackageId = rawDep.GetMainPackageId()
+			parsed.Index = i
+			parsedLocations[d] = parsed
+		} else if strings.HasSuffix(d, ".dar") && !strings.HasPrefix(d, "@") {
 			absPath := utils.ResolvePath(filepath.Dir(p.AbsolutePath), d)
 			u, err := url.Parse("file://" + filepath.ToSlash(absPath))
 			if err != nil {
@@ -138,7 +230,21 @@ func (p *DamlPackage) parseLocations(rawDeps []*RawDependency, artifactLocations
 				continue
 			}
 
-			rawUrl := strings.Replace(d, parsed[1], location.Url, 1)
+			fullUrl := strings.Replace(d, parsed[1], location.Url, 1)
+			if isHttp(fullUrl) || gitsource.IsGit(fullUrl) {
+				parse := lo.Ternary(isHttp(fullUrl), parseHttpDependency, parseGitDependency)
+				parsed, err := parse(fullUrl, location)
+				if err != nil {
+					errs = append(errs, err)
+					continue
+				}
+				parsed.MainPackageId = rawDep.GetMainPackageId()
+				parsed.Index = i
+				parsedLocations[d] = parsed
+				continue
+			}
+
+			rawUrl, semverRange, _ := ocilister.SplitRange(fullUrl)
 			u, err := url.Parse(rawUrl)
 			if err != nil {
 				errs = append(errs, fmt.Errorf("couldn't parse full url %q for dependency %q: ", rawUrl, d))
@@ -147,6 +253,7 @@ func (p *DamlPackage) parseLocations(rawDeps []*RawDependency, artifactLocations
 			parsedLocations[d] = &ParsedDarDependency{
 				Location:      location,
 				FullUrl:       u,
+				Range:         semverRange,
 				MainPackageId: rawDep.GetMainPackageId(),
 				Index:         i,
 			}
@@ -177,3 +284,54 @@ func (p *DamlPackage) parseLocations(rawDeps []*RawDependency, artifactLocations
 
 	return parsedLocations, nil
 }
+
+func isHttp(d string) bool {
+	return strings.HasPrefix(d, "http://") || strings.HasPrefix(d, "https://")
+}
+
+// parseHttpDependency parses "http(s)://<url>[@sha256:<hex>]".
+// Plain http is only allowed for dars pinned to a sha256 inline, as nothing else vouches for what's downloaded
+func parseHttpDependency(d string, location *ArtifactLocation) (*ParsedDarDependency, error) {
+	rawUrl, hex, pinned := strings.Cut(d, "@sha256:")
+	var dgst string
+	if pinned {
+		parsed, err := digest.Parse("sha256:" + hex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sha256 digest in dependency %q: %w", d, err)
+		}
+		dgst = parsed.String()
+	}
+
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse dependency url %q: %w", d, err)
+	}
+	if u.Scheme == "http" && !pinned {
+		return nil, fmt.Errorf("dependency %q must use https, unless it's pinned inline (<url>@sha256:<digest>)", d)
+	}
+	return &ParsedDarDependency{
+		FullUrl:  u,
+		Location: location,
+		Digest:   dgst,
+	}, nil
+}
+
+// parseGitDependency parses "git+https://<host>/<repo>[//<path>]@<rev>"
+func parseGitDependency(d string, location *ArtifactLocation) (*ParsedDarDependency, error) {
+	src, err := gitsource.Parse(d)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(src.Subdir, ".dar") {
+		return nil, fmt.Errorf("dependency %q must point to a dar in the repo, i.e. git+https://<host>/<repo>//<path to dar>@<rev>", d)
+	}
+	u, err := url.Parse(d)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse dependency url %q: %w", d, err)
+	}
+	return &ParsedDarDependency{
+		FullUrl:  u,
+		Location: location,
+		Git:      src,
+	}, nil
+}
@@ -0,0 +1,153 @@
+// Copyright (c) 2017-2026 Digital Asset (Switzerland) GmbH and/or its affiliates. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package damlpackage
+
+import (
+	"cmp"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"daml.com/x/assistant/pkg/assistantconfig"
+	"daml.com/x/assistant/pkg/multipackage"
+	"github.com/samber/lo"
+)
+
+// WorkspacePrefix is for dependencies on the dar of another package of the multi-package, e.g. "workspace:my-interfaces"
+const WorkspacePrefix = "workspace:"
+
+// Workspace is the daml packages of a multi-package, by name
+type Workspace struct {
+	// in the order listed in multi-package.yaml
+	packages []*DamlPackage
+	// more than one package only if they share a name, in which case depending on it is ambiguous
+	byName map[string][]*DamlPackage
+}
+
+// CycleError is returned by BuildOrder when workspace packages depend on each other in a cycle
+type CycleError struct {
+	// the dirs of the packages in the cycle
+	Dirs []string
+	// the names of the packages in the cycle, starting and ending with the same one
+	Names []string
+}
+
+func (e *CycleError) Error() string {
+	return "workspace packages depend on each other in a cycle: " + strings.Join(e.Names, " -> ")
+}
+
+// ReadWorkspace reads the packages of the multi-package.
+// Packages that can't be read are kept without any dependencies (they fail to resolve on their own anyway)
+func ReadWorkspace(m *multipackage.MultiPackage) *Workspace {
+	w := &Workspace{byName: map[string][]*DamlPackage{}}
+	for _, dir := range m.AbsolutePackages() {
+		damlYaml := filepath.Join(dir, assistantconfig.DamlPackageFilename)
+		p, err := Read(damlYaml)
+		if err != nil {
+			slog.Debug("couldn't read workspace package", "dir", dir, "err", err.Error())
+			p = &DamlPackage{AbsolutePath: damlYaml}
+		}
+		w.packages = append(w.packages, p)
+		if p.Name != "" {
+			w.byName[p.Name] = append(w.byName[p.Name], p)
+		}
+	}
+	return w
+}
+
+// DistDarPath is where damlc builds the package's dar, i.e. .daml/dist/<name>-<version>.dar
+func (p *DamlPackage) DistDarPath() string {
+	return filepath.Join(filepath.Dir(p.AbsolutePath), ".daml", "dist", fmt.Sprintf("%s-%s.dar", p.Name, p.Version))
+}
+
+// Dar returns the (possibly yet to be built) dar of the workspace package with the given name
+func (w *Workspace) Dar(name string) (string, error) {
+	ps := w.byName[name]
+	switch len(ps) {
+	case 0:
+		return "", fmt.Errorf("no package of the multi-package is named %q (known packages: %s)", name, strings.Join(w.names(), ", "))
+	case 1:
+		return ps[0].DistDarPath(), nil
+	default:
+		dirs := lo.Map(ps, func(p *DamlPackage, _ int) string { return filepath.Dir(p.AbsolutePath) })
+		return "", fmt.Errorf("%s%s is ambiguous, as packages %s of the multi-package are all named %q", WorkspacePrefix, name, strings.Join(dirs, ", "), name)
+	}
+}
+
+// BuildOrder returns the dirs of the workspace packages, ordered so that each package comes after the ones it depends on
+// via workspace:<package> dependencies. Otherwise, packages keep the order they're listed in multi-package.yaml.
+// A dependency cycle is returned as a *CycleError
+func (w *Workspace) BuildOrder() ([]string, error) {
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := map[*DamlPackage]int{}
+	var order []string
+	var path []*DamlPackage
+
+	var visit func(p *DamlPackage) error
+	visit = func(p *DamlPackage) error {
+		switch state[p] {
+		case done:
+			return nil
+		case visiting:
+			start := slices.Index(path, p)
+			cycle := append(slices.Clone(path[start:]), p)
+			return &CycleError{
+				Dirs: lo.Uniq(lo.Map(cycle, func(p *DamlPackage, _ int) string {
+					return filepath.Dir(p.AbsolutePath)
+				})),
+				Names: lo.Map(cycle, func(p *DamlPackage, _ int) string {
+					return p.Name
+				}),
+			}
+		}
+
+		state[p] = visiting
+		path = append(path, p)
+		for _, name := range p.WorkspaceDependencies() {
+			// unknown (or ambiguous) packages are reported when the dependency is resolved
+			if deps := w.byName[name]; len(deps) == 1 {
+				if err := visit(deps[0]); err != nil {
+					return err
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[p] = done
+		order = append(order, filepath.Dir(p.AbsolutePath))
+		return nil
+	}
+
+	for _, p := range w.packages {
+		if err := visit(p); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// WorkspaceDependencies returns the names of the workspace packages the package depends on (as dependencies or data-dependencies),
+// in the order they're declared
+func (p *DamlPackage) WorkspaceDependencies() []string {
+	if p.ParsedDarDependencies == nil {
+		return nil
+	}
+	deps := slices.Concat(lo.Values(p.ParsedDarDependencies.Dependencies), lo.Values(p.ParsedDarDependencies.DataDependencies))
+	slices.SortFunc(deps, func(a, b *ParsedDarDependency) int {
+		return cmp.Or(a.Index-b.Index, strings.Compare(a.Workspace, b.Workspace))
+	})
+	return lo.Uniq(lo.FilterMap(deps, func(d *ParsedDarDependency, _ int) (string, bool) {
+		return d.Workspace, d.Workspace != ""
+	}))
+}
+
+func (w *Workspace) names() []string {
+	names := lo.Keys(w.byName)
+	slices.Sort(names)
+	return names
+}
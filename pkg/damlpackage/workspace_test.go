@@ -0,0 +1,118 @@
+// Copyright (c) 2017-2026 Digital Asset (Switzerland) GmbH and/or its affiliates. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package damlpackage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"daml.com/x/assistant/pkg/assistantconfig"
+	"daml.com/x/assistant/pkg/multipackage"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mkWorkspace writes a multi-package whose packages (listed in the given order) each depend on the named workspace packages
+func mkWorkspace(t *testing.T, names []string, deps map[string][]string) (string, *Workspace) {
+	root := t.TempDir()
+	for _, name := range names {
+		dir := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(dir, 0755))
+		fields := []string{"name: " + name, "version: 1.0.0"}
+		if len(deps[name]) > 0 {
+			fields = append(fields, "dependencies:")
+			for _, dep := range deps[name] {
+				fields = append(fields, "  - "+WorkspacePrefix+dep)
+			}
+		}
+		require.NoError(t, os.WriteFile(filepath.Join(dir, assistantconfig.DamlPackageFilename), makeDamlYaml(fields...), 0644))
+	}
+
+	multiPackageYaml := "packages:\n" + strings.Join(lo.Map(names, func(name string, _ int) string {
+		return "  - ./" + name
+	}), "\n")
+	multiPackagePath := filepath.Join(root, assistantconfig.DamlMultiPackageFilename)
+	require.NoError(t, os.WriteFile(multiPackagePath, []byte(multiPackageYaml), 0644))
+
+	m, err := multipackage.Read(multiPackagePath)
+	require.NoError(t, err)
+	return root, ReadWorkspace(m)
+}
+
+func TestWorkspaceBuildOrder(t *testing.T) {
+	root, w := mkWorkspace(t, []string{"app", "interfaces", "util", "tests"}, map[string][]string{
+		"app":        {"interfaces", "util"},
+		"interfaces": {"util"},
+		"tests":      {"app"},
+	})
+
+	order, err := w.BuildOrder()
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(root, "util"),
+		filepath.Join(root, "interfaces"),
+		filepath.Join(root, "app"),
+		filepath.Join(root, "tests"),
+	}, order)
+
+	dar, err := w.Dar("util")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(root, "util", ".daml", "dist", "util-1.0.0.dar"), dar)
+
+	_, err = w.Dar("nope")
+	assert.ErrorContains(t, err, "known packages: app, interfaces, tests, util")
+}
+
+func TestWorkspaceCycle(t *testing.T) {
+	root, w := mkWorkspace(t, []string{"a", "b", "c"}, map[string][]string{
+		"a": {"b"},
+		"b": {"c"},
+		"c": {"b"},
+	})
+
+	_, err := w.BuildOrder()
+	var cycle *CycleError
+	require.ErrorAs(t, err, &cycle)
+	assert.Equal(t, []string{"b", "c", "b"}, cycle.Names)
+	assert.Equal(t, []string{filepath.Join(root, "b"), filepath.Join(root, "c")}, cycle.Dirs)
+	assert.ErrorContains(t, err, "b -> c -> b")
+}
+
+func TestWorkspaceAmbiguousName(t *testing.T) {
+	root, _ := mkWorkspace(t, []string{"app", "x", "y", "tests"}, map[string][]string{
+		"app":   {"common"},
+		"tests": {"app"},
+	})
+	for _, dir := range []string{"x", "y"} {
+		require.NoError(t, os.WriteFile(filepath.Join(root, dir, assistantconfig.DamlPackageFilename), makeDamlYaml("name: common", "version: 1.0.0"), 0644))
+	}
+	m, err := multipackage.Read(filepath.Join(root, assistantconfig.DamlMultiPackageFilename))
+	require.NoError(t, err)
+	w := ReadWorkspace(m)
+
+	// only depending on the shared name fails
+	_, err = w.Dar("common")
+	assert.ErrorContains(t, err, "workspace:common is ambiguous")
+	dar, err := w.Dar("app")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(root, "app", ".daml", "dist", "app-1.0.0.dar"), dar)
+
+	order, err := w.BuildOrder()
+	require.NoError(t, err)
+	assert.Len(t, order, 4)
+}
+
+func TestWorkspaceDependencies(t *testing.T) {
+	p := &DamlPackage{}
+	parsed, err := p.parseLocations([]*RawDependency{{ValueOnly: lo.ToPtr("workspace:foo")}}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "foo", parsed["workspace:foo"].Workspace)
+	assert.Equal(t, "workspace:foo", parsed["workspace:foo"].FullUrl.String())
+
+	_, err = p.parseLocations([]*RawDependency{{ValueOnly: lo.ToPtr("workspace:")}}, nil)
+	assert.Error(t, err)
+}
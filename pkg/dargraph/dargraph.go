@@ -0,0 +1,153 @@
+// Copyright (c) 2017-2026 Digital Asset (Switzerland) GmbH and/or its affiliates. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package dargraph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"daml.com/x/assistant/cmd/dpm/cmd/resolve/resolutionerrors"
+	"daml.com/x/assistant/pkg/assistantconfig"
+	"daml.com/x/assistant/pkg/darmanifest"
+	"oras.land/oras-go/v2/registry"
+)
+
+// Dependency is a dar required by a package, either directly or transitively (via the dar.yaml of another dar)
+type Dependency struct {
+	// e.g. oci://example.com/dars/foo:1.2.3@sha256:...
+	URI string
+	Ref registry.Reference
+	// the tag in URI, if any. Ref only has the digest of URIs with both
+	Tag string
+	// the digest a dependency given by tag only is known to resolve to (e.g. from the lockfile), if any
+	Digest string
+	// the URIs of the dars through which this one is required, outermost first. Empty for direct dependencies
+	RequiredBy []string
+	// the directory the dar is installed in, once resolved
+	Dir string
+}
+
+func NewDependency(uri string, requiredBy []string) (*Dependency, error) {
+	ref, err := registry.ParseReference(strings.TrimPrefix(uri, "oci://"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid dar dependency %q: %w", uri, err)
+	}
+	dep := &Dependency{URI: uri, Ref: ref, RequiredBy: requiredBy}
+	if _, err := ref.Digest(); err != nil {
+		dep.Tag = ref.Reference
+	} else {
+		name, _, _ := strings.Cut(strings.TrimPrefix(uri, "oci://"), "@")
+		if tagged, err := registry.ParseReference(name); err == nil {
+			dep.Tag = tagged.Reference
+		}
+	}
+	return dep, nil
+}
+
+// digest is the one in Ref, or else Digest
+func (d *Dependency) digest() string {
+	if dgst, err := d.Ref.Digest(); err == nil {
+		return dgst.String()
+	}
+	return d.Digest
+}
+
+func (d *Dependency) describe() string {
+	if len(d.RequiredBy) == 0 {
+		return d.URI + " (a direct dependency)"
+	}
+	return d.URI + " (required via " + strings.Join(d.RequiredBy, " -> ") + ")"
+}
+
+// Resolver returns the directory a dependency is installed in, installing it first if need be
+type Resolver func(ctx context.Context, dep *Dependency) (string, error)
+
+// Graph tracks the dars required by a package, so that transitive dependencies don't pull in
+// versions of a repository other than the ones already required
+type Graph struct {
+	// "<registry>/<repository>" -> the dependencies requiring it, in order
+	required map[string][]*Dependency
+}
+
+func New() *Graph {
+	return &Graph{required: map[string][]*Dependency{}}
+}
+
+// Require records dep, returning false if its repository is already required at the same version.
+// Direct dependencies never conflict (e.g. data-dependencies on several versions of a package), so they should
+// all be required before walking any transitive ones. A transitive dependency on a repository that's
+// already required at other versions is a DarVersionConflict resolution error
+func (g *Graph) Require(dep *Dependency) (bool, error) {
+	key := dep.Ref.Registry + "/" + dep.Ref.Repository
+	existing := g.required[key]
+	for _, e := range existing {
+		if !conflicts(e, dep) {
+			return false, nil
+		}
+	}
+	if len(existing) > 0 && len(dep.RequiredBy) > 0 {
+		return false, resolutionerrors.NewDarVersionConflictError(
+			fmt.Errorf("%s conflicts with %s", dep.describe(), existing[0].describe()),
+		)
+	}
+	g.required[key] = append(existing, dep)
+	return true, nil
+}
+
+// conflicts compares digests if both dependencies have (or are known to resolve to) one, or else tags.
+// A tag whose digest isn't known can't be compared against a digest alone, so those never conflict
+func conflicts(a, b *Dependency) bool {
+	if da, db := a.digest(), b.digest(); da != "" && db != "" {
+		return da != db
+	}
+	if a.Tag != "" && b.Tag != "" {
+		return a.Tag != b.Tag
+	}
+	return false
+}
+
+// Walk requires the dependencies recorded in the dar.yaml in dir and, once resolved, theirs in turn (depth-first).
+// It returns the newly required dependencies, with their Dir set
+func (g *Graph) Walk(ctx context.Context, dir string, requiredBy []string, resolve Resolver) ([]*Dependency, error) {
+	m, err := darmanifest.ReadDarManifest(filepath.Join(dir, assistantconfig.DarManifestName))
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*Dependency
+	var errs []error
+	for _, uri := range m.Spec.Dependencies {
+		dep, err := NewDependency(uri, requiredBy)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		isNew, err := g.Require(dep)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !isNew {
+			continue
+		}
+
+		dep.Dir, err = resolve(ctx, dep)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		result = append(result, dep)
+
+		transitive, err := g.Walk(ctx, dep.Dir, append(slices.Clone(requiredBy), uri), resolve)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		result = append(result, transitive...)
+	}
+	return result, errors.Join(errs...)
+}
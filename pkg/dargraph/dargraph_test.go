@@ -0,0 +1,173 @@
+// Copyright (c) 2017-2026 Digital Asset (Switzerland) GmbH and/or its affiliates. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package dargraph
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"daml.com/x/assistant/cmd/dpm/cmd/resolve/resolutionerrors"
+	"daml.com/x/assistant/pkg/assistantconfig"
+	"daml.com/x/assistant/pkg/darmanifest"
+	"daml.com/x/assistant/pkg/schema"
+	"daml.com/x/assistant/pkg/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	digestA = "sha256:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	digestB = "sha256:bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+	digestC = "sha256:cccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccc"
+)
+
+// fakeCache writes a dar.yaml per dar (keyed by URI) with the given dependencies, and resolves URIs to those dirs
+type fakeCache struct {
+	t    *testing.T
+	dirs map[string]string
+}
+
+func newFakeCache(t *testing.T, deps map[string][]string) *fakeCache {
+	c := &fakeCache{t: t, dirs: map[string]string{}}
+	for uri, ds := range deps {
+		dir := t.TempDir()
+		m := darmanifest.DarManifest{
+			ManifestMeta: schema.ManifestMeta{APIVersion: darmanifest.DarAPIVersion, Kind: darmanifest.DarKind},
+			Spec: &darmanifest.Spec{
+				Dars:         []darmanifest.Dar{{Path: "x.dar", MainPackageId: "abc"}},
+				Dependencies: ds,
+			},
+		}
+		require.NoError(t, os.WriteFile(filepath.Join(dir, assistantconfig.DarManifestName), testutil.MustMarshal(t, m), 0644))
+		c.dirs[uri] = dir
+	}
+	return c
+}
+
+func (c *fakeCache) resolve(_ context.Context, dep *Dependency) (string, error) {
+	dir, ok := c.dirs[dep.URI]
+	require.True(c.t, ok, "unexpected dependency %s", dep.URI)
+	return dir, nil
+}
+
+func TestWalk(t *testing.T) {
+	root := "oci://example.com/dars/root:1.0.0@" + digestA
+	foo := "oci://example.com/dars/foo:1.0.0@" + digestB
+	bar := "oci://example.com/dars/bar:2.0.0@" + digestC
+	cache := newFakeCache(t, map[string][]string{
+		root: {foo, bar},
+		foo:  {bar},
+		bar:  nil,
+	})
+
+	g := New()
+	direct, err := NewDependency(root, nil)
+	require.NoError(t, err)
+	_, err = g.Require(direct)
+	require.NoError(t, err)
+
+	deps, err := g.Walk(testutil.Context(t), cache.dirs[root], []string{root}, cache.resolve)
+	require.NoError(t, err)
+	require.Len(t, deps, 2, "bar is required twice, but only walked once")
+
+	assert.Equal(t, foo, deps[0].URI)
+	assert.Equal(t, []string{root}, deps[0].RequiredBy)
+	assert.Equal(t, cache.dirs[foo], deps[0].Dir)
+
+	assert.Equal(t, bar, deps[1].URI)
+	assert.Equal(t, []string{root, foo}, deps[1].RequiredBy)
+}
+
+func TestWalkConflict(t *testing.T) {
+	root := "oci://example.com/dars/root:1.0.0@" + digestA
+	foo := "oci://example.com/dars/foo:1.0.0@" + digestB
+	otherFoo := "oci://example.com/dars/foo:2.0.0@" + digestC
+	cache := newFakeCache(t, map[string][]string{
+		root: {otherFoo},
+	})
+
+	g := New()
+	for _, uri := range []string{root, foo} {
+		d, err := NewDependency(uri, nil)
+		require.NoError(t, err)
+		_, err = g.Require(d)
+		require.NoError(t, err)
+	}
+
+	_, err := g.Walk(testutil.Context(t), cache.dirs[root], []string{root}, cache.resolve)
+	var resErr *resolutionerrors.ResolutionError
+	require.ErrorAs(t, err, &resErr)
+	assert.Equal(t, resolutionerrors.DarVersionConflict, resErr.Code)
+	assert.True(t, strings.Contains(err.Error(), otherFoo) && strings.Contains(err.Error(), foo), err.Error())
+}
+
+func TestRequireTagAgainstDigest(t *testing.T) {
+	for name, tc := range map[string]struct {
+		direct, lockedDigest, transitive string
+		conflict                         bool
+	}{
+		"locked tag against another digest": {
+			direct: "oci://example.com/dars/foo:1.0.0", lockedDigest: digestA,
+			transitive: "oci://example.com/dars/foo@" + digestB,
+			conflict:   true,
+		},
+		"locked tag against its digest": {
+			direct: "oci://example.com/dars/foo:1.0.0", lockedDigest: digestA,
+			transitive: "oci://example.com/dars/foo@" + digestA,
+		},
+		"tag against another tag with a digest": {
+			direct:     "oci://example.com/dars/foo:1.0.0",
+			transitive: "oci://example.com/dars/foo:2.0.0@" + digestB,
+			conflict:   true,
+		},
+		"tag against the same tag with a digest": {
+			direct:     "oci://example.com/dars/foo:1.0.0",
+			transitive: "oci://example.com/dars/foo:1.0.0@" + digestB,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			g := New()
+			direct, err := NewDependency(tc.direct, nil)
+			require.NoError(t, err)
+			direct.Digest = tc.lockedDigest
+			_, err = g.Require(direct)
+			require.NoError(t, err)
+
+			transitive, err := NewDependency(tc.transitive, []string{"oci://example.com/dars/root:1.0.0"})
+			require.NoError(t, err)
+			isNew, err := g.Require(transitive)
+			if !tc.conflict {
+				require.NoError(t, err)
+				assert.False(t, isNew)
+				return
+			}
+			var resErr *resolutionerrors.ResolutionError
+			require.ErrorAs(t, err, &resErr)
+			assert.Equal(t, resolutionerrors.DarVersionConflict, resErr.Code)
+		})
+	}
+}
+
+func TestRequireDirect(t *testing.T) {
+	g := New()
+	for _, uri := range []string{
+		"oci://example.com/dars/foo:1.0.0@" + digestA,
+		"oci://example.com/dars/foo:2.0.0@" + digestB,
+	} {
+		d, err := NewDependency(uri, nil)
+		require.NoError(t, err)
+		isNew, err := g.Require(d)
+		require.NoError(t, err, "direct dependencies on several versions are allowed")
+		assert.True(t, isNew)
+	}
+
+	d, err := NewDependency("oci://example.com/dars/foo:2.0.0@"+digestB, []string{"oci://example.com/dars/root:1.0.0"})
+	require.NoError(t, err)
+	isNew, err := g.Require(d)
+	require.NoError(t, err)
+	assert.False(t, isNew)
+}
@@ -0,0 +1,137 @@
+// Copyright (c) 2017-2026 Digital Asset (Switzerland) GmbH and/or its affiliates. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package dargraph
+
+import (
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"daml.com/x/assistant/cmd/dpm/cmd/resolve/resolutionerrors"
+	"daml.com/x/assistant/pkg/assistantconfig"
+	"daml.com/x/assistant/pkg/darmanifest"
+	"daml.com/x/assistant/pkg/ocipusher/darpusher"
+	"daml.com/x/assistant/pkg/utils"
+	"github.com/samber/lo"
+)
+
+// InstalledDar is a dar file of a resolved dependency, identified by its main package
+type InstalledDar struct {
+	// the URI of the dependency the dar was installed from
+	URI  string
+	Path string
+	// the main package's name and version (e.g. foo-1.0.0), if it could be read from the dar
+	Name          string
+	MainPackageId string
+}
+
+// Conflict is a PackageIdConflict between some of the resolved dars
+type Conflict struct {
+	Dars []*InstalledDar
+	Err  *resolutionerrors.ResolutionError
+}
+
+// InstalledDars lists the dar files of the dependency uri installed in dir.
+// The main package IDs recorded in its dar.yaml take precedence over reading them from the dars themselves
+func InstalledDars(dir, uri string) ([]*InstalledDar, error) {
+	m, err := darmanifest.ReadDarManifest(filepath.Join(dir, assistantconfig.DarManifestName))
+	if err != nil {
+		return nil, err
+	}
+
+	var dars []*InstalledDar
+	for _, d := range m.Spec.Dars {
+		dars = append(dars, InstalledDarFile(utils.ResolvePath(dir, d.Path), uri, d.MainPackageId))
+	}
+	return dars, nil
+}
+
+// InstalledDarFile reads the main package of the dar file at path (installed from the dependency uri),
+// unless its main package ID is already known
+func InstalledDarFile(path, uri, mainPackageId string) *InstalledDar {
+	dar := &InstalledDar{URI: uri, Path: path, MainPackageId: mainPackageId}
+	if name, err := darpusher.GetMainPackageName(dar.Path); err != nil {
+		slog.Debug("couldn't read the main package name of dar", "path", dar.Path, "err", err.Error())
+	} else {
+		dar.Name = name
+	}
+	if dar.MainPackageId == "" {
+		if id, err := darpusher.GetMainPackageId(dar.Path); err != nil {
+			slog.Debug("couldn't read the main package id of dar", "path", dar.Path, "err", err.Error())
+		} else {
+			dar.MainPackageId = id
+		}
+	}
+	return dar
+}
+
+// PackageIdConflicts finds the names (and versions) that the dars embed with different main package IDs,
+// and the main package IDs installed from different dependencies (i.e. not the same artifact).
+// Either would otherwise only surface as a confusing compiler error
+func PackageIdConflicts(dars []*InstalledDar) []*Conflict {
+	dars = lo.UniqBy(dars, func(d *InstalledDar) string {
+		return d.Path
+	})
+
+	var conflicts []*Conflict
+
+	byName := lo.GroupBy(lo.Filter(dars, func(d *InstalledDar, _ int) bool {
+		return d.Name != "" && d.MainPackageId != ""
+	}), func(d *InstalledDar) string {
+		return d.Name
+	})
+	for _, name := range sortedKeys(byName) {
+		group := byName[name]
+		ids := lo.Uniq(lo.Map(group, func(d *InstalledDar, _ int) string {
+			return d.MainPackageId
+		}))
+		if len(ids) < 2 {
+			continue
+		}
+		conflicts = append(conflicts, &Conflict{
+			Dars: group,
+			Err: resolutionerrors.NewPackageIdConflictError(
+				fmt.Errorf("%s has different main package IDs in %s", name, describeAll(group)),
+			),
+		})
+	}
+
+	byId := lo.GroupBy(lo.Filter(dars, func(d *InstalledDar, _ int) bool {
+		return d.MainPackageId != ""
+	}), func(d *InstalledDar) string {
+		return d.MainPackageId
+	})
+	for _, id := range sortedKeys(byId) {
+		group := byId[id]
+		// a dependency declared with and without its digest (say) is still the same artifact
+		artifacts := lo.Uniq(lo.Map(group, func(d *InstalledDar, _ int) string {
+			return filepath.Dir(d.Path)
+		}))
+		if len(artifacts) < 2 {
+			continue
+		}
+		conflicts = append(conflicts, &Conflict{
+			Dars: group,
+			Err: resolutionerrors.NewPackageIdConflictError(
+				fmt.Errorf("package %s comes from more than one dar: %s", id, describeAll(group)),
+			),
+		})
+	}
+
+	return conflicts
+}
+
+func describeAll(dars []*InstalledDar) string {
+	return strings.Join(lo.Map(dars, func(d *InstalledDar, _ int) string {
+		return fmt.Sprintf("%s (%s)", d.URI, d.MainPackageId)
+	}), ", ")
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := lo.Keys(m)
+	slices.Sort(keys)
+	return keys
+}
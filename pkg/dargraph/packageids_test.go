@@ -0,0 +1,81 @@
+// Copyright (c) 2017-2026 Digital Asset (Switzerland) GmbH and/or its affiliates. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package dargraph
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"daml.com/x/assistant/cmd/dpm/cmd/resolve/resolutionerrors"
+	"daml.com/x/assistant/pkg/assistantconfig"
+	"daml.com/x/assistant/pkg/darmanifest"
+	"daml.com/x/assistant/pkg/schema"
+	"daml.com/x/assistant/pkg/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstalledDars(t *testing.T) {
+	dir := t.TempDir()
+	b, err := os.ReadFile(testutil.TestdataPath(t, "test-dar", "test.dar"))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "test.dar"), b, 0644))
+	m := darmanifest.DarManifest{
+		ManifestMeta: schema.ManifestMeta{APIVersion: darmanifest.DarAPIVersion, Kind: darmanifest.DarKind},
+		Spec:         &darmanifest.Spec{Dars: []darmanifest.Dar{{Path: "test.dar"}}},
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(dir, assistantconfig.DarManifestName), testutil.MustMarshal(t, m), 0644))
+
+	dars, err := InstalledDars(dir, "oci://example.com/dars/meep:0.0.1")
+	require.NoError(t, err)
+	require.Len(t, dars, 1)
+	assert.Equal(t, "meep-main-0.0.1", dars[0].Name)
+	assert.Equal(t, "0984ff5e3082add400bfcc6e3244bf9822ca5a617cfd92429e3fbce58058dbfa", dars[0].MainPackageId)
+	assert.Equal(t, filepath.Join(dir, "test.dar"), dars[0].Path)
+}
+
+func TestPackageIdConflicts(t *testing.T) {
+	dar := func(uri, dir, name, id string) *InstalledDar {
+		return &InstalledDar{URI: uri, Path: filepath.Join(dir, name+".dar"), Name: name, MainPackageId: id}
+	}
+
+	t.Run("no conflicts", func(t *testing.T) {
+		conflicts := PackageIdConflicts([]*InstalledDar{
+			dar("oci://example.com/dars/foo:1.0.0", "/a", "foo-1.0.0", "111"),
+			dar("oci://example.com/dars/foo:2.0.0", "/b", "foo-2.0.0", "222"),
+			// the same artifact, declared by two packages
+			dar("oci://example.com/dars/foo:1.0.0@"+digestA, "/a", "foo-1.0.0", "111"),
+		})
+		assert.Empty(t, conflicts)
+	})
+
+	t.Run("same name and version, different package ids", func(t *testing.T) {
+		a := dar("oci://example.com/dars/foo:1.0.0", "/a", "foo-1.0.0", "111")
+		b := dar("oci://other.com/dars/foo:1.0.0", "/b", "foo-1.0.0", "222")
+		conflicts := PackageIdConflicts([]*InstalledDar{a, b})
+		require.Len(t, conflicts, 1)
+		assert.Equal(t, resolutionerrors.PackageIdConflict, conflicts[0].Err.Code)
+		assert.ElementsMatch(t, []*InstalledDar{a, b}, conflicts[0].Dars)
+		assert.ErrorContains(t, conflicts[0].Err, "foo-1.0.0")
+	})
+
+	t.Run("same package id from different uris", func(t *testing.T) {
+		a := dar("oci://example.com/dars/foo:1.0.0", "/a", "foo-1.0.0", "111")
+		b := dar("oci://example.com/dars/bar:1.0.0", "/b", "bar-1.0.0", "111")
+		conflicts := PackageIdConflicts([]*InstalledDar{a, b})
+		require.Len(t, conflicts, 1)
+		assert.Equal(t, resolutionerrors.PackageIdConflict, conflicts[0].Err.Code)
+		assert.ErrorContains(t, conflicts[0].Err, "oci://example.com/dars/foo:1.0.0")
+		assert.ErrorContains(t, conflicts[0].Err, "oci://example.com/dars/bar:1.0.0")
+	})
+
+	t.Run("unreadable dars are skipped", func(t *testing.T) {
+		conflicts := PackageIdConflicts([]*InstalledDar{
+			dar("oci://example.com/dars/foo:1.0.0", "/a", "foo-1.0.0", ""),
+			dar("oci://example.com/dars/foo:1.0.1", "/b", "foo-1.0.0", "222"),
+		})
+		assert.Empty(t, conflicts)
+	})
+}
@@ -24,6 +24,9 @@ type DarManifest struct {
 
 type Spec struct {
 	Dars []Dar `yaml:"dars"`
+	// the dars this one was built against, as digest-pinned OCI URIs
+	// e.g. oci://example.com/dars/foo:1.2.3@sha256:...
+	Dependencies []string `yaml:"dependencies,omitempty"`
 }
 
 type Dar struct {
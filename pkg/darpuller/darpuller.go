@@ -14,6 +14,7 @@ import (
 	"daml.com/x/assistant/pkg/darmanifest"
 	ociconsts "daml.com/x/assistant/pkg/oci"
 	"daml.com/x/assistant/pkg/ocicache"
+	"daml.com/x/assistant/pkg/progress"
 	"daml.com/x/assistant/pkg/utils"
 	"github.com/Masterminds/semver/v3"
 	v1 "github.com/opencontainers/image-spec/specs-go/v1"
@@ -52,7 +53,7 @@ func (a *OciDarPuller) PullDar(ctx context.Context, dar *damlpackage.ParsedDarDe
 }
 
 func (a *OciDarPuller) doPullDar(ctx context.Context, dar *damlpackage.ParsedDarDependency) (*PulledDar, error) {
-	assistantRemote, ref, err := dar.GetOciRemote()
+	assistantRemote, ref, err := dar.GetResolvedOciRemote(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -98,7 +99,7 @@ func (a *OciDarPuller) doPullDar(ctx context.Context, dar *damlpackage.ParsedDar
 	// errors out if dest already exists
 	dest.DisableOverwrite = true
 
-	_, err = oras.Copy(ctx, src, ref.Reference, dest, ref.Reference, oras.CopyOptions{})
+	_, err = oras.Copy(ctx, src, ref.Reference, dest, ref.Reference, progress.Default().CopyOptions(progress.OperationPull, oras.CopyOptions{}))
 	if err != nil {
 		return nil, err
 	}
@@ -57,7 +57,7 @@ func fake(t *testing.T) *OciDarPuller {
 	if err != nil {
 		require.NoError(t, err)
 	}
-	if err := config.EnsureDirs(); err != nil {
+	if err := config.EnsureDirs(testutil.Context(t)); err != nil {
 		require.NoError(t, err)
 	}
 
@@ -0,0 +1,412 @@
+// Copyright (c) 2017-2026 Digital Asset (Switzerland) GmbH and/or its affiliates. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package deptree
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"daml.com/x/assistant/cmd/dpm/cmd/resolve/resolutionerrors"
+	"daml.com/x/assistant/pkg/assembler"
+	"daml.com/x/assistant/pkg/assembler/assemblyplan"
+	"daml.com/x/assistant/pkg/assistantconfig"
+	"daml.com/x/assistant/pkg/damlpackage"
+	"daml.com/x/assistant/pkg/dargraph"
+	"daml.com/x/assistant/pkg/darmanifest"
+	"daml.com/x/assistant/pkg/gitsource"
+	"daml.com/x/assistant/pkg/httpdar"
+	"daml.com/x/assistant/pkg/packagelock"
+	"daml.com/x/assistant/pkg/resolution"
+	"daml.com/x/assistant/pkg/resolver"
+	"daml.com/x/assistant/pkg/sdkmanifest"
+	"daml.com/x/assistant/pkg/utils"
+	"github.com/opencontainers/go-digest"
+	"github.com/samber/lo"
+	"oras.land/oras-go/v2/registry"
+)
+
+type Kind string
+
+const (
+	KindPackage      Kind = "package"
+	KindSdk          Kind = "sdk"
+	KindMultiPackage Kind = "multi-package"
+	KindDamlPackage  Kind = "daml-package"
+	KindComponent    Kind = "component"
+	KindDar          Kind = "dar"
+)
+
+// Node is a package, one of the layers its components are assembled from (the sdk, then the overrides
+// from multi-package.yaml and daml.yaml), or a component or dar it depends on
+type Node struct {
+	Kind Kind   `json:"kind"`
+	Name string `json:"name"`
+	// the version (or tag, range, uri...) as declared
+	Declared string `json:"declared,omitempty"`
+	// the version in effect, once resolved
+	Resolved string `json:"resolved,omitempty"`
+	// the manifest declaring the layer, or where the component or dar is installed
+	Path string `json:"path,omitempty"`
+	// the manifest of the layer overriding this component, if any
+	OverriddenBy string   `json:"overridden-by,omitempty"`
+	Errors       []string `json:"errors,omitempty"`
+	Children     []*Node  `json:"children,omitempty"`
+}
+
+// IsDependency whether the node is a component or dar (rather than a package or layer)
+func (n *Node) IsDependency() bool {
+	return n.Kind == KindComponent || n.Kind == KindDar
+}
+
+// Matches whether the node is the component or dar called name.
+// Dars match on their URI (with or without the oci:// scheme), or the last element of their repository
+func (n *Node) Matches(name string) bool {
+	if !n.IsDependency() {
+		return false
+	}
+	if n.Kind == KindComponent {
+		return n.Name == name
+	}
+	return n.Name == name || strings.TrimPrefix(n.Name, "oci://") == name || path.Base(n.Name) == name
+}
+
+// Label is a one-line, human-readable description of the node
+func (n *Node) Label() string {
+	var b strings.Builder
+	switch n.Kind {
+	case KindPackage, KindMultiPackage, KindDamlPackage:
+		fmt.Fprintf(&b, "%s (%s)", n.Name, n.Path)
+	case KindSdk:
+		b.WriteString(n.Name)
+		if n.Declared != "" {
+			b.WriteString(" " + n.Declared)
+		}
+		if n.Path != "" {
+			fmt.Fprintf(&b, " (%s)", n.Path)
+		}
+	default:
+		fmt.Fprintf(&b, "%s %s", n.Kind, n.Name)
+		if n.Declared != "" {
+			b.WriteString(" " + n.Declared)
+		}
+		if n.Resolved != "" && n.Resolved != n.Declared {
+			b.WriteString(" -> " + n.Resolved)
+		}
+		switch {
+		case n.OverriddenBy != "":
+			fmt.Fprintf(&b, " (overridden by %s)", n.OverriddenBy)
+		case n.Path != "":
+			fmt.Fprintf(&b, " (%s)", n.Path)
+		default:
+			b.WriteString(" (not installed)")
+		}
+	}
+	for _, e := range n.Errors {
+		b.WriteString("\n  error: " + e)
+	}
+	return b.String()
+}
+
+// Path is a chain of nodes, from a package down to one of its (possibly transitive) dependencies
+type Path []*Node
+
+// Why returns every path from the roots to the components or dars called name
+func Why(roots []*Node, name string) []Path {
+	var paths []Path
+	var walk func(n *Node, parents Path)
+	walk = func(n *Node, parents Path) {
+		p := append(slices.Clone(parents), n)
+		if n.Matches(name) {
+			paths = append(paths, p)
+		}
+		for _, c := range n.Children {
+			walk(c, p)
+		}
+	}
+	for _, r := range roots {
+		walk(r, nil)
+	}
+	return paths
+}
+
+type Builder struct {
+	config    *assistantconfig.Config
+	assembler *assembler.Assembler
+}
+
+func New(config *assistantconfig.Config, a *assembler.Assembler) *Builder {
+	return &Builder{config: config, assembler: a}
+}
+
+// Build returns the dependency tree of each package in scope (all the packages of the multi-package, or else the single package),
+// sorted by path
+func (b *Builder) Build(ctx context.Context) ([]*Node, error) {
+	res, err := resolver.New(b.config, b.assembler).RunDeepResolution(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Packages) == 0 {
+		return nil, fmt.Errorf("not in a (single-package or multi-package) project directory")
+	}
+
+	dirs := lo.Keys(res.Packages)
+	slices.Sort(dirs)
+	return lo.Map(dirs, func(dir string, _ int) *Node {
+		return b.packageTree(ctx, dir, res.Packages[dir])
+	}), nil
+}
+
+func (b *Builder) packageTree(ctx context.Context, dir string, pkg *resolution.Package) *Node {
+	damlPackagePath := filepath.Join(dir, assistantconfig.DamlPackageFilename)
+	root := &Node{
+		Kind: KindPackage,
+		Name: filepath.Base(dir),
+		Path: damlPackagePath,
+		Errors: lo.Map(pkg.Errors, func(e *resolutionerrors.ResolutionError, _ int) string {
+			return e.Error()
+		}),
+	}
+
+	plan, err := assemblyplan.NewShallow(ctx, b.config, b.assembler, damlPackagePath)
+	if err != nil {
+		// the resolution will usually have run into the same error already
+		if len(root.Errors) == 0 {
+			root.Errors = append(root.Errors, err.Error())
+		}
+		return root
+	}
+	root.Children = componentLayers(plan, pkg)
+
+	p, err := damlpackage.Read(damlPackagePath)
+	if err != nil {
+		root.Errors = append(root.Errors, err.Error())
+		return root
+	}
+	lock := packagelock.ReadPackageLockIfExists(filepath.Join(dir, assistantconfig.DpmLockFileName))
+	deps := slices.Concat(lo.Values(p.ParsedDarDependencies.Dependencies), lo.Values(p.ParsedDarDependencies.DataDependencies))
+	slices.SortFunc(deps, func(a, b *damlpackage.ParsedDarDependency) int { return a.Index - b.Index })
+	for _, dep := range deps {
+		root.Children = append(root.Children, b.darNode(dep, lock))
+	}
+	return root
+}
+
+// componentLayers returns a node per layer of the plan, with the components it declares.
+// Components declared by a higher layer (daml.yaml, then multi-package.yaml, then the sdk) override the lower ones
+func componentLayers(plan *assemblyplan.AssemblyPlan, pkg *resolution.Package) []*Node {
+	type layer struct {
+		node     *Node
+		manifest *sdkmanifest.SdkManifest
+	}
+
+	sdk := &Node{Kind: KindSdk, Name: "sdk", Path: plan.Base.AbsolutePath}
+	if plan.SdkVersion != nil {
+		sdk.Declared = plan.SdkVersion.String()
+		sdk.Resolved = pkg.SdkVersion
+	}
+	layers := []layer{{sdk, &plan.Base}}
+	if plan.MultiPackage != nil {
+		layers = append(layers, layer{&Node{Kind: KindMultiPackage, Name: filepath.Base(plan.MultiPackage.AbsolutePath), Path: plan.MultiPackage.AbsolutePath}, plan.MultiPackage})
+	}
+	if plan.DamlPackage != nil {
+		layers = append(layers, layer{&Node{Kind: KindDamlPackage, Name: filepath.Base(plan.DamlPackage.AbsolutePath), Path: plan.DamlPackage.AbsolutePath}, plan.DamlPackage})
+	}
+
+	// component name -> index of the layer it's taken from
+	winners := map[string]int{}
+	for i, l := range layers {
+		for name := range components(l.manifest) {
+			winners[name] = i
+		}
+	}
+
+	var nodes []*Node
+	for i, l := range layers {
+		comps := components(l.manifest)
+		names := lo.Keys(comps)
+		slices.Sort(names)
+		for _, name := range names {
+			comp := comps[name]
+			n := &Node{Kind: KindComponent, Name: name, Declared: declared(comp)}
+			if w := winners[name]; w != i {
+				n.OverriddenBy = layers[w].node.Path
+			} else {
+				n.Path = pkg.Components[name]
+				n.Resolved = pkg.ComponentsV2[name]["version"]
+			}
+			l.node.Children = append(l.node.Children, n)
+		}
+		if l.node.Kind == KindSdk && l.node.Declared == "" && len(l.node.Children) == 0 {
+			// no sdk at all (opt-in components only)
+			continue
+		}
+		nodes = append(nodes, l.node)
+	}
+	return nodes
+}
+
+func components(m *sdkmanifest.SdkManifest) map[string]*sdkmanifest.Component {
+	if m == nil || m.Spec == nil {
+		return nil
+	}
+	return m.Spec.Components
+}
+
+// declared returns the component's version, range, image-tag, local-path, uri or git source
+func declared(comp *sdkmanifest.Component) string {
+	s := strings.TrimPrefix(comp.String(), comp.Name)
+	s = strings.TrimPrefix(strings.TrimPrefix(s, ":"), "@")
+	if comp.Digest != "" {
+		s += "@" + comp.Digest
+	}
+	return s
+}
+
+func (b *Builder) darNode(dep *damlpackage.ParsedDarDependency, lock *packagelock.PackageLock) *Node {
+	if httpdar.IsHttp(dep) {
+		n := &Node{Kind: KindDar, Name: dep.FullUrl.String(), Declared: dep.Digest}
+		dgst := dep.Digest
+		if locked, ok := findLockedDar(lock, dep); ok {
+			n.Resolved = locked.Digest
+			dgst = locked.Digest
+		}
+		if dgst != "" {
+			n.Path = installedDir(httpdar.Dir(b.config, digest.Digest(dgst)))
+		}
+		return n
+	}
+	if dep.Git != nil {
+		n := &Node{Kind: KindDar, Name: strings.TrimSuffix(dep.Git.String(), "@"+dep.Git.Rev), Declared: dep.Git.Rev}
+		if dep.Git.IsCommit() {
+			n.Resolved = dep.Git.Rev
+		} else if locked, ok := findLockedDar(lock, dep); ok {
+			n.Resolved = locked.Commit
+		}
+		if n.Resolved != "" {
+			if p, ok, err := gitsource.CheckedOut(b.config, dep.Git, n.Resolved); err == nil && ok {
+				n.Path = p
+			}
+		}
+		return n
+	}
+	if dep.Workspace != "" {
+		// built by the multi-package, so it has no path until then
+		return &Node{Kind: KindDar, Name: dep.FullUrl.String()}
+	}
+	if dep.FullUrl.Scheme != "oci" {
+		return &Node{
+			Kind: KindDar,
+			Name: dep.FullUrl.String(),
+			Path: strings.TrimPrefix(dep.FullUrl.String(), dep.FullUrl.Scheme+"://"),
+		}
+	}
+
+	_, ref, err := dep.GetOciRemote()
+	if err != nil {
+		return &Node{Kind: KindDar, Name: dep.String(), Errors: []string{err.Error()}}
+	}
+	n := &Node{Kind: KindDar, Name: repoURI(ref)}
+	n.Declared, n.Resolved = splitDeclared(dep.String(), n.Name)
+	if dep.Range != "" {
+		n.Resolved = ""
+	}
+
+	if locked := lockedDar(lock, dep, ref); locked != nil {
+		if lockedRef, err := registry.ParseReference(strings.TrimPrefix(locked.URI.String(), "oci://")); err == nil {
+			if _, err := lockedRef.Digest(); err != nil {
+				n.Resolved = lockedRef.Reference
+			}
+		}
+		n.Path = filepath.Dir(locked.Path)
+	} else {
+		n.Path = installedDir(b.config.CachePathForDar(ref))
+	}
+	n.Children = b.transitiveDars(n.Path, []string{n.Name})
+	return n
+}
+
+// transitiveDars returns the dars recorded as dependencies in the dar.yaml in dir, and theirs in turn.
+// via are the (repository URIs of the) dars leading to dir, so that cycles aren't followed
+func (b *Builder) transitiveDars(dir string, via []string) []*Node {
+	if dir == "" {
+		return nil
+	}
+	m, err := darmanifest.ReadDarManifest(filepath.Join(dir, assistantconfig.DarManifestName))
+	if err != nil {
+		slog.Debug("couldn't read the dependencies of installed dar", "dir", dir, "err", err.Error())
+		return nil
+	}
+
+	var nodes []*Node
+	for _, uri := range m.Spec.Dependencies {
+		dep, err := dargraph.NewDependency(uri, via)
+		if err != nil {
+			nodes = append(nodes, &Node{Kind: KindDar, Name: uri, Errors: []string{err.Error()}})
+			continue
+		}
+		n := &Node{Kind: KindDar, Name: repoURI(&dep.Ref)}
+		n.Declared, n.Resolved = splitDeclared(uri, n.Name)
+		n.Path = installedDir(b.config.CachePathForDar(&dep.Ref))
+		if !slices.Contains(via, n.Name) {
+			n.Children = b.transitiveDars(n.Path, append(slices.Clone(via), n.Name))
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes
+}
+
+// findLockedDar returns the dpm.lock entry of an http(s) or git dar declared in daml.yaml, if any
+func findLockedDar(lock *packagelock.PackageLock, dep *damlpackage.ParsedDarDependency) (*packagelock.Dar, bool) {
+	if lock == nil {
+		return nil, false
+	}
+	return lock.FindDar(dep.FullUrl)
+}
+
+// lockedDar returns the dpm.lock entry of a dar declared in daml.yaml, if any
+func lockedDar(lock *packagelock.PackageLock, dep *damlpackage.ParsedDarDependency, ref *registry.Reference) *packagelock.Dar {
+	if lock == nil {
+		return nil
+	}
+	for _, d := range lock.Dars {
+		if len(d.RequiredBy) > 0 || d.URI == nil || d.URI.Scheme != "oci" {
+			continue
+		}
+		locked, err := registry.ParseReference(strings.TrimPrefix(d.URI.String(), "oci://"))
+		if err != nil || locked.Registry != ref.Registry || locked.Repository != ref.Repository {
+			continue
+		}
+		if dep.Range != "" {
+			if d.Range == dep.Range {
+				return d
+			}
+		} else if locked.Reference == ref.Reference || d.Digest == ref.Reference {
+			return d
+		}
+	}
+	return nil
+}
+
+func repoURI(ref *registry.Reference) string {
+	return "oci://" + ref.Registry + "/" + ref.Repository
+}
+
+// splitDeclared returns the tag, range and/or digest following the repository in uri, and the version (tag) alone
+func splitDeclared(uri, repo string) (declared, version string) {
+	declared = strings.TrimPrefix(strings.TrimPrefix(uri, repo), ":")
+	version, _, _ = strings.Cut(declared, "@")
+	return declared, version
+}
+
+func installedDir(dir string) string {
+	if ok, err := utils.DirExists(dir); err != nil || !ok {
+		return ""
+	}
+	return dir
+}
@@ -0,0 +1,131 @@
+// Copyright (c) 2017-2026 Digital Asset (Switzerland) GmbH and/or its affiliates. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package deptree
+
+import (
+	"testing"
+
+	"daml.com/x/assistant/pkg/assembler/assemblyplan"
+	"daml.com/x/assistant/pkg/resolution"
+	"daml.com/x/assistant/pkg/sdkmanifest"
+	"github.com/Masterminds/semver/v3"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func manifest(path string, comps map[string]string) *sdkmanifest.SdkManifest {
+	return &sdkmanifest.SdkManifest{
+		AbsolutePath: path,
+		Spec: &sdkmanifest.Spec{
+			Components: lo.MapValues(comps, func(v, name string) *sdkmanifest.Component {
+				return &sdkmanifest.Component{Name: name, Version: sdkmanifest.AssemblySemVer(semver.MustParse(v))}
+			}),
+		},
+	}
+}
+
+func TestComponentLayers(t *testing.T) {
+	plan := &assemblyplan.AssemblyPlan{
+		Base:         *manifest("/sdk/3.4.0.yaml", map[string]string{"damlc": "3.4.0", "daml-script": "3.4.0"}),
+		SdkVersion:   semver.MustParse("3.4.0"),
+		MultiPackage: manifest("/proj/multi-package.yaml", map[string]string{"damlc": "3.4.1"}),
+		DamlPackage:  manifest("/proj/a/daml.yaml", map[string]string{"daml-script": "3.4.2"}),
+	}
+	pkg := &resolution.Package{
+		SdkVersion: "3.4.0",
+		Components: map[string]string{"damlc": "/cache/damlc/3.4.1", "daml-script": "/cache/daml-script/3.4.2"},
+		ComponentsV2: map[string]map[string]string{
+			"damlc":       {"path": "/cache/damlc/3.4.1", "version": "3.4.1"},
+			"daml-script": {"path": "/cache/daml-script/3.4.2", "version": "3.4.2"},
+		},
+	}
+
+	layers := componentLayers(plan, pkg)
+	require.Len(t, layers, 3)
+
+	sdk := layers[0]
+	assert.Equal(t, KindSdk, sdk.Kind)
+	assert.Equal(t, "3.4.0", sdk.Declared)
+	require.Len(t, sdk.Children, 2)
+	assert.Equal(t, "daml-script", sdk.Children[0].Name)
+	assert.Equal(t, "/proj/a/daml.yaml", sdk.Children[0].OverriddenBy)
+	assert.Equal(t, "/proj/multi-package.yaml", sdk.Children[1].OverriddenBy)
+
+	multiPackage := layers[1]
+	assert.Equal(t, KindMultiPackage, multiPackage.Kind)
+	require.Len(t, multiPackage.Children, 1)
+	damlc := multiPackage.Children[0]
+	assert.Empty(t, damlc.OverriddenBy)
+	assert.Equal(t, "3.4.1", damlc.Declared)
+	assert.Equal(t, "3.4.1", damlc.Resolved)
+	assert.Equal(t, "/cache/damlc/3.4.1", damlc.Path)
+
+	damlPackage := layers[2]
+	assert.Equal(t, KindDamlPackage, damlPackage.Kind)
+	require.Len(t, damlPackage.Children, 1)
+	assert.Equal(t, "/cache/daml-script/3.4.2", damlPackage.Children[0].Path)
+}
+
+func TestComponentLayersWithoutSdk(t *testing.T) {
+	plan := &assemblyplan.AssemblyPlan{
+		Base:        sdkmanifest.SdkManifest{Spec: &sdkmanifest.Spec{Components: map[string]*sdkmanifest.Component{}}},
+		DamlPackage: manifest("/proj/a/daml.yaml", map[string]string{"damlc": "3.4.2"}),
+	}
+	layers := componentLayers(plan, &resolution.Package{})
+	require.Len(t, layers, 1, "the empty sdk layer is left out")
+	assert.Equal(t, KindDamlPackage, layers[0].Kind)
+}
+
+func TestWhy(t *testing.T) {
+	bar := func() *Node {
+		return &Node{Kind: KindDar, Name: "oci://example.com/dars/bar", Declared: "2.0.0"}
+	}
+	roots := []*Node{
+		{Kind: KindPackage, Name: "a", Children: []*Node{
+			{Kind: KindDar, Name: "oci://example.com/dars/foo", Declared: "1.0.0", Children: []*Node{bar()}},
+			bar(),
+		}},
+		{Kind: KindPackage, Name: "b", Children: []*Node{
+			{Kind: KindSdk, Name: "sdk", Children: []*Node{
+				{Kind: KindComponent, Name: "bar", Declared: "1.0.0"},
+			}},
+		}},
+	}
+
+	paths := Why(roots, "bar")
+	require.Len(t, paths, 3)
+	names := lo.Map(paths, func(p Path, _ int) []string {
+		return lo.Map(p, func(n *Node, _ int) string { return n.Name })
+	})
+	assert.Equal(t, []string{"a", "oci://example.com/dars/foo", "oci://example.com/dars/bar"}, names[0])
+	assert.Equal(t, []string{"a", "oci://example.com/dars/bar"}, names[1])
+	assert.Equal(t, []string{"b", "sdk", "bar"}, names[2])
+
+	assert.Len(t, Why(roots, "example.com/dars/foo"), 1)
+	assert.Empty(t, Why(roots, "sdk"), "only components and dars match")
+	assert.Empty(t, Why(roots, "a"))
+}
+
+func TestSplitDeclared(t *testing.T) {
+	digest := "sha256:aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	declared, version := splitDeclared("oci://example.com/dars/foo:1.0.0@"+digest, "oci://example.com/dars/foo")
+	assert.Equal(t, "1.0.0@"+digest, declared)
+	assert.Equal(t, "1.0.0", version)
+
+	declared, version = splitDeclared("oci://example.com/dars/foo:^1.2", "oci://example.com/dars/foo")
+	assert.Equal(t, "^1.2", declared)
+	assert.Equal(t, "^1.2", version)
+}
+
+func TestLabel(t *testing.T) {
+	assert.Equal(t, "component damlc 3.4.0 (overridden by /proj/daml.yaml)",
+		(&Node{Kind: KindComponent, Name: "damlc", Declared: "3.4.0", OverriddenBy: "/proj/daml.yaml"}).Label())
+	assert.Equal(t, "dar oci://example.com/dars/foo ^1.2 -> 1.4.0 (/cache/foo)",
+		(&Node{Kind: KindDar, Name: "oci://example.com/dars/foo", Declared: "^1.2", Resolved: "1.4.0", Path: "/cache/foo"}).Label())
+	assert.Equal(t, "dar builtin://daml-prim (daml-prim)",
+		(&Node{Kind: KindDar, Name: "builtin://daml-prim", Path: "daml-prim"}).Label())
+	assert.Equal(t, "dar oci://example.com/dars/bar 2.0.0 (not installed)",
+		(&Node{Kind: KindDar, Name: "oci://example.com/dars/bar", Declared: "2.0.0", Resolved: "2.0.0"}).Label())
+}
@@ -0,0 +1,448 @@
+// Copyright (c) 2017-2026 Digital Asset (Switzerland) GmbH and/or its affiliates. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package gitsource fetches dars and components from git repos (git+https://host/repo//subdir@rev).
+// Repos are mirrored into dpm-home's cache, so that revs that have already been fetched resolve offline,
+// and every locked commit is checked out into its own dir
+package gitsource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	"daml.com/x/assistant/pkg/assistantconfig"
+	"daml.com/x/assistant/pkg/assistantconfig/assistantremote"
+	"daml.com/x/assistant/pkg/utils"
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	"oras.land/oras-go/v2/registry/remote/credentials"
+)
+
+const Prefix = "git+"
+
+var commitRegex = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// fileRepos counts the tests that currently allow git+file:// sources
+var fileRepos atomic.Int32
+
+// AllowFileReposInTests lets Parse accept git+file:// sources (i.e. local repos, such as testutil.GitRepo's)
+// until the test ends. Otherwise, git sources must use https
+func AllowFileReposInTests(t interface{ Cleanup(func()) }) {
+	fileRepos.Add(1)
+	t.Cleanup(func() { fileRepos.Add(-1) })
+}
+
+// Source is a dir (or file) at a rev of a git repo
+type Source struct {
+	// the repo's url, e.g. https://github.com/example/foo
+	Repo string
+	// the path within the repo, or "" for its root
+	Subdir string
+	// the tag, branch or commit as declared
+	Rev string
+}
+
+// IsGit whether the dependency (or component) is given as git+<url>
+func IsGit(s string) bool {
+	return strings.HasPrefix(s, Prefix)
+}
+
+// Parse parses "git+https://host/repo[//subdir]@rev"
+func Parse(s string) (*Source, error) {
+	if !IsGit(s) {
+		return nil, fmt.Errorf("%q isn't a git source: it must start with %s", s, Prefix)
+	}
+	rest := strings.TrimPrefix(s, Prefix)
+
+	scheme, rest, ok := strings.Cut(rest, "://")
+	if !ok || rest == "" {
+		return nil, fmt.Errorf("git source %q must be of the form git+https://<host>/<repo>[//<subdir>]@<rev>", s)
+	}
+	if scheme != "https" && (scheme != "file" || fileRepos.Load() == 0) {
+		return nil, fmt.Errorf("git source %q must use https", s)
+	}
+
+	// the rev follows the last '@' after the host (so it can't be confused with any user info)
+	at := strings.LastIndex(rest, "@")
+	if at < 0 || at < strings.Index(rest, "/") {
+		return nil, fmt.Errorf("git source %q must be pinned to a tag, branch or commit with @<rev>", s)
+	}
+	rev := rest[at+1:]
+	rest = rest[:at]
+	if rev == "" {
+		return nil, fmt.Errorf("git source %q has an empty rev", s)
+	}
+
+	repo, subdir, _ := strings.Cut(rest, "//")
+	subdir = strings.Trim(subdir, "/")
+	if subdir != "" && (path.Clean(subdir) != subdir || strings.HasPrefix(subdir, "..")) {
+		return nil, fmt.Errorf("git source %q has an invalid subdir %q", s, subdir)
+	}
+
+	u, err := url.Parse(scheme + "://" + repo)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse git source %q: %w", s, err)
+	}
+	return &Source{Repo: u.String(), Subdir: subdir, Rev: rev}, nil
+}
+
+func (s *Source) String() string {
+	result := Prefix + s.Repo
+	if s.Subdir != "" {
+		result += "//" + s.Subdir
+	}
+	return result + "@" + s.Rev
+}
+
+// Name is the repo (without its scheme), followed by the subdir if any, e.g. github.com/example/foo/components/bar
+func (s *Source) Name() string {
+	u, err := url.Parse(s.Repo)
+	if err != nil {
+		return s.Repo
+	}
+	name := strings.TrimSuffix(strings.Trim(u.Host+u.Path, "/"), ".git")
+	if s.Subdir != "" {
+		name += "/" + s.Subdir
+	}
+	return name
+}
+
+// IsCommit whether the rev is already a (full) commit SHA, in which case it needn't be locked
+func (s *Source) IsCommit() bool {
+	return commitRegex.MatchString(s.Rev)
+}
+
+// Dir is where the source's subdir is checked out at the given commit
+func Dir(config *assistantconfig.Config, s *Source, commit string) string {
+	return filepath.Join(repoCachePath(config, s), commit, filepath.FromSlash(s.Subdir))
+}
+
+// CheckedOut returns Dir, if it's been checked out already
+func CheckedOut(config *assistantconfig.Config, s *Source, commit string) (string, bool, error) {
+	ok, err := utils.DirExists(filepath.Join(repoCachePath(config, s), commit))
+	if err != nil || !ok {
+		return "", false, err
+	}
+	return Dir(config, s, commit), true, nil
+}
+
+// CachePaths are the paths in the cache that the checkout at the given commit relies on:
+// the checkout itself, the repo's mirror that it's checked out from, and the lock guarding both
+func CachePaths(config *assistantconfig.Config, s *Source, commit string) []string {
+	return []string{filepath.Join(repoCachePath(config, s), commit), mirrorPath(config, s), lockPath(config, s)}
+}
+
+// Resolve resolves the source's rev to a commit SHA, fetching the repo into its mirror first.
+// If the fetch fails, revs that have been fetched before still resolve. When dpm is offline,
+// the repo isn't fetched at all, and only those revs resolve.
+// authPath is a docker-style config file with the credentials for the repo's host, if any
+func Resolve(ctx context.Context, config *assistantconfig.Config, s *Source, authPath string) (string, error) {
+	if config.Offline {
+		commit, ok, err := ResolveCached(config, s)
+		if err != nil {
+			return "", err
+		} else if !ok {
+			return "", &assistantremote.OfflineError{Artifact: s.String()}
+		}
+		return commit, nil
+	}
+
+	var commit string
+	err := withRepoLock(ctx, config, s, func() error {
+		repo, fetchErr := mirror(ctx, config, s, authPath)
+		if repo == nil {
+			return fetchErr
+		}
+		hash, err := repo.ResolveRevision(plumbing.Revision(s.Rev))
+		if err != nil {
+			return errors.Join(fmt.Errorf("couldn't resolve %s to a commit: %w", s, err), fetchErr)
+		}
+		if fetchErr != nil {
+			slog.Warn("couldn't fetch git repo, using its mirror in the cache", "repo", s.Repo, "err", fetchErr.Error())
+		}
+		commit = hash.String()
+		return nil
+	})
+	return commit, err
+}
+
+// ResolveCached resolves the source's rev to a commit SHA from its mirror in the cache, without fetching it,
+// so a branch resolves to wherever it was when the repo was last fetched.
+// It returns false if the repo hasn't been fetched yet, or the rev isn't in it
+func ResolveCached(config *assistantconfig.Config, s *Source) (string, bool, error) {
+	repo, err := openMirror(config, s)
+	if errors.Is(err, git.ErrRepositoryNotExists) {
+		return "", false, nil
+	} else if err != nil {
+		return "", false, err
+	}
+	hash, err := repo.ResolveRevision(plumbing.Revision(s.Rev))
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return "", false, nil
+	} else if err != nil {
+		return "", false, err
+	}
+	return hash.String(), true, nil
+}
+
+// Checkout checks out the source at the given commit (unless it's already been), and returns its Dir
+func Checkout(ctx context.Context, config *assistantconfig.Config, s *Source, commit string, authPath string) (string, error) {
+	if dir, ok, err := CheckedOut(config, s, commit); err != nil || ok {
+		return dir, err
+	}
+
+	var dir string
+	err := withRepoLock(ctx, config, s, func() (err error) {
+		dir, err = checkout(ctx, config, s, commit, authPath)
+		return err
+	})
+	return dir, err
+}
+
+// checkout must be called with the repo's lock held
+func checkout(ctx context.Context, config *assistantconfig.Config, s *Source, commit string, authPath string) (string, error) {
+	// checked out while waiting for the lock
+	if dir, ok, err := CheckedOut(config, s, commit); err != nil || ok {
+		return dir, err
+	}
+
+	c, err := commitObject(config, s, commit)
+	if err != nil {
+		// e.g. a commit that's locked, but hasn't been fetched on this machine yet
+		if config.Offline {
+			return "", &assistantremote.OfflineError{Artifact: s.String()}
+		}
+		if _, err := mirror(ctx, config, s, authPath); err != nil {
+			return "", err
+		}
+		if c, err = commitObject(config, s, commit); err != nil {
+			return "", fmt.Errorf("commit %s not found in %s: %w", commit, s.Repo, err)
+		}
+	}
+
+	if err := os.MkdirAll(repoCachePath(config, s), 0755); err != nil {
+		return "", err
+	}
+	tmp, err := os.MkdirTemp(repoCachePath(config, s), ".checkout-*")
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = os.RemoveAll(tmp) }()
+
+	tree, err := c.Tree()
+	if err != nil {
+		return "", err
+	}
+	if err := writeTree(tree, tmp); err != nil {
+		return "", fmt.Errorf("failed to check out %s at %s: %w", s.Repo, commit, err)
+	}
+
+	if err := os.Rename(tmp, filepath.Join(repoCachePath(config, s), commit)); err != nil {
+		return "", err
+	}
+	return Dir(config, s, commit), nil
+}
+
+func repoCachePath(config *assistantconfig.Config, s *Source) string {
+	u, err := url.Parse(s.Repo)
+	if err != nil {
+		return filepath.Join(config.CachePath, "git", utils.UrlToFilePath(s.Repo))
+	}
+	return filepath.Join(config.CachePath, "git", utils.UrlToFilePath(strings.Trim(u.Host+u.Path, "/")))
+}
+
+func lockPath(config *assistantconfig.Config, s *Source) string {
+	return repoCachePath(config, s) + ".lock"
+}
+
+// withRepoLock guards the repo's mirror and checkouts, so that concurrent dpm processes (or pulls)
+// don't fetch into, or clone over, a mirror that's in use
+func withRepoLock(ctx context.Context, config *assistantconfig.Config, s *Source, action func() error) error {
+	return utils.WithInstallLock(ctx, lockPath(config, s), action)
+}
+
+func mirrorPath(config *assistantconfig.Config, s *Source) string {
+	return filepath.Join(repoCachePath(config, s), "mirror.git")
+}
+
+func openMirror(config *assistantconfig.Config, s *Source) (*git.Repository, error) {
+	return git.PlainOpen(mirrorPath(config, s))
+}
+
+func commitObject(config *assistantconfig.Config, s *Source, commit string) (*object.Commit, error) {
+	repo, err := openMirror(config, s)
+	if err != nil {
+		return nil, err
+	}
+	return repo.CommitObject(plumbing.NewHash(commit))
+}
+
+// mirror clones the repo into the cache, or else fetches all its refs. It must be called with the repo's lock held.
+// When the fetch fails, the existing mirror (if any) is returned along with the error
+func mirror(ctx context.Context, config *assistantconfig.Config, s *Source, authPath string) (*git.Repository, error) {
+	auth, err := authMethod(ctx, s, authPath)
+	if err != nil {
+		return nil, err
+	}
+
+	repo, err := openMirror(config, s)
+	cloning := errors.Is(err, git.ErrRepositoryNotExists)
+	if cloning {
+		slog.Debug("cloning git repo", "repo", s.Repo)
+		repo, err = initMirror(config, s)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Debug("fetching git repo", "repo", s.Repo)
+	err = repo.FetchContext(ctx, &git.FetchOptions{Auth: auth, Force: true, Tags: git.AllTags})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		if cloning {
+			_ = os.RemoveAll(mirrorPath(config, s))
+			return nil, fmt.Errorf("failed to clone %s: %w", s.Repo, err)
+		}
+		return repo, fmt.Errorf("failed to fetch %s: %w", s.Repo, err)
+	}
+	return repo, nil
+}
+
+// initMirror creates a bare repo whose origin fetches all the repo's refs as they are
+func initMirror(config *assistantconfig.Config, s *Source) (*git.Repository, error) {
+	repo, err := git.PlainInit(mirrorPath(config, s), true)
+	if err != nil {
+		return nil, err
+	}
+	_, err = repo.CreateRemote(&gitconfig.RemoteConfig{
+		Name:  git.DefaultRemoteName,
+		URLs:  []string{s.Repo},
+		Fetch: []gitconfig.RefSpec{"+refs/*:refs/*"},
+	})
+	if err != nil {
+		_ = os.RemoveAll(mirrorPath(config, s))
+		return nil, err
+	}
+	return repo, nil
+}
+
+// authMethod reads the credentials for the repo's host from the docker-style config file at authPath, if any
+func authMethod(ctx context.Context, s *Source, authPath string) (transport.AuthMethod, error) {
+	u, err := url.Parse(s.Repo)
+	if err != nil || authPath == "" || u.Scheme == "file" {
+		return nil, err
+	}
+	store, err := credentials.NewStore(authPath, credentials.StoreOptions{})
+	if err != nil {
+		return nil, err
+	}
+	cred, err := store.Get(ctx, u.Host)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case cred.AccessToken != "":
+		// hosts like github take tokens as the password of any user
+		return &githttp.BasicAuth{Username: "dpm", Password: cred.AccessToken}, nil
+	case cred.Username != "" || cred.Password != "":
+		return &githttp.BasicAuth{Username: cred.Username, Password: cred.Password}, nil
+	default:
+		return nil, nil
+	}
+}
+
+// writeTree writes the tree's files into dir. As the repo isn't trusted, it fails on any file or symlink
+// that would end up (or point) outside of dir
+func writeTree(tree *object.Tree, dir string) error {
+	err := tree.Files().ForEach(func(f *object.File) error {
+		name := filepath.FromSlash(f.Name)
+		if !filepath.IsLocal(name) {
+			return fmt.Errorf("%q is outside of the checkout", f.Name)
+		}
+		dest := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return err
+		}
+
+		if f.Mode == filemode.Symlink {
+			target, err := f.Contents()
+			if err != nil {
+				return err
+			}
+			if path.IsAbs(target) || filepath.IsAbs(target) || !isWithin(dir, filepath.Join(filepath.Dir(dest), filepath.FromSlash(target))) {
+				return fmt.Errorf("symlink %q points outside of the checkout, to %q", f.Name, target)
+			}
+			return os.Symlink(target, dest)
+		}
+
+		perm := os.FileMode(0644)
+		if f.Mode == filemode.Executable {
+			perm = 0755
+		}
+		r, err := f.Reader()
+		if err != nil {
+			return err
+		}
+		defer func() { _ = r.Close() }()
+		out, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = out.Close() }()
+		if _, err := io.Copy(out, r); err != nil {
+			return err
+		}
+		return out.Close()
+	})
+	if err != nil {
+		return err
+	}
+	return checkSymlinks(dir)
+}
+
+// checkSymlinks fails if any symlink in dir resolves to outside of it, which chains of symlinks
+// that each point inside of it can still do (e.g. a -> . and b -> a/..)
+func checkSymlinks(dir string) error {
+	root, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return err
+	}
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.Type()&fs.ModeSymlink == 0 {
+			return err
+		}
+		resolved, err := filepath.EvalSymlinks(p)
+		if errors.Is(err, fs.ErrNotExist) {
+			// dangling, which is harmless as long as it points inside dir, as checked when it was written
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if !isWithin(root, resolved) {
+			rel, _ := filepath.Rel(dir, p)
+			return fmt.Errorf("symlink %q resolves to outside of the checkout", filepath.ToSlash(rel))
+		}
+		return nil
+	})
+}
+
+// isWithin whether the (clean) path p is dir or inside of it
+func isWithin(dir, p string) bool {
+	rel, err := filepath.Rel(dir, p)
+	return err == nil && (rel == "." || filepath.IsLocal(rel))
+}
@@ -0,0 +1,259 @@
+// Copyright (c) 2017-2026 Digital Asset (Switzerland) GmbH and/or its affiliates. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package gitsource
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"daml.com/x/assistant/pkg/assistantconfig/assistantremote"
+	"daml.com/x/assistant/pkg/testutil"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		in       string
+		expected *Source
+	}{
+		{"git+https://github.com/example/foo@v1.0.0", &Source{Repo: "https://github.com/example/foo", Rev: "v1.0.0"}},
+		{"git+https://github.com/example/foo.git//components/bar@main", &Source{Repo: "https://github.com/example/foo.git", Subdir: "components/bar", Rev: "main"}},
+		{"git+https://user@github.com/example/foo//dars/foo.dar@release/1.x", &Source{Repo: "https://user@github.com/example/foo", Subdir: "dars/foo.dar", Rev: "release/1.x"}},
+	}
+	for _, c := range cases {
+		t.Run(c.in, func(t *testing.T) {
+			s, err := Parse(c.in)
+			require.NoError(t, err)
+			assert.Equal(t, c.expected, s)
+			assert.Equal(t, c.in, s.String())
+		})
+	}
+
+	for _, in := range []string{
+		"https://github.com/example/foo@v1.0.0",
+		"git+ssh://github.com/example/foo@v1.0.0",
+		"git+http://github.com/example/foo@v1.0.0",
+		"git+file:///tmp/foo@v1.0.0",
+		"git+https://github.com/example/foo",
+		"git+https://user@github.com/example/foo",
+		"git+https://github.com/example/foo@",
+		"git+https://github.com/example/foo//../bar@v1.0.0",
+	} {
+		t.Run(in, func(t *testing.T) {
+			_, err := Parse(in)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestName(t *testing.T) {
+	s, err := Parse("git+https://github.com/example/foo.git//components/bar@v1.0.0")
+	require.NoError(t, err)
+	assert.Equal(t, "github.com/example/foo/components/bar", s.Name())
+	assert.False(t, s.IsCommit())
+}
+
+func TestResolveAndCheckout(t *testing.T) {
+	ctx := testutil.Context(t)
+	config := testutil.MkConfig(t)
+
+	AllowFileReposInTests(t)
+	repoDir, commit := testutil.GitRepo(t, map[string][]byte{
+		"README.md":                     []byte("hi"),
+		"components/bar/run.sh":         []byte("#!/bin/sh\n"),
+		"components/bar/component.yaml": []byte("kind: Component\n"),
+	}, "v1.0.0")
+
+	s, err := Parse("git+file://" + filepath.ToSlash(repoDir) + "//components/bar@v1.0.0")
+	require.NoError(t, err)
+
+	_, ok, err := ResolveCached(config, s)
+	require.NoError(t, err)
+	assert.False(t, ok, "not fetched yet")
+
+	resolved, err := Resolve(ctx, config, s, "")
+	require.NoError(t, err)
+	assert.Equal(t, commit, resolved)
+
+	resolved, ok, err = ResolveCached(config, s)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, commit, resolved)
+
+	_, ok, err = ResolveCached(config, &Source{Repo: s.Repo, Rev: "v2.0.0"})
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	_, ok, err = CheckedOut(config, s, commit)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	dir, err := Checkout(ctx, config, s, commit, "")
+	require.NoError(t, err)
+	assert.Equal(t, Dir(config, s, commit), dir)
+	assert.FileExists(t, filepath.Join(dir, "component.yaml"))
+	if testutil.OS == "unix" {
+		info, err := os.Stat(filepath.Join(dir, "run.sh"))
+		require.NoError(t, err)
+		assert.NotZero(t, info.Mode()&0100, "executable bit is kept")
+	}
+
+	// the rev still resolves (from the mirror) once the repo is gone
+	require.NoError(t, os.RemoveAll(repoDir))
+	resolved, err = Resolve(ctx, config, s, "")
+	require.NoError(t, err)
+	assert.Equal(t, commit, resolved)
+
+	// as does the checkout
+	s.Rev = commit
+	assert.True(t, s.IsCommit())
+	dir, err = Checkout(ctx, config, s, commit, "")
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(dir, "run.sh"))
+}
+
+func TestConcurrentCheckouts(t *testing.T) {
+	ctx := testutil.Context(t)
+	config := testutil.MkConfig(t)
+
+	AllowFileReposInTests(t)
+	repoDir, commit := testutil.GitRepo(t, map[string][]byte{
+		"foo/component.yaml": []byte("kind: Component\n"),
+		"bar/component.yaml": []byte("kind: Component\n"),
+	}, "v1.0.0")
+
+	// subdirs of the same repo share its mirror and checkout, so are cloned and checked out once
+	var wg sync.WaitGroup
+	for i := range 6 {
+		subdir := []string{"foo", "bar"}[i%2]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s, err := Parse("git+file://" + filepath.ToSlash(repoDir) + "//" + subdir + "@v1.0.0")
+			if !assert.NoError(t, err) {
+				return
+			}
+			resolved, err := Resolve(ctx, config, s, "")
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, commit, resolved)
+			dir, err := Checkout(ctx, config, s, resolved, "")
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.FileExists(t, filepath.Join(dir, "component.yaml"))
+		}()
+	}
+	wg.Wait()
+}
+
+func TestOffline(t *testing.T) {
+	ctx := testutil.Context(t)
+	config := testutil.MkConfig(t)
+	config.Offline = true
+
+	// never fetched, and couldn't be
+	unreachable, err := Parse("git+https://example.invalid/foo@v1.0.0")
+	require.NoError(t, err)
+	_, err = Resolve(ctx, config, unreachable, "")
+	assert.ErrorIs(t, err, assistantremote.ErrOffline)
+	_, err = Checkout(ctx, config, unreachable, strings.Repeat("a", 40), "")
+	assert.ErrorIs(t, err, assistantremote.ErrOffline)
+
+	// fetched while online
+	AllowFileReposInTests(t)
+	repoDir, commit := testutil.GitRepo(t, map[string][]byte{"foo.dar": []byte("dar")}, "v1.0.0")
+	s, err := Parse("git+file://" + filepath.ToSlash(repoDir) + "@v1.0.0")
+	require.NoError(t, err)
+	config.Offline = false
+	_, err = Resolve(ctx, config, s, "")
+	require.NoError(t, err)
+	config.Offline = true
+
+	resolved, err := Resolve(ctx, config, s, "")
+	require.NoError(t, err)
+	assert.Equal(t, commit, resolved)
+	dir, err := Checkout(ctx, config, s, commit, "")
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(dir, "foo.dar"))
+
+	_, err = Resolve(ctx, config, &Source{Repo: s.Repo, Rev: "v2.0.0"}, "")
+	assert.ErrorIs(t, err, assistantremote.ErrOffline)
+}
+
+// entry is a file in a git tree, or a symlink to its content
+type entry struct {
+	name    string
+	mode    filemode.FileMode
+	content string
+}
+
+// tree builds a git tree of the given entries, which (unlike a worktree's files) needn't have valid paths
+func tree(t *testing.T, entries ...entry) *object.Tree {
+	s := memory.NewStorage()
+	var treeEntries []object.TreeEntry
+	for _, e := range entries {
+		blob := s.NewEncodedObject()
+		blob.SetType(plumbing.BlobObject)
+		w, err := blob.Writer()
+		require.NoError(t, err)
+		_, err = w.Write([]byte(e.content))
+		require.NoError(t, err)
+		require.NoError(t, w.Close())
+		h, err := s.SetEncodedObject(blob)
+		require.NoError(t, err)
+		treeEntries = append(treeEntries, object.TreeEntry{Name: e.name, Mode: e.mode, Hash: h})
+	}
+
+	slices.SortFunc(treeEntries, func(a, b object.TreeEntry) int { return strings.Compare(a.Name, b.Name) })
+
+	obj := s.NewEncodedObject()
+	require.NoError(t, (&object.Tree{Entries: treeEntries}).Encode(obj))
+	h, err := s.SetEncodedObject(obj)
+	require.NoError(t, err)
+	tr, err := object.GetTree(s, h)
+	require.NoError(t, err)
+	return tr
+}
+
+func TestWriteTreeStaysInTheCheckout(t *testing.T) {
+	if testutil.OS != "unix" {
+		t.Skip("symlinks need privileges on windows")
+	}
+
+	dir := t.TempDir()
+	require.NoError(t, writeTree(tree(t,
+		entry{"foo.dar", filemode.Regular, "dar"},
+		entry{"current.dar", filemode.Symlink, "foo.dar"},
+		entry{"here", filemode.Symlink, "."},
+	), dir))
+	target, err := os.Readlink(filepath.Join(dir, "current.dar"))
+	require.NoError(t, err)
+	assert.Equal(t, "foo.dar", target)
+
+	for name, tr := range map[string]*object.Tree{
+		"file outside":             tree(t, entry{"../evil", filemode.Regular, "evil"}),
+		"absolute symlink":         tree(t, entry{"passwd", filemode.Symlink, "/etc/passwd"}),
+		"relative symlink outside": tree(t, entry{"up", filemode.Symlink, "../.."}),
+		"chain of symlinks":        tree(t, entry{"a", filemode.Symlink, "."}, entry{"b", filemode.Symlink, "a/.."}),
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "checkout")
+			require.NoError(t, os.Mkdir(dir, 0755))
+			// (go-git may already refuse to walk some of them)
+			assert.Error(t, writeTree(tr, dir))
+			assert.NoFileExists(t, filepath.Join(filepath.Dir(dir), "evil"))
+		})
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright (c) 2017-2026 Digital Asset (Switzerland) GmbH and/or its affiliates. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package hooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/samber/lo"
+)
+
+const (
+	PrePrefix  = "pre-"
+	PostPrefix = "post-"
+)
+
+// Hooks maps pre-<command> and post-<command> to the shell commands that run before and after an SDK command,
+// where <command> is the command's full name (e.g. "build", or "canton sandbox" for a command in a group)
+type Hooks map[string]Commands
+
+func (h *Hooks) UnmarshalYAML(data []byte) error {
+	raw := make(map[string]Commands)
+	if err := yaml.UnmarshalWithOptions(data, &raw, yaml.Strict()); err != nil {
+		return err
+	}
+
+	var errs []error
+	for name, commands := range raw {
+		command, ok := strings.CutPrefix(name, PrePrefix)
+		if !ok {
+			command, ok = strings.CutPrefix(name, PostPrefix)
+		}
+		if !ok || command == "" {
+			errs = append(errs, fmt.Errorf("invalid hook %q. Must be named %s<command> or %s<command>", name, PrePrefix, PostPrefix))
+		}
+		if lo.Contains(commands, "") {
+			errs = append(errs, fmt.Errorf("hook %q has an empty command", name))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	*h = raw
+	return nil
+}
+
+// Pre returns the hooks to run before the given SDK command, given the dir of the yaml file they're declared in
+func (h Hooks) Pre(command, dir string) []*Hook {
+	return h.hooks(PrePrefix+command, dir)
+}
+
+// Post returns the hooks to run after the given SDK command, given the dir of the yaml file they're declared in
+func (h Hooks) Post(command, dir string) []*Hook {
+	return h.hooks(PostPrefix+command, dir)
+}
+
+func (h Hooks) hooks(name, dir string) []*Hook {
+	return lo.Map(h[name], func(command string, _ int) *Hook {
+		return &Hook{Name: name, Command: command, Dir: dir}
+	})
+}
+
+// Commands are a hook's shell commands, given either as a single string or as a list
+type Commands []string
+
+func (c *Commands) UnmarshalYAML(data []byte) error {
+	var single string
+	if err := yaml.Unmarshal(data, &single); err == nil {
+		*c = Commands{single}
+		return nil
+	}
+
+	var list []string
+	if err := yaml.UnmarshalWithOptions(data, &list, yaml.Strict()); err != nil {
+		return fmt.Errorf("a hook must be a command or a list of commands: %w", err)
+	}
+	*c = list
+	return nil
+}
+
+// Hook is a shell command that runs around an SDK command
+type Hook struct {
+	// e.g. pre-build
+	Name    string
+	Command string
+	// the dir of the daml.yaml or multi-package.yaml that declares it, which it runs in
+	Dir string
+}
+
+func (h *Hook) String() string {
+	return fmt.Sprintf("%s hook %q", h.Name, h.Command)
+}
+
+// Run runs the hook through the shell
+func (h *Hook) Run(ctx context.Context, env []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	cmd := exec.CommandContext(ctx, "sh", "-c", h.Command)
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", h.Command)
+	}
+	cmd.Dir = h.Dir
+	cmd.Env = env
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s (in %q) failed: %w", h, h.Dir, err)
+	}
+	return nil
+}
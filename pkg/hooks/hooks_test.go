@@ -0,0 +1,58 @@
+// Copyright (c) 2017-2026 Digital Asset (Switzerland) GmbH and/or its affiliates. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package hooks
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"daml.com/x/assistant/pkg/testutil"
+	"github.com/goccy/go-yaml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnmarshal(t *testing.T) {
+	var h Hooks
+	require.NoError(t, yaml.Unmarshal([]byte(`
+pre-build: dpm codegen-js
+post-test:
+  - ./upload-reports.sh
+  - rm -rf .reports
+pre-canton sandbox: echo starting
+`), &h))
+	assert.Equal(t, []*Hook{{Name: "pre-build", Command: "dpm codegen-js", Dir: "/p"}}, h.Pre("build", "/p"))
+	assert.Equal(t, []*Hook{
+		{Name: "post-test", Command: "./upload-reports.sh", Dir: "/p"},
+		{Name: "post-test", Command: "rm -rf .reports", Dir: "/p"},
+	}, h.Post("test", "/p"))
+	assert.Len(t, h.Pre("canton sandbox", "/p"), 1)
+	assert.Empty(t, h.Post("build", "/p"))
+
+	err := yaml.Unmarshal([]byte("build: dpm codegen-js\npre-: echo\npost-test: ['']\n"), &h)
+	assert.ErrorContains(t, err, `invalid hook "build"`)
+	assert.ErrorContains(t, err, `invalid hook "pre-"`)
+	assert.ErrorContains(t, err, `hook "post-test" has an empty command`)
+
+	assert.ErrorContains(t, yaml.Unmarshal([]byte("pre-build: {run: dpm codegen-js}\n"), &h), "a hook must be a command or a list of commands")
+}
+
+func TestRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh syntax")
+	}
+	ctx := testutil.Context(t)
+	dir := t.TempDir()
+
+	h := &Hook{Name: "pre-build", Command: `echo "$GREETING" > greeting.txt`, Dir: dir}
+	require.NoError(t, h.Run(ctx, []string{"GREETING=hello"}, nil, os.Stdout, os.Stderr))
+	greeting, err := os.ReadFile(filepath.Join(dir, "greeting.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "hello\n", string(greeting))
+
+	h = &Hook{Name: "post-test", Command: "exit 3", Dir: dir}
+	assert.ErrorContains(t, h.Run(ctx, nil, nil, os.Stdout, os.Stderr), `post-test hook "exit 3"`)
+}
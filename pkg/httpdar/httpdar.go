@@ -0,0 +1,209 @@
+// Copyright (c) 2017-2026 Digital Asset (Switzerland) GmbH and/or its affiliates. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package httpdar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"daml.com/x/assistant/cmd/dpm/cmd/resolve/resolutionerrors"
+	"daml.com/x/assistant/pkg/assistantconfig"
+	"daml.com/x/assistant/pkg/assistantconfig/assistantremote"
+	"daml.com/x/assistant/pkg/damlpackage"
+	"daml.com/x/assistant/pkg/darmanifest"
+	"daml.com/x/assistant/pkg/ocipusher/darpusher"
+	"daml.com/x/assistant/pkg/schema"
+	"daml.com/x/assistant/pkg/utils"
+	"github.com/goccy/go-yaml"
+	"github.com/opencontainers/go-digest"
+	"oras.land/oras-go/v2/registry"
+	"oras.land/oras-go/v2/registry/remote/credentials"
+)
+
+// downloadTimeout bounds a dar's whole download, retries included
+const downloadTimeout = 10 * time.Minute
+
+// httpClient retries failed downloads like registry requests are retried
+var httpClient = assistantremote.NewHttpClient(nil)
+
+// IsHttp whether the dar dependency is downloaded over http(s), rather than pulled from an OCI registry
+func IsHttp(dep *damlpackage.ParsedDarDependency) bool {
+	return dep.FullUrl != nil && (dep.FullUrl.Scheme == "https" || dep.FullUrl.Scheme == "http")
+}
+
+type PulledDar struct {
+	Dir         string
+	DarFilePath string
+	// the digest of the dar file itself
+	Digest digest.Digest
+}
+
+// Dir is where the dar with the given (file) digest is cached.
+// It's laid out like a pulled OCI dar (i.e. with a dar.yaml), so it's resolved the same way
+func Dir(config *assistantconfig.Config, d digest.Digest) string {
+	return config.CachePathForDar(&registry.Reference{Reference: d.String()})
+}
+
+// Pull downloads the dar into the cache, unless it's already there.
+// expected is the digest the dar is pinned to (inline or in the lockfile), and the download is checked against it.
+// If it's empty, the dar is trusted as downloaded (so that it can be pinned)
+func Pull(ctx context.Context, config *assistantconfig.Config, dep *damlpackage.ParsedDarDependency, expected digest.Digest) (*PulledDar, error) {
+	if expected != "" {
+		if p, ok, err := Installed(config, expected); err != nil || ok {
+			return p, err
+		}
+	}
+
+	if config.Offline {
+		return nil, &assistantremote.OfflineError{Artifact: dep.FullUrl.String()}
+	}
+
+	downloadsDir := filepath.Join(config.CachePath, "dars")
+	if err := os.MkdirAll(downloadsDir, 0755); err != nil {
+		return nil, err
+	}
+	tmp, err := os.MkdirTemp(downloadsDir, ".download-*")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = os.RemoveAll(tmp) }()
+
+	fileName := path.Base(dep.FullUrl.Path)
+	if fileName == "." || fileName == "/" {
+		fileName = "dependency.dar"
+	}
+	actual, err := download(ctx, dep, filepath.Join(tmp, fileName))
+	if err != nil {
+		return nil, err
+	}
+	if expected != "" && actual != expected {
+		return nil, fmt.Errorf("%s has digest %s, but is pinned to %s", dep.FullUrl, actual, expected)
+	}
+
+	if err := writeDarManifest(tmp, fileName); err != nil {
+		return nil, err
+	}
+	dir := Dir(config, actual)
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp, dir); err != nil {
+		// downloaded concurrently
+		if p, ok, _ := Installed(config, actual); ok {
+			return p, nil
+		}
+		return nil, err
+	}
+	return &PulledDar{Dir: dir, DarFilePath: filepath.Join(dir, fileName), Digest: actual}, nil
+}
+
+// UnpinnedError is the error for a dar that's pinned to a sha256 neither inline nor in the lockfile
+func UnpinnedError(dar *damlpackage.ParsedDarDependency) error {
+	lock := "run 'dpm update' to lock it"
+	if !assistantconfig.DpmLockfileEnabled() {
+		lock = fmt.Sprintf("set %s=true and run 'dpm update' to lock it", assistantconfig.DpmLockfileEnabledEnvVar)
+	}
+	return resolutionerrors.NewUnpinnedDarError(fmt.Errorf("dar %q needs a sha256: pin it inline (<url>@sha256:<digest>) or %s", dar.StringWithAlias(), lock))
+}
+
+// Installed returns the cached dar with the given digest, if it's been downloaded
+func Installed(config *assistantconfig.Config, d digest.Digest) (*PulledDar, bool, error) {
+	dir := Dir(config, d)
+	ok, err := utils.DirExists(dir)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	m, err := darmanifest.ReadDarManifest(filepath.Join(dir, assistantconfig.DarManifestName))
+	if err != nil {
+		return nil, false, err
+	}
+	return &PulledDar{Dir: dir, DarFilePath: utils.ResolvePath(dir, m.Spec.Dars[0].Path), Digest: d}, true, nil
+}
+
+// download writes the dar to dest, and returns its digest
+func download(ctx context.Context, dep *damlpackage.ParsedDarDependency, dest string) (digest.Digest, error) {
+	ctx, cancel := context.WithTimeout(ctx, downloadTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, dep.FullUrl.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", assistantconfig.GetAssistantUserAgent())
+	if err := authorize(ctx, req, dep.Location); err != nil {
+		return "", err
+	}
+
+	slog.Debug("downloading dar", "url", dep.FullUrl.String())
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to download %s: %w", dep.FullUrl, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to download %s: %s", dep.FullUrl, resp.Status)
+	}
+
+	f, err := os.Create(dest)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+
+	digester := digest.Canonical.Digester()
+	if _, err := io.Copy(io.MultiWriter(f, digester.Hash()), resp.Body); err != nil {
+		return "", fmt.Errorf("failed to download %s: %w", dep.FullUrl, err)
+	}
+	return digester.Digest(), f.Close()
+}
+
+// authorize adds the credentials for the request's host from the artifact-location's auth file, if any
+func authorize(ctx context.Context, req *http.Request, location *damlpackage.ArtifactLocation) error {
+	if location == nil || location.Auth == "" {
+		return nil
+	}
+	store, err := credentials.NewStore(location.Auth, credentials.StoreOptions{})
+	if err != nil {
+		return err
+	}
+	cred, err := store.Get(ctx, req.URL.Host)
+	if err != nil {
+		return err
+	}
+	switch {
+	case cred.AccessToken != "":
+		req.Header.Set("Authorization", "Bearer "+cred.AccessToken)
+	case cred.Username != "" || cred.Password != "":
+		req.SetBasicAuth(cred.Username, cred.Password)
+	default:
+		slog.Debug("no credentials for dar download", "host", req.URL.Host, "auth", location.Auth)
+	}
+	return nil
+}
+
+func writeDarManifest(dir, fileName string) error {
+	mainPackageId, err := darpusher.GetMainPackageId(filepath.Join(dir, fileName))
+	if err != nil {
+		return errors.Join(fmt.Errorf("downloaded file %q isn't a valid dar", fileName), err)
+	}
+	m := darmanifest.DarManifest{
+		ManifestMeta: schema.ManifestMeta{APIVersion: darmanifest.DarAPIVersion, Kind: darmanifest.DarKind},
+		Spec: &darmanifest.Spec{
+			Dars: []darmanifest.Dar{{Path: fileName, MainPackageId: mainPackageId}},
+		},
+	}
+	b, err := yaml.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, assistantconfig.DarManifestName), b, 0644)
+}
@@ -0,0 +1,130 @@
+// Copyright (c) 2017-2026 Digital Asset (Switzerland) GmbH and/or its affiliates. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package httpdar
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"daml.com/x/assistant/pkg/assistantconfig"
+	"daml.com/x/assistant/pkg/assistantconfig/assistantremote"
+	"daml.com/x/assistant/pkg/damlpackage"
+	"daml.com/x/assistant/pkg/darmanifest"
+	"daml.com/x/assistant/pkg/testutil"
+	"github.com/opencontainers/go-digest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serveDar(t *testing.T, user, password string) (*httptest.Server, digest.Digest, *int) {
+	b, err := os.ReadFile(testutil.TestdataPath(t, "test-dar", "test.dar"))
+	require.NoError(t, err)
+	downloads := 0
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if u, p, _ := r.BasicAuth(); u != user || p != password {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		downloads++
+		// the first attempt fails, and is retried
+		if downloads == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write(b)
+	}))
+	t.Cleanup(srv.Close)
+
+	// trust the test server's certificate
+	prev := httpClient
+	httpClient = assistantremote.NewHttpClient(srv.Client().Transport)
+	t.Cleanup(func() { httpClient = prev })
+
+	return srv, digest.FromBytes(b), &downloads
+}
+
+func dependency(t *testing.T, rawUrl string, location *damlpackage.ArtifactLocation) *damlpackage.ParsedDarDependency {
+	u, err := url.Parse(rawUrl)
+	require.NoError(t, err)
+	return &damlpackage.ParsedDarDependency{FullUrl: u, Location: location}
+}
+
+func TestPull(t *testing.T) {
+	ctx := testutil.Context(t)
+	config := &assistantconfig.Config{CachePath: t.TempDir()}
+	srv, expected, downloads := serveDar(t, "", "")
+	dep := dependency(t, srv.URL+"/releases/meep-1.0.0.dar", nil)
+
+	pulled, err := Pull(ctx, config, dep, expected)
+	require.NoError(t, err)
+	assert.Equal(t, expected, pulled.Digest)
+	assert.Equal(t, Dir(config, expected), pulled.Dir)
+	assert.Equal(t, filepath.Join(pulled.Dir, "meep-1.0.0.dar"), pulled.DarFilePath)
+
+	m, err := darmanifest.ReadDarManifest(filepath.Join(pulled.Dir, assistantconfig.DarManifestName))
+	require.NoError(t, err)
+	assert.Equal(t, "0984ff5e3082add400bfcc6e3244bf9822ca5a617cfd92429e3fbce58058dbfa", m.Spec.Dars[0].MainPackageId)
+
+	_, err = Pull(ctx, config, dep, expected)
+	require.NoError(t, err)
+	assert.Equal(t, 2, *downloads, "one retry, and then the cached dar is reused")
+
+	entries, err := os.ReadDir(filepath.Join(config.CachePath, "dars"))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "no leftover downloads")
+}
+
+func TestPullUnpinned(t *testing.T) {
+	config := &assistantconfig.Config{CachePath: t.TempDir()}
+	srv, expected, _ := serveDar(t, "", "")
+
+	pulled, err := Pull(testutil.Context(t), config, dependency(t, srv.URL+"/meep.dar", nil), "")
+	require.NoError(t, err)
+	assert.Equal(t, expected, pulled.Digest)
+}
+
+func TestPullOffline(t *testing.T) {
+	config := &assistantconfig.Config{CachePath: t.TempDir(), Offline: true}
+	srv, expected, downloads := serveDar(t, "", "")
+
+	_, err := Pull(testutil.Context(t), config, dependency(t, srv.URL+"/meep.dar", nil), expected)
+	assert.ErrorIs(t, err, assistantremote.ErrOffline)
+	assert.Zero(t, *downloads)
+}
+
+func TestPullDigestMismatch(t *testing.T) {
+	config := &assistantconfig.Config{CachePath: t.TempDir()}
+	srv, _, _ := serveDar(t, "", "")
+
+	other := digest.FromString("something else")
+	_, err := Pull(testutil.Context(t), config, dependency(t, srv.URL+"/meep.dar", nil), other)
+	require.ErrorContains(t, err, "is pinned to "+other.String())
+
+	_, ok, err := Installed(config, other)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestPullWithAuth(t *testing.T) {
+	config := &assistantconfig.Config{CachePath: t.TempDir()}
+	srv, expected, _ := serveDar(t, "alice", "s3cret")
+
+	host := srv.Listener.Addr().String()
+	authPath := filepath.Join(t.TempDir(), "auth.json")
+	creds := base64.StdEncoding.EncodeToString([]byte("alice:s3cret"))
+	require.NoError(t, os.WriteFile(authPath, []byte(fmt.Sprintf(`{"auths": {%q: {"auth": %q}}}`, host, creds)), 0600))
+
+	_, err := Pull(testutil.Context(t), config, dependency(t, srv.URL+"/meep.dar", nil), expected)
+	require.ErrorContains(t, err, "401")
+
+	pulled, err := Pull(testutil.Context(t), config, dependency(t, srv.URL+"/meep.dar", &damlpackage.ArtifactLocation{Auth: authPath}), expected)
+	require.NoError(t, err)
+	assert.Equal(t, expected, pulled.Digest)
+}
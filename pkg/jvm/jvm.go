@@ -0,0 +1,183 @@
+// Copyright (c) 2017-2026 Digital Asset (Switzerland) GmbH and/or its affiliates. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package jvm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/samber/lo"
+)
+
+const (
+	// JdkComponentName is the name of the (SDK-provided) component that jar-commands run with,
+	// unless their own component maps another one to JavaHomeEnvVar in its dependency-paths
+	JdkComponentName = "jdk"
+	JavaHomeEnvVar   = "JAVA_HOME"
+)
+
+var ErrNoCompatibleJvm = errors.New("no compatible java runtime found")
+
+// versions memoizes the version of each java that's been asked for one (by versionKey),
+// as the jar-commands of an assembly mostly share the same java
+var versions sync.Map
+
+// versionRegex matches the version in the first line of `java -version`'s output, e.g. openjdk version "17.0.2" 2022-01-18
+var versionRegex = regexp.MustCompile(`version "([^"]+)"`)
+
+// Java is the java binary a jar-command runs with
+type Java struct {
+	Path string
+	// the JDK's root dir, if it's managed by dpm (i.e. a component)
+	Home string
+}
+
+// Find returns the java binary of the JDK at javaHome, or else the one on PATH
+func Find(javaHome string) (*Java, error) {
+	if javaHome == "" {
+		p, err := exec.LookPath("java")
+		if err != nil {
+			return nil, fmt.Errorf("%w: 'java' not found on PATH: %w", ErrNoCompatibleJvm, err)
+		}
+		return &Java{Path: p}, nil
+	}
+
+	p := filepath.Join(javaHome, "bin", lo.Ternary(runtime.GOOS == "windows", "java.exe", "java"))
+	if _, err := os.Stat(p); err != nil {
+		return nil, fmt.Errorf("%w: the JDK at %q has no java binary: %w", ErrNoCompatibleJvm, javaHome, err)
+	}
+	return &Java{Path: p, Home: javaHome}, nil
+}
+
+// Require fails unless the java's version satisfies the given range
+func (j *Java) Require(ctx context.Context, versionRange string) error {
+	c, err := semver.NewConstraint(versionRange)
+	if err != nil {
+		return fmt.Errorf("invalid java version range %q: %w", versionRange, err)
+	}
+	v, err := j.Version(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: couldn't determine the version of %q: %w", ErrNoCompatibleJvm, j.Path, err)
+	}
+	if !c.Check(v) {
+		return fmt.Errorf("%w: java %q is version %s, which doesn't satisfy %q", ErrNoCompatibleJvm, j.Path, v, versionRange)
+	}
+	return nil
+}
+
+// Version reads the java's version off its JDK's release file, or else asks the binary for it.
+// It's only looked up once per java, unless the JDK changes in place
+func (j *Java) Version(ctx context.Context) (*semver.Version, error) {
+	key := j.versionKey()
+	if v, ok := versions.Load(key); ok {
+		return v.(*semver.Version), nil
+	}
+	v, err := j.lookupVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+	versions.Store(key, v)
+	return v, nil
+}
+
+// versionKey identifies the java binary, and its JDK's release file, as they currently are
+func (j *Java) versionKey() string {
+	key := j.Path
+	for _, p := range []string{j.Path, filepath.Join(j.home(), "release")} {
+		if info, err := os.Stat(p); err == nil {
+			key += fmt.Sprintf("\x00%d\x00%d", info.ModTime().UnixNano(), info.Size())
+		}
+	}
+	return key
+}
+
+func (j *Java) lookupVersion(ctx context.Context) (*semver.Version, error) {
+	if v, err := releaseFileVersion(j.home()); err == nil {
+		return v, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	// java -version prints to stderr
+	out, err := exec.CommandContext(ctx, j.Path, "-version").CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("'%s -version' failed: %w", j.Path, err)
+	}
+	m := versionRegex.FindSubmatch(out)
+	if m == nil {
+		return nil, fmt.Errorf("unexpected output of '%s -version': %q", j.Path, firstLine(out))
+	}
+	return ParseVersion(string(m[1]))
+}
+
+// home is the JDK's root dir: for a java on PATH, the parent of the bin dir its (symlinked) binary lives in
+func (j *Java) home() string {
+	if j.Home != "" {
+		return j.Home
+	}
+	p, err := filepath.EvalSymlinks(j.Path)
+	if err != nil {
+		p = j.Path
+	}
+	return filepath.Dir(filepath.Dir(p))
+}
+
+// releaseFileVersion reads JAVA_VERSION off the release file at the root of every JDK (and JRE) since java 9
+func releaseFileVersion(javaHome string) (*semver.Version, error) {
+	f, err := os.Open(filepath.Join(javaHome, "release"))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if v, ok := strings.CutPrefix(scanner.Text(), "JAVA_VERSION="); ok {
+			return ParseVersion(strings.Trim(v, `"`))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("no JAVA_VERSION in %q", f.Name())
+}
+
+// ParseVersion parses java versions, both legacy (1.8.0_292) and current (17.0.2, 21, 11.0.2-ea, 17.0.2+8)
+func ParseVersion(s string) (*semver.Version, error) {
+	v := s
+	// drop any pre-release or build suffix, a -ea build should satisfy >=11 like a GA one would
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	v = strings.ReplaceAll(v, "_", ".")
+	if rest, ok := strings.CutPrefix(v, "1."); ok {
+		v = rest
+	}
+	if parts := strings.Split(v, "."); len(parts) > 3 {
+		v = strings.Join(parts[:3], ".")
+	}
+
+	parsed, err := semver.NewVersion(v)
+	if err != nil {
+		return nil, fmt.Errorf("invalid java version %q: %w", s, err)
+	}
+	return parsed, nil
+}
+
+func firstLine(b []byte) string {
+	line, _, _ := bytes.Cut(b, []byte("\n"))
+	return strings.TrimSpace(string(line))
+}
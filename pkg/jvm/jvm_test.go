@@ -0,0 +1,129 @@
+// Copyright (c) 2017-2026 Digital Asset (Switzerland) GmbH and/or its affiliates. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package jvm
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"daml.com/x/assistant/pkg/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseVersion(t *testing.T) {
+	for s, expected := range map[string]string{
+		"1.8.0_292":  "8.0.292",
+		"11.0.2-ea":  "11.0.2",
+		"17.0.2":     "17.0.2",
+		"17.0.2+8":   "17.0.2",
+		"21":         "21.0.0",
+		"21.0.4.0.1": "21.0.4",
+	} {
+		v, err := ParseVersion(s)
+		require.NoError(t, err, s)
+		assert.Equal(t, expected, v.String(), s)
+	}
+
+	_, err := ParseVersion("seventeen")
+	assert.Error(t, err)
+}
+
+// mkJdk creates a JDK at a temp dir, with a java that prints the given `java -version` output
+// and, unless releaseVersion is "", a release file
+func mkJdk(t *testing.T, versionOutput, releaseVersion string) string {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script java")
+	}
+	home := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(home, "bin"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(home, "bin", "java"), []byte("#!/bin/sh\necho '"+versionOutput+"' >&2\n"), 0o755))
+	if releaseVersion != "" {
+		require.NoError(t, os.WriteFile(filepath.Join(home, "release"), []byte("IMPLEMENTOR=\"Eclipse Adoptium\"\nJAVA_VERSION=\""+releaseVersion+"\"\n"), 0o644))
+	}
+	return home
+}
+
+func TestFind(t *testing.T) {
+	home := mkJdk(t, `openjdk version "17.0.2" 2022-01-18`, "")
+
+	java, err := Find(home)
+	require.NoError(t, err)
+	assert.Equal(t, &Java{Path: filepath.Join(home, "bin", "java"), Home: home}, java)
+
+	_, err = Find(t.TempDir())
+	assert.ErrorIs(t, err, ErrNoCompatibleJvm)
+
+	t.Setenv("PATH", filepath.Join(home, "bin"))
+	java, err = Find("")
+	require.NoError(t, err)
+	assert.Equal(t, &Java{Path: filepath.Join(home, "bin", "java")}, java)
+
+	t.Setenv("PATH", t.TempDir())
+	_, err = Find("")
+	assert.ErrorIs(t, err, ErrNoCompatibleJvm)
+}
+
+func TestRequire(t *testing.T) {
+	ctx := testutil.Context(t)
+
+	t.Run("from java -version", func(t *testing.T) {
+		java, err := Find(mkJdk(t, `openjdk version "17.0.2" 2022-01-18`, ""))
+		require.NoError(t, err)
+		assert.NoError(t, java.Require(ctx, ">=17, <22"))
+		err = java.Require(ctx, ">=21")
+		assert.ErrorIs(t, err, ErrNoCompatibleJvm)
+		assert.ErrorContains(t, err, `is version 17.0.2, which doesn't satisfy ">=21"`)
+	})
+
+	t.Run("from the release file", func(t *testing.T) {
+		java, err := Find(mkJdk(t, "not a java", "21.0.4"))
+		require.NoError(t, err)
+		assert.NoError(t, java.Require(ctx, ">=21"))
+	})
+
+	t.Run("legacy", func(t *testing.T) {
+		java, err := Find(mkJdk(t, `java version "1.8.0_292"`, ""))
+		require.NoError(t, err)
+		assert.ErrorIs(t, java.Require(ctx, ">=11"), ErrNoCompatibleJvm)
+	})
+
+	t.Run("not a java", func(t *testing.T) {
+		java, err := Find(mkJdk(t, "i am a fake java!", ""))
+		require.NoError(t, err)
+		err = java.Require(ctx, ">=11")
+		assert.ErrorIs(t, err, ErrNoCompatibleJvm)
+		assert.ErrorContains(t, err, "i am a fake java!")
+	})
+}
+
+func TestVersionIsMemoized(t *testing.T) {
+	ctx := testutil.Context(t)
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script java")
+	}
+	home := t.TempDir()
+	calls := filepath.Join(home, "calls")
+	require.NoError(t, os.MkdirAll(filepath.Join(home, "bin"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(home, "bin", "java"), []byte("#!/bin/sh\necho >> '"+calls+"'\necho 'openjdk version \"17.0.2\"' >&2\n"), 0o755))
+
+	java, err := Find(home)
+	require.NoError(t, err)
+	for range 2 {
+		v, err := java.Version(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "17.0.2", v.String())
+	}
+	b, err := os.ReadFile(calls)
+	require.NoError(t, err)
+	assert.Equal(t, "\n", string(b), "java is only asked once")
+
+	// but a JDK that changed is looked at again
+	require.NoError(t, os.WriteFile(filepath.Join(home, "release"), []byte(`JAVA_VERSION="21.0.4"`+"\n"), 0o644))
+	v, err := java.Version(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "21.0.4", v.String())
+}
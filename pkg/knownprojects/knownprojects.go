@@ -0,0 +1,109 @@
+// Copyright (c) 2017-2026 Digital Asset (Switzerland) GmbH and/or its affiliates. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package knownprojects keeps track of the (single or multi-package) project directories
+// dpm has installed things for, so that the cache garbage collector can tell which
+// cached components and dars are still in use by a project that isn't the current one.
+package knownprojects
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"daml.com/x/assistant/pkg/utils"
+)
+
+const KnownProjectsSchema = "known-projects/v1"
+
+type KnownProjects struct {
+	AbsolutePath string
+}
+
+type KnownProjectsContents struct {
+	Schema   string   `json:"schema"`
+	Projects []string `json:"projects"`
+}
+
+// Add remembers the given project directories (i.e. the dirs containing a daml.yaml or multi-package.yaml)
+func (k *KnownProjects) Add(projectDirs ...string) error {
+	contents, err := k.read()
+	if err != nil {
+		return err
+	}
+
+	changed := false
+	for _, d := range projectDirs {
+		abs, err := filepath.Abs(d)
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(contents.Projects, abs) {
+			contents.Projects = append(contents.Projects, abs)
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	slices.Sort(contents.Projects)
+	return k.write(contents)
+}
+
+// List returns the remembered project directories
+func (k *KnownProjects) List() ([]string, error) {
+	contents, err := k.read()
+	if err != nil {
+		return nil, err
+	}
+	return contents.Projects, nil
+}
+
+// Forget drops the given project directories
+func (k *KnownProjects) Forget(projectDirs ...string) error {
+	contents, err := k.read()
+	if err != nil {
+		return err
+	}
+
+	n := len(contents.Projects)
+	contents.Projects = slices.DeleteFunc(contents.Projects, func(p string) bool {
+		return slices.Contains(projectDirs, p)
+	})
+	if len(contents.Projects) == n {
+		return nil
+	}
+	return k.write(contents)
+}
+
+// read returns empty contents if the file doesn't exist yet
+func (k *KnownProjects) read() (*KnownProjectsContents, error) {
+	contents := &KnownProjectsContents{Schema: KnownProjectsSchema}
+
+	b, err := os.ReadFile(k.AbsolutePath)
+	if os.IsNotExist(err) {
+		return contents, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(b, contents); err != nil {
+		return nil, err
+	}
+	return contents, nil
+}
+
+func (k *KnownProjects) write(contents *KnownProjectsContents) error {
+	if err := utils.EnsureDirs(filepath.Dir(k.AbsolutePath)); err != nil {
+		return err
+	}
+
+	contents.Schema = KnownProjectsSchema
+	b, err := json.Marshal(contents)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(k.AbsolutePath, b, 0o644)
+}
@@ -10,6 +10,7 @@ import (
 	"path/filepath"
 
 	"daml.com/x/assistant/pkg/componentlist"
+	"daml.com/x/assistant/pkg/hooks"
 	"daml.com/x/assistant/pkg/sdkmanifest"
 	"daml.com/x/assistant/pkg/utils"
 	"daml.com/x/assistant/pkg/yamledit"
@@ -27,6 +28,9 @@ type MultiPackage struct {
 
 	// deprecated in favor of Components
 	DeprecatedOverrideComponents map[string]*sdkmanifest.Component `yaml:"override-components,omitempty"`
+
+	// shell commands to run before and after SDK commands, in any of the packages
+	Hooks hooks.Hooks `yaml:"hooks,omitempty"`
 }
 
 func (m *MultiPackage) AbsolutePackages() []string {
@@ -1,8 +1,9 @@
 // Copyright (c) 2017-2026 Digital Asset (Switzerland) GmbH and/or its affiliates. All rights reserved.
 // SPDX-License-Identifier: Apache-2.0
 
-// copied as is from:
+// copied from:
 // https://github.com/oras-project/oras/blob/ae989e834228c87ebb795643d61da983b1d47a1b/internal/cache/target.go
+// with progress reporting added to cacheReadCloser, and resumption of interrupted downloads
 package cache
 
 import (
@@ -10,6 +11,7 @@ import (
 	"io"
 	"sync"
 
+	"daml.com/x/assistant/pkg/progress"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"oras.land/oras-go/v2"
 	"oras.land/oras-go/v2/content"
@@ -55,8 +57,8 @@ func (t *target) Fetch(ctx context.Context, target ocispec.Descriptor) (io.ReadC
 		return nil, err
 	}
 
-	// Fetch from origin with caching
-	return t.cacheReadCloser(ctx, rc, target), nil
+	// Fetch from origin with caching, resuming the download should it be interrupted
+	return t.cacheReadCloser(ctx, resumable(ctx, rc, target), target), nil
 }
 
 func (t *target) cacheReadCloser(ctx context.Context, rc io.ReadCloser, target ocispec.Descriptor) io.ReadCloser {
@@ -77,7 +79,7 @@ func (t *target) cacheReadCloser(ctx context.Context, rc io.ReadCloser, target o
 		io.Reader
 		io.Closer
 	}{
-		Reader: io.TeeReader(rc, pw),
+		Reader: io.TeeReader(progress.Default().Reader(progress.OperationPull, target, rc), pw),
 		Closer: closer(func() error {
 			rcErr := rc.Close()
 			if err := pw.Close(); err != nil {
@@ -139,5 +141,5 @@ func (t *referenceTarget) FetchReference(ctx context.Context, reference string)
 	}
 
 	// Fetch from origin with caching
-	return target, t.cacheReadCloser(ctx, rc, target), nil
+	return target, t.cacheReadCloser(ctx, resumable(ctx, rc, target), target), nil
 }
@@ -0,0 +1,74 @@
+// Copyright (c) 2017-2026 Digital Asset (Switzerland) GmbH and/or its affiliates. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// maxResumes bounds how many times a single blob download is resumed after being interrupted
+const maxResumes = 5
+
+// resumingReader picks up an interrupted blob download where it left off, via an HTTP range request.
+// oras' remote blob readers are only seekable when the registry advertises range support (Accept-Ranges: bytes),
+// otherwise the read error is returned as is
+type resumingReader struct {
+	ctx    context.Context
+	rc     io.ReadCloser
+	seeker io.Seeker
+	desc   ocispec.Descriptor
+
+	offset  int64
+	resumes int
+}
+
+func resumable(ctx context.Context, rc io.ReadCloser, desc ocispec.Descriptor) io.ReadCloser {
+	seeker, ok := rc.(io.Seeker)
+	if !ok {
+		return rc
+	}
+	return &resumingReader{ctx: ctx, rc: rc, seeker: seeker, desc: desc}
+}
+
+func (r *resumingReader) Read(p []byte) (int, error) {
+	n, err := r.rc.Read(p)
+	r.offset += int64(n)
+	if err == nil || errors.Is(err, io.EOF) {
+		return n, err
+	}
+	if r.resumes >= maxResumes || r.offset >= r.desc.Size || r.ctx.Err() != nil {
+		return n, err
+	}
+
+	r.resumes++
+	slog.Debug("blob download interrupted, resuming",
+		"digest", r.desc.Digest.String(), "offset", r.offset, "size", r.desc.Size, "attempt", r.resumes, "err", err.Error())
+	if resumeErr := r.resume(); resumeErr != nil {
+		slog.Debug("failed to resume blob download", "digest", r.desc.Digest.String(), "err", resumeErr.Error())
+		return n, err
+	}
+	if n > 0 {
+		return n, nil
+	}
+	return r.Read(p)
+}
+
+// resume re-requests the blob from the current offset.
+// Seeking to the reader's current offset is a no-op, so seek to the end first to drop the broken response body
+func (r *resumingReader) resume() error {
+	if _, err := r.seeker.Seek(0, io.SeekEnd); err != nil {
+		return err
+	}
+	_, err := r.seeker.Seek(r.offset, io.SeekStart)
+	return err
+}
+
+func (r *resumingReader) Close() error {
+	return r.rc.Close()
+}
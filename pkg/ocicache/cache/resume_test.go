@@ -0,0 +1,86 @@
+// Copyright (c) 2017-2026 Digital Asset (Switzerland) GmbH and/or its affiliates. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package cache
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"oras.land/oras-go/v2/content/memory"
+	"oras.land/oras-go/v2/registry/remote"
+)
+
+// TestFetchResumesInterruptedDownload has the registry drop the connection halfway through the blob download,
+// and checks that the rest of the blob is fetched with a range request
+func TestFetchResumesInterruptedDownload(t *testing.T) {
+	blob := []byte(strings.Repeat("0123456789", 10_000))
+	desc := ocispec.Descriptor{
+		MediaType: "application/octet-stream",
+		Digest:    digest.FromBytes(blob),
+		Size:      int64(len(blob)),
+	}
+
+	var requests, rangeRequests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/blobs/"+desc.Digest.String()) {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Accept-Ranges", "bytes")
+
+		if rng := r.Header.Get("Range"); rng != "" {
+			rangeRequests.Add(1)
+			var start, end int
+			_, err := fmt.Sscanf(rng, "bytes=%d-%d", &start, &end)
+			assert.NoError(t, err)
+			w.Header().Set("Content-Length", strconv.Itoa(end-start+1))
+			w.WriteHeader(http.StatusPartialContent)
+			_, _ = w.Write(blob[start : end+1])
+			return
+		}
+
+		requests.Add(1)
+		// claim the full length, but hang up halfway through
+		w.Header().Set("Content-Length", strconv.Itoa(len(blob)))
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(blob[:len(blob)/2])
+		w.(http.Flusher).Flush()
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if assert.NoError(t, err) {
+			_ = conn.Close()
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	repo, err := remote.NewRepository(server.Listener.Addr().String() + "/test")
+	require.NoError(t, err)
+	repo.PlainHTTP = true
+
+	store := memory.New()
+	rc, err := New(repo, store).Fetch(context.Background(), desc)
+	require.NoError(t, err)
+	got, err := io.ReadAll(rc)
+	require.NoError(t, err)
+	require.NoError(t, rc.Close())
+
+	assert.True(t, bytes.Equal(blob, got))
+	assert.EqualValues(t, 1, requests.Load())
+	assert.EqualValues(t, 1, rangeRequests.Load())
+
+	exists, err := store.Exists(context.Background(), desc)
+	require.NoError(t, err)
+	assert.True(t, exists, "resumed blob should be cached")
+}
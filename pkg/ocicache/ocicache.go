@@ -4,15 +4,39 @@
 package ocicache
 
 import (
+	"sync"
+
 	"daml.com/x/assistant/pkg/ocicache/cache"
 	"oras.land/oras-go/v2"
 	"oras.land/oras-go/v2/content/oci"
 )
 
+var (
+	storesMu sync.Mutex
+	// one store per oci-layout dir, so that concurrent pulls don't clobber each other's index.json
+	stores = map[string]*oci.Store{}
+)
+
 func CachedTarget(src oras.ReadOnlyTarget, ociLayoutCache string) (oras.ReadOnlyTarget, error) {
-	ociStore, err := oci.New(ociLayoutCache)
+	ociStore, err := Store(ociLayoutCache)
 	if err != nil {
 		return nil, err
 	}
 	return cache.New(src, ociStore), nil
 }
+
+// Store returns the (shared) oci-layout store at the given path
+func Store(ociLayoutCache string) (*oci.Store, error) {
+	storesMu.Lock()
+	defer storesMu.Unlock()
+
+	if s, ok := stores[ociLayoutCache]; ok {
+		return s, nil
+	}
+	s, err := oci.New(ociLayoutCache)
+	if err != nil {
+		return nil, err
+	}
+	stores[ociLayoutCache] = s
+	return s, nil
+}
@@ -80,7 +80,7 @@ func listTags(ctx context.Context, repoName string, client *assistantremote.Remo
 		}
 
 		if IsFloaty(tag) {
-			version, err := ociindex.ResolveTag(ctx, client, &ociconsts.SdkManifestArtifact{repoName}, tag)
+			version, err := ociindex.ResolveTag(ctx, client, &ociconsts.SdkManifestArtifact{SdkManifestsRepo: repoName}, tag)
 			if err != nil {
 				slog.Warn("failed to resolve floaty tag to semver",
 					slog.String("repo", repoName),
@@ -0,0 +1,99 @@
+// Copyright (c) 2017-2026 Digital Asset (Switzerland) GmbH and/or its affiliates. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package ocilister
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"daml.com/x/assistant/pkg/assistantconfig/assistantremote"
+	"github.com/Masterminds/semver/v3"
+)
+
+var (
+	// the OCI distribution spec's tag grammar
+	tagRegex = regexp.MustCompile(`^[a-zA-Z0-9_][a-zA-Z0-9._-]{0,127}$`)
+	// npm-style wildcard ranges (e.g. 1.x or 1.2.x), which are valid tags too but are taken as ranges
+	wildcardRegex = regexp.MustCompile(`^\d+(\.(\d+|[xX]))*\.[xX]$`)
+)
+
+// IsRange whether tag is a semver range (e.g. "^1.4", "~2.3.1", ">=1.0 <2.0" or "1.x") rather than an OCI tag
+func IsRange(tag string) bool {
+	if tag == "" || (tagRegex.MatchString(tag) && !wildcardRegex.MatchString(tag)) {
+		return false
+	}
+	_, err := semver.NewConstraint(tag)
+	return err == nil
+}
+
+// SplitRange splits the semver range off "[oci://]<registry>/<repo>:<range>[@<digest>]",
+// returning "[oci://]<registry>/<repo>[@<digest>]" and the range, or false if the reference's tag isn't a range
+func SplitRange(ref string) (withoutRange, constraint string, ok bool) {
+	namePart, digestPart, hasDigest := strings.Cut(ref, "@")
+	lastSlash := strings.LastIndexByte(namePart, '/')
+	lastColon := strings.LastIndexByte(namePart, ':')
+	if lastColon <= lastSlash || !IsRange(namePart[lastColon+1:]) {
+		return ref, "", false
+	}
+
+	withoutRange = namePart[:lastColon]
+	if hasDigest {
+		withoutRange += "@" + digestPart
+	}
+	return withoutRange, namePart[lastColon+1:], true
+}
+
+// ResolveRange returns the highest version tagged in repo that satisfies the range, along with its tag.
+// Pre-releases only match ranges that mention a pre-release themselves
+func ResolveRange(ctx context.Context, client *assistantremote.Remote, repo, constraint string) (*semver.Version, string, error) {
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid semver range %q: %w", constraint, err)
+	}
+	tags, _, err := ListTags(ctx, client, repo)
+	if err != nil {
+		return nil, "", err
+	}
+
+	v, tag, ok := HighestMatch(tags, c)
+	if !ok {
+		return nil, "", fmt.Errorf("no version of %s/%s satisfies %q", client.Registry, repo, constraint)
+	}
+	return v, tag, nil
+}
+
+// LatestRange moves a semver range up to the latest (non pre-release) version in repo.
+// Caret and tilde ranges keep their operator (e.g. ^1.4 -> ^2.1.0), anything else becomes a caret range
+func LatestRange(ctx context.Context, client *assistantremote.Remote, repo, semverRange string) (string, error) {
+	latest, _, err := ResolveRange(ctx, client, repo, ">=0.0.0")
+	if err != nil {
+		return "", err
+	}
+	op := "^"
+	if strings.HasPrefix(semverRange, "~") && !strings.ContainsAny(semverRange[1:], " ,|<>=~^") {
+		op = "~"
+	}
+	return op + latest.String(), nil
+}
+
+// HighestMatch returns the highest (strict semver) tag satisfying c
+func HighestMatch(tags []string, c *semver.Constraints) (*semver.Version, string, bool) {
+	var candidates []*semver.Version
+	for _, tag := range tags {
+		if IsPlatformTag(tag) {
+			continue
+		}
+		if v, err := semver.StrictNewVersion(tag); err == nil && c.Check(v) {
+			candidates = append(candidates, v)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, "", false
+	}
+	v := slices.MaxFunc(candidates, Cmp)
+	return v, v.Original(), true
+}
@@ -0,0 +1,91 @@
+// Copyright (c) 2017-2026 Digital Asset (Switzerland) GmbH and/or its affiliates. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package ocilister
+
+import (
+	"testing"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsRange(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want bool
+	}{
+		{"^1.4", true},
+		{"~2.3.1", true},
+		{">=1.0 <2.0", true},
+		{">=1.0, <2.0", true},
+		{"1.x", true},
+		{"1.2.x", true},
+		{"1.X.x", true},
+
+		{"", false},
+		{"1.4.0", false},
+		{"1.4", false},
+		{"latest", false},
+		{"x", false},
+		{"1.x-beta", false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.tag, func(t *testing.T) {
+			assert.Equal(t, tc.want, IsRange(tc.tag))
+		})
+	}
+}
+
+func TestSplitRange(t *testing.T) {
+	tests := []struct {
+		ref          string
+		withoutRange string
+		constraint   string
+		ok           bool
+	}{
+		{"oci://example.com/foo:^1.4", "oci://example.com/foo", "^1.4", true},
+		{"example.com/foo:>=1.0 <2.0", "example.com/foo", ">=1.0 <2.0", true},
+		{"oci://localhost:5000/foo:~2.3.1@sha256:abcd", "oci://localhost:5000/foo@sha256:abcd", "~2.3.1", true},
+		{"oci://example.com/foo:1.2.x", "oci://example.com/foo", "1.2.x", true},
+
+		{"oci://example.com/foo:1.4.0", "oci://example.com/foo:1.4.0", "", false},
+		{"oci://localhost:5000/foo", "oci://localhost:5000/foo", "", false},
+		{"oci://example.com/foo:latest@sha256:abcd", "oci://example.com/foo:latest@sha256:abcd", "", false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.ref, func(t *testing.T) {
+			withoutRange, constraint, ok := SplitRange(tc.ref)
+			assert.Equal(t, tc.withoutRange, withoutRange)
+			assert.Equal(t, tc.constraint, constraint)
+			assert.Equal(t, tc.ok, ok)
+		})
+	}
+}
+
+func TestHighestMatch(t *testing.T) {
+	tags := []string{"1.3.9", "1.4.0", "1.4.2", "1.5.0-rc1", "1.6.0.darwin_amd64", "2.0.0", "latest", "1.7"}
+
+	tests := []struct {
+		constraint string
+		want       string
+		ok         bool
+	}{
+		{"^1.4", "1.4.2", true},
+		{"~1.3", "1.3.9", true},
+		{">=1.0 <2.0", "1.4.2", true},
+		{">=1.5.0-rc0 <2.0", "1.5.0-rc1", true},
+		{"^2", "2.0.0", true},
+		{"^3", "", false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.constraint, func(t *testing.T) {
+			c, err := semver.NewConstraint(tc.constraint)
+			require.NoError(t, err)
+			_, tag, ok := HighestMatch(tags, c)
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.want, tag)
+		})
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"daml.com/x/assistant/pkg/ocicache"
 	"daml.com/x/assistant/pkg/ociindex"
 	"daml.com/x/assistant/pkg/ocipuller"
+	"daml.com/x/assistant/pkg/progress"
 	"daml.com/x/assistant/pkg/sdkmanifest"
 	"daml.com/x/assistant/pkg/simpleplatform"
 	"fmt"
@@ -74,7 +75,7 @@ func (a *RemoteOciPuller) pull(ctx context.Context, repo, reference, destPath st
 	dest.PreservePermissions = true
 	// errors out if dest already exists
 	dest.DisableOverwrite = true
-	opts := ocipuller.ApplyFileInfoCopyOptions(destPath)
+	opts := progress.Default().CopyOptions(progress.OperationPull, ocipuller.ApplyFileInfoCopyOptions(destPath))
 	if nonGeneric, ok := platform.(*simpleplatform.NonGeneric); ok {
 		index, _, err := ociindex.FetchIndex(ctx, a.remote, repo, reference)
 		if err != nil {
@@ -32,10 +32,11 @@ import (
 )
 
 type DarOpts struct {
-	Artifact    oci.Artifact
-	Version     semver.Version
-	Dars        []string
-	LicenseFile string
+	Artifact     oci.Artifact
+	Version      semver.Version
+	Dars         []string
+	Dependencies []string
+	LicenseFile  string
 }
 
 type DarPushOperation struct {
@@ -66,7 +67,8 @@ func DarNew(ctx context.Context, opts DarOpts) (*DarPushOperation, error) {
 			Kind:       darmanifest.DarKind,
 		},
 		Spec: &darmanifest.Spec{
-			Dars: []darmanifest.Dar{},
+			Dars:         []darmanifest.Dar{},
+			Dependencies: opts.Dependencies,
 		},
 	}
 
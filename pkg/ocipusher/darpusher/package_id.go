@@ -24,6 +24,25 @@ func GetMainPackageId(darPath string) (string, error) {
 	return hash, nil
 }
 
+// GetMainPackageName unpacks a dar and extracts the name of its main package, including the version (e.g. "foo-1.0.0")
+func GetMainPackageName(darPath string) (string, error) {
+	manifest, err := readDar(darPath)
+	if err != nil {
+		return "", err
+	}
+
+	m := regexp.MustCompile(`(?m)^Name:\s*(\S+)\s*$`).FindStringSubmatch(unfold(manifest))
+	if m == nil {
+		return "", fmt.Errorf("could not extract Name from the dar's manifest")
+	}
+	return m[1], nil
+}
+
+// unfold joins the continuation lines (starting with a single space) of a jar manifest
+func unfold(manifest string) string {
+	return regexp.MustCompile(`\r?\n `).ReplaceAllString(manifest, "")
+}
+
 // readDar extracts the manifest out of a dar
 func readDar(darPath string) (string, error) {
 	reader, err := zip.OpenReader(darPath)
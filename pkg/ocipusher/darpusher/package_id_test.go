@@ -13,3 +13,10 @@ func TestDar(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, "0984ff5e3082add400bfcc6e3244bf9822ca5a617cfd92429e3fbce58058dbfa", hash)
 }
+
+func TestDarName(t *testing.T) {
+	darPath := testutil.TestdataPath(t, "test-dar", "test.dar")
+	name, err := GetMainPackageName(darPath)
+	require.NoError(t, err)
+	require.Equal(t, "meep-main-0.0.1", name)
+}
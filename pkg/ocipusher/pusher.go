@@ -13,6 +13,7 @@ import (
 
 	"daml.com/x/assistant/pkg/assistantconfig/assistantremote"
 	"daml.com/x/assistant/pkg/oci"
+	"daml.com/x/assistant/pkg/progress"
 	"daml.com/x/assistant/pkg/simpleplatform"
 	"daml.com/x/assistant/pkg/utils/fileinfo"
 	v1 "github.com/opencontainers/image-spec/specs-go/v1"
@@ -56,7 +57,8 @@ func (op *PushOperation) Do(ctx context.Context, client *assistantremote.Remote)
 	repo.Client = client
 	repo.PlainHTTP = client.Insecure
 
-	d, err := oras.Copy(ctx, op.fs, op.Tag(), repo, op.Tag(), oras.DefaultCopyOptions)
+	reporter := progress.Default()
+	d, err := oras.Copy(ctx, reporter.Target(progress.OperationPush, op.fs), op.Tag(), repo, op.Tag(), reporter.CopyOptions(progress.OperationPush, oras.DefaultCopyOptions))
 	if err != nil {
 		return nil, err
 	}
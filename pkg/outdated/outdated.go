@@ -0,0 +1,377 @@
+// Copyright (c) 2017-2026 Digital Asset (Switzerland) GmbH and/or its affiliates. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package outdated
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"daml.com/x/assistant/pkg/assistantconfig"
+	"daml.com/x/assistant/pkg/assistantconfig/assistantremote"
+	"daml.com/x/assistant/pkg/damlpackage"
+	"daml.com/x/assistant/pkg/multipackage"
+	ociconsts "daml.com/x/assistant/pkg/oci"
+	"daml.com/x/assistant/pkg/ocilister"
+	"daml.com/x/assistant/pkg/packagelock"
+	"daml.com/x/assistant/pkg/sdkmanifest"
+	"github.com/Masterminds/semver/v3"
+	"github.com/samber/lo"
+	"oras.land/oras-go/v2/registry"
+)
+
+type Kind string
+
+const (
+	KindSdk       Kind = "sdk"
+	KindComponent Kind = "component"
+	KindDar       Kind = "dar"
+)
+
+type Entry struct {
+	// the daml.yaml or multi-package.yaml declaring the dependency
+	Source string `json:"source"`
+	Kind   Kind   `json:"kind"`
+	Name   string `json:"name"`
+	// the version or tag as declared
+	Constraint string `json:"constraint"`
+	// the locked (or else declared) version, "" if it can't be determined
+	Current string `json:"current"`
+	// the newest version matching the constraint
+	Wanted string `json:"wanted"`
+	// the newest version overall
+	Latest string `json:"latest"`
+}
+
+// Outdated whether a newer version than the current one is available
+func (e *Entry) Outdated() bool {
+	current, err := semver.NewVersion(e.Current)
+	if err != nil {
+		return false
+	}
+	for _, v := range []string{e.Wanted, e.Latest} {
+		if newer, err := semver.NewVersion(v); err == nil && newer.GreaterThan(current) {
+			return true
+		}
+	}
+	return false
+}
+
+type Checker struct {
+	config *assistantconfig.Config
+	// "<registry>/<repo>" -> available versions, so that each repo is listed only once
+	versions map[string][]*semver.Version
+}
+
+func New(config *assistantconfig.Config) *Checker {
+	return &Checker{config: config, versions: map[string][]*semver.Version{}}
+}
+
+// Check reports on the sdk-version, components and dars of the project in scope:
+// the multi-package and all its packages, or else the single package
+func (c *Checker) Check(ctx context.Context) ([]*Entry, error) {
+	var entries []*Entry
+
+	multiPackagePath, isMultiPackage, err := assistantconfig.GetMultiPackageAbsolutePath()
+	if err != nil {
+		return nil, err
+	}
+	var packages []string
+	if isMultiPackage {
+		m, err := multipackage.Read(multiPackagePath)
+		if err != nil {
+			return nil, err
+		}
+		lock := packagelock.ReadPackageLockIfExists(filepath.Join(filepath.Dir(multiPackagePath), assistantconfig.DpmMultiPackageLockFileName))
+		es, err := c.checkSdkAndComponents(ctx, multiPackagePath, m.SdkVersion, m.Components, lock)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, es...)
+		packages = lo.Map(m.AbsolutePackages(), func(p string, _ int) string {
+			return filepath.Join(p, assistantconfig.DamlPackageFilename)
+		})
+	} else {
+		damlPackagePath, ok, err := assistantconfig.GetDamlPackageAbsolutePath()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("not in a (single-package or multi-package) project directory")
+		}
+		packages = []string{damlPackagePath}
+	}
+
+	for _, p := range packages {
+		es, err := c.checkPackage(ctx, p)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, es...)
+	}
+	return entries, nil
+}
+
+func (c *Checker) checkPackage(ctx context.Context, damlPackagePath string) ([]*Entry, error) {
+	p, err := damlpackage.Read(damlPackagePath)
+	if err != nil {
+		return nil, err
+	}
+	lock := packagelock.ReadPackageLockIfExists(filepath.Join(filepath.Dir(damlPackagePath), assistantconfig.DpmLockFileName))
+
+	entries, err := c.checkSdkAndComponents(ctx, damlPackagePath, p.SdkVersion, p.Components, lock)
+	if err != nil {
+		return nil, err
+	}
+
+	deps := slices.Concat(lo.Values(p.ParsedDarDependencies.Dependencies), lo.Values(p.ParsedDarDependencies.DataDependencies))
+	slices.SortFunc(deps, func(a, b *damlpackage.ParsedDarDependency) int { return a.Index - b.Index })
+	for _, dep := range deps {
+		if dep.FullUrl == nil || dep.FullUrl.Scheme != "oci" {
+			continue
+		}
+		client, ref, err := dep.GetOciRemote()
+		if err != nil {
+			return nil, err
+		}
+		tag := dep.Range
+		if tag == "" {
+			tag = tagOf(dep.FullUrl.String())
+		}
+		name, _, _ := strings.Cut(dep.StringWithAlias(), "@sha256:")
+		e, err := c.check(ctx, componentVersions(client, ref.Repository), &Entry{
+			Source:     damlPackagePath,
+			Kind:       KindDar,
+			Name:       strings.TrimSuffix(name, ":"+tag),
+			Constraint: tag,
+			Current:    lockedDarVersion(lock, ref),
+		})
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+func (c *Checker) checkSdkAndComponents(ctx context.Context, source, sdkVersion string, comps map[string]*sdkmanifest.Component, lock *packagelock.PackageLock) ([]*Entry, error) {
+	var entries []*Entry
+
+	if sdkVersion != "" {
+		client, err := assistantremote.NewFromConfig(c.config)
+		if err != nil {
+			return nil, err
+		}
+		edition, err := c.config.Edition.Get()
+		if err != nil {
+			return nil, err
+		}
+		current := sdkVersion
+		if lock != nil && ocilister.IsFloaty(sdkVersion) {
+			current = lock.SdkVersion.Version
+		}
+		repo, err := edition.SdkManifestsRepo()
+		if err != nil {
+			return nil, err
+		}
+		list := lister{
+			repo: client.Registry + "/" + repo,
+			list: func(ctx context.Context) (map[*semver.Version][]string, error) {
+				return ocilister.ListSDKVersions(ctx, edition, client)
+			},
+		}
+		e, err := c.check(ctx, list, &Entry{
+			Source:     source,
+			Kind:       KindSdk,
+			Name:       "sdk-version",
+			Constraint: sdkVersion,
+			Current:    current,
+		})
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+
+	names := lo.Keys(comps)
+	slices.Sort(names)
+	for _, name := range names {
+		comp := comps[name]
+		var client *assistantremote.Remote
+		var repo, constraint string
+		var err error
+		switch {
+		case comp.Version != nil:
+			client, err = assistantremote.NewFromConfig(c.config)
+			repo = ociconsts.ComponentRepoPrefix + comp.Name
+			constraint = comp.Version.Value().String()
+		case comp.Range != "":
+			client, err = assistantremote.NewFromConfig(c.config)
+			repo = ociconsts.ComponentRepoPrefix + comp.Name
+			constraint = comp.Range
+		case comp.Uri != nil:
+			uri, semverRange, isRange := ocilister.SplitRange(*comp.Uri)
+			var ref registry.Reference
+			ref, err = registry.ParseReference(strings.TrimPrefix(uri, "oci://"))
+			if err != nil {
+				return nil, fmt.Errorf("invalid uri for component %q: %w", comp.Name, err)
+			}
+			client, err = assistantremote.New(ref.Registry, c.config.RegistryAuthPath, c.config.Insecure)
+			repo = ref.Repository
+			constraint = tagOf(*comp.Uri)
+			if isRange {
+				constraint = semverRange
+			}
+		default:
+			// local-path and git components aren't versioned
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := c.check(ctx, componentVersions(client, repo), &Entry{
+			Source:     source,
+			Kind:       KindComponent,
+			Name:       comp.Name,
+			Constraint: constraint,
+			Current:    c.currentComponentVersion(ctx, comp, constraint, lock),
+		})
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// lister lists the versions available in a repo
+type lister struct {
+	// "<registry>/<repo>"
+	repo string
+	list func(ctx context.Context) (map[*semver.Version][]string, error)
+}
+
+func componentVersions(client *assistantremote.Remote, repo string) lister {
+	return lister{
+		repo: client.Registry + "/" + repo,
+		list: func(ctx context.Context) (map[*semver.Version][]string, error) {
+			return ocilister.ListComponentVersions(ctx, repo, client)
+		},
+	}
+}
+
+// check fills in the wanted and latest versions of e, from the versions available in its repo
+func (c *Checker) check(ctx context.Context, l lister, e *Entry) (*Entry, error) {
+	versions, ok := c.versions[l.repo]
+	if !ok {
+		m, err := l.list(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the versions of %s: %w", l.repo, err)
+		}
+		versions = lo.Filter(lo.Keys(m), func(v *semver.Version, _ int) bool { return v != nil })
+		slices.SortFunc(versions, ocilister.Cmp)
+		c.versions[l.repo] = versions
+	}
+
+	current, _ := semver.NewVersion(e.Current)
+	if latest, ok := newest(versions, current, func(*semver.Version) bool { return true }); ok {
+		e.Latest = latest.String()
+	}
+	if wanted, ok := newest(versions, current, matches(e.Constraint)); ok {
+		e.Wanted = wanted.String()
+	}
+	return e, nil
+}
+
+// matches returns whether a version satisfies the constraint: an exact version only matches itself,
+// a semver range the versions within it, while a floaty tag (e.g. latest) matches any version
+func matches(constraint string) func(*semver.Version) bool {
+	if exact, err := semver.StrictNewVersion(constraint); err == nil {
+		return exact.Equal
+	}
+	if ocilister.IsRange(constraint) {
+		if c, err := semver.NewConstraint(constraint); err == nil {
+			return c.Check
+		}
+	}
+	return func(*semver.Version) bool { return true }
+}
+
+// newest returns the highest of versions satisfying ok.
+// Pre-releases are only considered if the current version is one
+func newest(versions []*semver.Version, current *semver.Version, ok func(*semver.Version) bool) (*semver.Version, bool) {
+	allowPrerelease := current != nil && current.Prerelease() != ""
+	for _, v := range slices.Backward(versions) {
+		if v.Prerelease() != "" && !allowPrerelease {
+			continue
+		}
+		if ok(v) {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// currentComponentVersion is the declared version, or else the one the locked (or pinned) digest was installed as
+func (c *Checker) currentComponentVersion(ctx context.Context, comp *sdkmanifest.Component, constraint string, lock *packagelock.PackageLock) string {
+	if !ocilister.IsFloaty(constraint) {
+		return constraint
+	}
+
+	var d string
+	if comp.Uri != nil {
+		_, d, _ = strings.Cut(*comp.Uri, "@")
+	}
+	if lock != nil && d == "" {
+		if locked, ok := lock.FindComponent(comp.Name); ok {
+			d = locked.Digest
+		}
+	}
+	if !strings.HasPrefix(d, "sha256:") {
+		return ""
+	}
+	_, version, ok, err := c.config.CacheIndex.Get(ctx, d)
+	if err != nil || !ok {
+		return ""
+	}
+	return version
+}
+
+// lockedDarVersion is the version the dar from ref is locked at, or else its declared version
+func lockedDarVersion(lock *packagelock.PackageLock, ref *registry.Reference) string {
+	if lock != nil {
+		for _, d := range lock.Dars {
+			if d.URI == nil || d.URI.Scheme != "oci" {
+				continue
+			}
+			locked, err := registry.ParseReference(strings.TrimPrefix(d.URI.String(), "oci://"))
+			if err == nil && locked.Registry == ref.Registry && locked.Repository == ref.Repository {
+				return locked.Reference
+			}
+		}
+	}
+	if tag := ref.Reference; !ocilister.IsFloaty(tag) {
+		return tag
+	}
+	return ""
+}
+
+// tagOf returns the tag of an oci:// URI (ignoring any digest), or "" if there's none
+func tagOf(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return ""
+	}
+	namePart, _, _ := strings.Cut(u.Host+u.Path, "@")
+	lastSlash := strings.LastIndexByte(namePart, '/')
+	lastColon := strings.LastIndexByte(namePart, ':')
+	if lastColon > lastSlash && lastColon+1 < len(namePart) {
+		return namePart[lastColon+1:]
+	}
+	return ""
+}
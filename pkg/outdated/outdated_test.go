@@ -0,0 +1,65 @@
+// Copyright (c) 2017-2026 Digital Asset (Switzerland) GmbH and/or its affiliates. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package outdated
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"daml.com/x/assistant/pkg/testutil"
+	"github.com/Masterminds/semver/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheck(t *testing.T) {
+	_, reg := testutil.StartRegistry(t)
+	for _, v := range []string{"1.0.0", "1.1.0", "2.0.0", "3.0.0-rc1"} {
+		testutil.PushGenericComponentWithCommand(t, reg, "meep", v, "meep")
+	}
+	config := testutil.MkConfig(t)
+
+	testutil.ActivateDamlYamlForTest(t, fmt.Sprintf(`sdk-version:
+components:
+  - meep:1.1.0
+  - oci://%s/components/meep:latest
+`, strings.TrimPrefix(reg.URL, "http://")))
+
+	entries, err := New(config).Check(testutil.Context(t))
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	byVersion, byUri := entries[0], entries[1]
+	if byVersion.Constraint == "latest" {
+		byVersion, byUri = byUri, byVersion
+	}
+
+	assert.Equal(t, KindComponent, byVersion.Kind)
+	assert.Equal(t, "1.1.0", byVersion.Current)
+	assert.Equal(t, "1.1.0", byVersion.Wanted)
+	assert.Equal(t, "2.0.0", byVersion.Latest, "pre-releases are skipped")
+	assert.True(t, byVersion.Outdated())
+
+	assert.Equal(t, "latest", byUri.Constraint)
+	assert.Empty(t, byUri.Current, "never installed, so the current version is unknown")
+	assert.Equal(t, "2.0.0", byUri.Wanted)
+	assert.False(t, byUri.Outdated())
+}
+
+func TestTagOf(t *testing.T) {
+	assert.Equal(t, "1.2.3", tagOf("oci://example.com:5000/foo/bar:1.2.3"))
+	assert.Equal(t, "latest", tagOf("oci://example.com/foo/bar:latest@sha256:abcd"))
+	assert.Equal(t, "", tagOf("oci://example.com:5000/foo/bar"))
+}
+
+func TestMatches(t *testing.T) {
+	v := semver.MustParse
+	assert.True(t, matches("1.2.3")(v("1.2.3")))
+	assert.False(t, matches("1.2.3")(v("1.2.4")))
+	assert.True(t, matches("^1.2")(v("1.9.0")))
+	assert.False(t, matches("^1.2")(v("2.0.0")))
+	assert.True(t, matches(">=1.0 <2.0")(v("1.0.0")))
+	assert.True(t, matches("latest")(v("2.0.0")))
+}
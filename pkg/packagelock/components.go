@@ -0,0 +1,210 @@
+package packagelock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"daml.com/x/assistant/cmd/dpm/cmd/resolve/resolutionerrors"
+	"daml.com/x/assistant/pkg/assistantconfig"
+	"daml.com/x/assistant/pkg/assistantconfig/assistantremote"
+	"daml.com/x/assistant/pkg/gitsource"
+	ociconsts "daml.com/x/assistant/pkg/oci"
+	"daml.com/x/assistant/pkg/ocilister"
+	"daml.com/x/assistant/pkg/sdkmanifest"
+	"github.com/Masterminds/semver/v3"
+	"github.com/samber/lo"
+	"oras.land/oras-go/v2/registry"
+)
+
+// UnresolvedComponent returns the lock entry for a component as declared (without its digest),
+// or false for (unlockable) local-path components.
+// Components given by version, semver range or image-tag are the ones pulled from the configured registry,
+// and git components are locked by their git+https URI.
+// A semver range is kept separate from the URI, as it's only resolved to a version on locking
+func UnresolvedComponent(config *assistantconfig.Config, comp *sdkmanifest.Component, source string) (*Component, bool, error) {
+	var uri, semverRange string
+	switch {
+	case comp.LocalPath != nil:
+		return nil, false, nil
+	case comp.Uri != nil:
+		uri, semverRange, _ = ocilister.SplitRange(*comp.Uri)
+	case comp.Git != nil:
+		uri = *comp.Git
+	case comp.Version != nil:
+		uri = fmt.Sprintf("oci://%s/%s%s:%s", config.Registry, ociconsts.ComponentRepoPrefix, comp.Name, comp.Version.Value().String())
+	case comp.Range != "":
+		uri = fmt.Sprintf("oci://%s/%s%s", config.Registry, ociconsts.ComponentRepoPrefix, comp.Name)
+		semverRange = comp.Range
+	case comp.ImageTag != nil:
+		uri = fmt.Sprintf("oci://%s/%s%s:%s", config.Registry, ociconsts.ComponentRepoPrefix, comp.Name, *comp.ImageTag)
+	default:
+		return nil, false, nil
+	}
+
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid uri for component %q: %w", comp.Name, err)
+	}
+	return &Component{Name: comp.Name, URI: u, Range: semverRange, Source: source}, true, nil
+}
+
+// declaredComponents lists the components of daml.yaml or multi-package.yaml that can be locked, sorted by name
+func declaredComponents(config *assistantconfig.Config, comps map[string]*sdkmanifest.Component, source string) ([]*Component, error) {
+	var result []*Component
+	for _, comp := range comps {
+		c, ok, err := UnresolvedComponent(config, comp, source)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			result = append(result, c)
+		}
+	}
+	sortComponents(result)
+	return result, nil
+}
+
+// sdkComponents lists the components (including the assistant) of the installed sdk manifest
+func (l *Locker) sdkComponents(version *semver.Version) ([]*Component, error) {
+	installed, err := assistantconfig.GetInstalledSdkVersion(l.config, version)
+	if errors.Is(err, assistantconfig.ErrTargetSdkNotInstalled) {
+		return nil, resolutionerrors.NewSdkNotInstalledError(fmt.Errorf("%w. You can install it via 'dpm install %s'", err, version.String()))
+	} else if err != nil {
+		return nil, err
+	}
+	manifest, err := sdkmanifest.ReadSdkManifest(installed.ManifestPath)
+	if err != nil {
+		return nil, err
+	}
+
+	comps := lo.Values(manifest.Spec.Components)
+	if manifest.Spec.Assistant != nil {
+		comps = append(comps, manifest.Spec.Assistant)
+	}
+
+	var result []*Component
+	for _, comp := range comps {
+		c, ok, err := UnresolvedComponent(l.config, comp, ComponentSourceSdk)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			result = append(result, c)
+		}
+	}
+	sortComponents(result)
+	return result, nil
+}
+
+func sortComponents(comps []*Component) {
+	slices.SortFunc(comps, func(a, b *Component) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+}
+
+// resolveDigest returns the digest pinned by an oci:// URI, or else the one its tag currently resolves to
+func (l *Locker) resolveDigest(ctx context.Context, u *url.URL) (string, error) {
+	ref, err := registry.ParseReference(strings.TrimPrefix(u.String(), "oci://"))
+	if err != nil {
+		return "", err
+	}
+	if d, err := ref.Digest(); err == nil {
+		return d.String(), nil
+	}
+
+	client, err := assistantremote.New(ref.Registry, l.config.RegistryAuthPath, l.config.Insecure)
+	if err != nil {
+		return "", err
+	}
+	repo, err := client.Repo(ref.Repository)
+	if err != nil {
+		return "", err
+	}
+	desc, err := repo.Resolve(ctx, ref.Reference)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", u, err)
+	}
+	return desc.Digest.String(), nil
+}
+
+// resolveRange points the URI of a component declared with a semver range (and not already pinned to a digest)
+// at the highest matching version
+func (l *Locker) resolveRange(ctx context.Context, c *Component) error {
+	if c.Range == "" {
+		return nil
+	}
+	ref, err := registry.ParseReference(strings.TrimPrefix(c.URI.String(), "oci://"))
+	if err != nil {
+		return err
+	}
+	if _, err := ref.Digest(); err == nil {
+		return nil
+	}
+
+	client, err := assistantremote.New(ref.Registry, l.config.RegistryAuthPath, l.config.Insecure)
+	if err != nil {
+		return err
+	}
+	_, tag, err := ocilister.ResolveRange(ctx, client, ref.Repository, c.Range)
+	if err != nil {
+		return err
+	}
+	c.URI, err = url.Parse(fmt.Sprintf("oci://%s/%s:%s", ref.Registry, ref.Repository, tag))
+	return err
+}
+
+// lockComponents pins the sdk manifest and all the components in effect to their current digests (or commits, for git components).
+// The sdk's own components are only locked when no components are declared, as dpm doesn't allow using both
+func (l *Locker) lockComponents(ctx context.Context, lock *PackageLock) error {
+	if lock.SdkVersion.URI != nil {
+		d, err := l.resolveDigest(ctx, lock.SdkVersion.URI)
+		if err != nil {
+			return err
+		}
+		lock.SdkVersion.Digest = d
+
+		// a floaty sdk version can't be matched against the installed sdks
+		if v, err := semver.StrictNewVersion(lock.SdkVersion.Version); err == nil && len(lock.Components) == 0 {
+			lock.Components, err = l.sdkComponents(v)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, c := range lock.Components {
+		if gitsource.IsGit(c.URI.String()) {
+			commit, err := l.resolveCommit(ctx, c.URI.String(), l.config.RegistryAuthPath)
+			if err != nil {
+				return fmt.Errorf("failed to lock component %q: %w", c.Name, err)
+			}
+			c.Commit = commit
+			continue
+		}
+		if err := l.resolveRange(ctx, c); err != nil {
+			return fmt.Errorf("failed to lock component %q: %w", c.Name, err)
+		}
+		d, err := l.resolveDigest(ctx, c.URI)
+		if err != nil {
+			return fmt.Errorf("failed to lock component %q: %w", c.Name, err)
+		}
+		c.Digest = d
+	}
+	return nil
+}
+
+// resolveCommit returns the commit a git source's rev currently resolves to
+func (l *Locker) resolveCommit(ctx context.Context, source, authPath string) (string, error) {
+	src, err := gitsource.Parse(source)
+	if err != nil {
+		return "", err
+	}
+	if src.IsCommit() {
+		return src.Rev, nil
+	}
+	return gitsource.Resolve(ctx, l.config, src, authPath)
+}
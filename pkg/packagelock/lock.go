@@ -1,13 +1,17 @@
 package packagelock
 
 import (
+	"errors"
 	"fmt"
+	"log/slog"
+	"maps"
 	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"daml.com/x/assistant/pkg/damlpackage"
+	"daml.com/x/assistant/pkg/gitsource"
 	"daml.com/x/assistant/pkg/ocilister"
 	"daml.com/x/assistant/pkg/schema"
 	"daml.com/x/assistant/pkg/utils/stringset"
@@ -18,32 +22,67 @@ import (
 
 const (
 	PackageLockKind       = "PackageLock"
-	PackageLockVersion    = "v1"
+	PackageLockVersion    = "v2"
 	PackageLockAPIVersion = schema.APIGroup + "/" + PackageLockVersion
+
+	// v1 lockfiles (which don't pin components) are still readable, but are always considered out of sync
+	PackageLockAPIVersionV1 = schema.APIGroup + "/v1"
+)
+
+// sources of locked components
+const (
+	ComponentSourceSdk          = "sdk"
+	ComponentSourceDamlPackage  = "daml.yaml"
+	ComponentSourceMultiPackage = "multi-package.yaml"
 )
 
 var ErrInvalidPackageLock = fmt.Errorf("invalid package lock")
 
 type PackageLock struct {
 	schema.ManifestMeta `yaml:",inline"`
-	SdkVersion          SdkVersion `yaml:"sdk-version"`
-	Dars                []*Dar     `yaml:"dars"`
+	SdkVersion          SdkVersion   `yaml:"sdk-version"`
+	Components          []*Component `yaml:"components,omitempty"`
+	Dars                []*Dar       `yaml:"dars"`
 }
 
 type SdkVersion struct {
 	// Resolved version (strict semver), or "" in the no-sdk case
 	Version string `yaml:"version"`
 	// e.g. OCI://europe-docker.pkg.dev/da-images/public/sdk-manifests/open-source:3.4.11
-	URI    *url.URL `yaml:"uri"`
-	Digest string   `yaml:"digest"`
+	URI *url.URL `yaml:"uri"`
+	// digest of the sdk manifest's OCI index
+	Digest string `yaml:"digest"`
 
 	SemVer *semver.Version `yaml:"-"`
 }
 
+// Component pins a component, either one provided by the sdk or one declared in daml.yaml / multi-package.yaml,
+// to the digest of its OCI index, or git components to a commit. Local-path components aren't locked
+type Component struct {
+	Name string `yaml:"name"`
+	// as declared, e.g. oci://europe-docker.pkg.dev/da-images/public/components/damlc:3.4.11,
+	// or resolved to the highest matching version if declared with a semver range
+	URI *url.URL `yaml:"uri"`
+	// the semver range (e.g. ^3.4) as declared, if any
+	Range  string `yaml:"range,omitempty"`
+	Digest string `yaml:"digest"`
+	// the commit a git component's rev resolved to
+	Commit string `yaml:"commit,omitempty"`
+	// one of ComponentSourceSdk, ComponentSourceDamlPackage or ComponentSourceMultiPackage
+	Source string `yaml:"source"`
+}
+
 type Dar struct {
-	URI    *url.URL `yaml:"uri"`
-	Digest string   `yaml:"digest,omitempty"`
-	Path   string   `yaml:"path"`
+	URI *url.URL `yaml:"uri"`
+	// the semver range (e.g. ^1.4) as declared, if any. URI is then resolved to the highest matching version
+	Range  string `yaml:"range,omitempty"`
+	Digest string `yaml:"digest,omitempty"`
+	// the commit a git dependency's rev resolved to. Digest is then that of the dar file in it
+	Commit string `yaml:"commit,omitempty"`
+	Path   string `yaml:"path"`
+	// the dars through which a transitive dependency is required (see darmanifest.Spec.Dependencies),
+	// outermost first. Empty for the dependencies declared in daml.yaml
+	RequiredBy []string `yaml:"required-by,omitempty"`
 
 	Dependency *damlpackage.ParsedDarDependency `yaml:"-"`
 }
@@ -60,6 +99,19 @@ func ReadPackageLock(filePath string) (*PackageLock, error) {
 	return ReadPackageLockContents(bytes)
 }
 
+// ReadPackageLockIfExists is ReadPackageLock for lockfiles that are optional: it returns nil if the lockfile
+// doesn't exist, or (after warning) if it can't be read
+func ReadPackageLockIfExists(filePath string) *PackageLock {
+	lock, err := ReadPackageLock(filePath)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			slog.Warn("ignoring lockfile", "path", filePath, "err", err.Error())
+		}
+		return nil
+	}
+	return lock
+}
+
 func ReadPackageLockContents(contents []byte) (*PackageLock, error) {
 	var c PackageLock
 	if err := yaml.Unmarshal(contents, &c); err != nil {
@@ -70,6 +122,9 @@ func ReadPackageLockContents(contents []byte) (*PackageLock, error) {
 		APIVersion: PackageLockAPIVersion,
 		Kind:       PackageLockKind,
 	}
+	if c.APIVersion == PackageLockAPIVersionV1 {
+		s.APIVersion = PackageLockAPIVersionV1
+	}
 	if err := s.ValidateSchema(c.ManifestMeta); err != nil {
 		return nil, fmt.Errorf("%w: %s", ErrInvalidPackageLock, err.Error())
 	}
@@ -95,10 +150,24 @@ func ReadPackageLockContents(contents []byte) (*PackageLock, error) {
 func (l *PackageLock) toDiffableMap() (map[string]stringset.StringSet, error) {
 	m := map[string]stringset.StringSet{}
 	for _, d := range l.Dars {
+		// transitive dependencies follow from the direct ones
+		if len(d.RequiredBy) > 0 {
+			continue
+		}
 		if d.URI.Scheme == "builtin" {
 			m["builtin://"] = make(stringset.StringSet).Add(d.URI.Host)
 			continue
 		}
+		// an expected http(s) dar that isn't pinned inline has no digest to diff against yet
+		if d.URI.Scheme == "https" || d.URI.Scheme == "http" {
+			m[d.URI.String()] = make(stringset.StringSet).Add(d.Digest)
+			continue
+		}
+		// the rev is part of the URI, and only resolved to a commit on locking
+		if gitsource.IsGit(d.URI.String()) {
+			m[d.URI.String()] = make(stringset.StringSet)
+			continue
+		}
 
 		ref, err := registry.ParseReference(strings.TrimPrefix(d.URI.String(), "oci://"))
 		if err != nil {
@@ -109,14 +178,76 @@ func (l *PackageLock) toDiffableMap() (map[string]stringset.StringSet, error) {
 		if _, ok := m[k]; !ok {
 			m[k] = make(stringset.StringSet)
 		}
+		// an expected dar declared with a range has no tag to diff against yet
+		if _, err := ref.Digest(); d.Range != "" && (ref.Reference == "" || err == nil) {
+			m[k].Add(d.Range)
+			continue
+		}
 		m[k].Add(ref.Reference)
 	}
 	return m, nil
 }
 
+// FindDar returns the locked dar declared (in daml.yaml) with the given URI, if any
+func (l *PackageLock) FindDar(uri *url.URL) (*Dar, bool) {
+	for _, d := range l.Dars {
+		if len(d.RequiredBy) == 0 && d.URI != nil && d.URI.String() == uri.String() {
+			return d, true
+		}
+	}
+	return nil, false
+}
+
+// FindComponent returns the locked component with the given name, if any
+func (l *PackageLock) FindComponent(name string) (*Component, bool) {
+	for _, c := range l.Components {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return nil, false
+}
+
+// declaredComponents maps the name of every component declared in daml.yaml / multi-package.yaml to its Declaration.
+// Sdk-provided components aren't included, as they follow from the (locked) sdk manifest
+func (l *PackageLock) declaredComponents() map[string]string {
+	m := map[string]string{}
+	for _, c := range l.Components {
+		if c.Source != ComponentSourceSdk {
+			m[c.Name] = c.Declaration()
+		}
+	}
+	return m
+}
+
+// Declaration is the component's URI as declared: the locked URI, or for semver ranges, its repo with the range
+// (as the locked URI has the resolved version instead)
+func (c *Component) Declaration() string {
+	if c.Range == "" {
+		return c.URI.String()
+	}
+	ref, err := registry.ParseReference(strings.TrimPrefix(c.URI.String(), "oci://"))
+	if err != nil {
+		return c.URI.String()
+	}
+	return fmt.Sprintf("oci://%s/%s:%s", ref.Registry, ref.Repository, c.Range)
+}
+
 // isInSync checks whether this (existing) lockfile matches an expected lockfile.
 // it takes into account the fact that tags in the expected lockfile might be floaty
 func (l *PackageLock) isInSync(expected *PackageLock) (bool, error) {
+	if l.APIVersion != expected.APIVersion {
+		return false, nil
+	}
+	// a floaty sdk version can't be compared against the resolved one
+	if _, err := semver.StrictNewVersion(expected.SdkVersion.Version); (err == nil || expected.SdkVersion.Version == "") &&
+		l.SdkVersion.Version != expected.SdkVersion.Version {
+		return false, nil
+	}
+	if !maps.Equal(l.declaredComponents(), expected.declaredComponents()) {
+		return false, nil
+	}
+
 	expectedMap, err := expected.toDiffableMap()
 	if err != nil {
 		return false, err
@@ -141,14 +272,75 @@ func (l *PackageLock) isInSync(expected *PackageLock) (bool, error) {
 		}
 
 		for x := range xs {
+			if strings.HasPrefix(k, "oci://") && ocilister.IsRange(x) {
+				if !satisfiesAny(x, ys) {
+					return false, nil
+				}
+				continue
+			}
 			if strings.HasPrefix(k, "oci://") && ocilister.IsFloaty(x) {
 				continue
 			}
+			if strings.HasPrefix(k, "http") && x == "" {
+				continue
+			}
 			if !ys.Contains(x) {
 				return false, nil
 			}
 		}
 	}
 
+	// a digest-pinned dar must be locked at that very digest, whatever tag it's locked under
+	expectedDigests, err := expected.ociDigests()
+	if err != nil {
+		return false, err
+	}
+	existingDigests, err := l.ociDigests()
+	if err != nil {
+		return false, err
+	}
+	for k, xs := range expectedDigests {
+		for x := range xs {
+			if !existingDigests[k].Contains(x) {
+				return false, nil
+			}
+		}
+	}
+
 	return true, nil
 }
+
+// ociDigests maps the repo of every (direct) oci dar to the digests it's locked at, or for an expected lockfile,
+// pinned to inline in daml.yaml
+func (l *PackageLock) ociDigests() (map[string]stringset.StringSet, error) {
+	m := map[string]stringset.StringSet{}
+	for _, d := range l.Dars {
+		if len(d.RequiredBy) > 0 || d.URI.Scheme != "oci" || d.Digest == "" {
+			continue
+		}
+		ref, err := registry.ParseReference(strings.TrimPrefix(d.URI.String(), "oci://"))
+		if err != nil {
+			return nil, err
+		}
+		k := fmt.Sprintf("oci://%s/%s", ref.Registry, ref.Repository)
+		if _, ok := m[k]; !ok {
+			m[k] = make(stringset.StringSet)
+		}
+		m[k].Add(d.Digest)
+	}
+	return m, nil
+}
+
+// satisfiesAny whether any of the (locked) versions satisfies the semver range
+func satisfiesAny(semverRange string, versions stringset.StringSet) bool {
+	c, err := semver.NewConstraint(semverRange)
+	if err != nil {
+		return false
+	}
+	for v := range versions {
+		if sv, err := semver.NewVersion(v); err == nil && c.Check(sv) {
+			return true
+		}
+	}
+	return false
+}
@@ -4,6 +4,8 @@ import (
 	"net/url"
 	"testing"
 
+	"daml.com/x/assistant/pkg/schema"
+	"github.com/goccy/go-yaml"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -18,6 +20,30 @@ func mk(t *testing.T, uris ...string) *PackageLock {
 	return pl
 }
 
+// ranged marks the dars of pl as declared with the semver range r
+func ranged(pl *PackageLock, r string) *PackageLock {
+	for _, d := range pl.Dars {
+		d.Range = r
+	}
+	return pl
+}
+
+// pinned sets the digest of the dars of pl
+func pinned(pl *PackageLock, digest string) *PackageLock {
+	for _, d := range pl.Dars {
+		d.Digest = digest
+	}
+	return pl
+}
+
+// withTransitive adds a dar that's transitively required via the first one
+func withTransitive(t *testing.T, pl *PackageLock, uri string) *PackageLock {
+	p, err := url.Parse(uri)
+	require.NoError(t, err)
+	pl.Dars = append(pl.Dars, &Dar{URI: p, RequiredBy: []string{pl.Dars[0].URI.String()}})
+	return pl
+}
+
 func TestIsInSync(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -55,6 +81,78 @@ func TestIsInSync(t *testing.T) {
 			existing: mk(t, "oci://example2.com/b:1.2.3", "builtin://daml-script"),
 			want:     true,
 		},
+		{
+			name:     "locked version within range",
+			expected: ranged(mk(t, "oci://example2.com/b"), "^1.2"),
+			existing: ranged(mk(t, "oci://example2.com/b:1.4.0"), "^1.2"),
+			want:     true,
+		},
+		{
+			name:     "digest-pinned, locked version within range",
+			expected: ranged(mk(t, "oci://example2.com/b@sha256:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"), "~1.4.0"),
+			existing: ranged(mk(t, "oci://example2.com/b:1.4.3"), "~1.4.0"),
+			want:     true,
+		},
+		{
+			name:     "digest-pinned, locked at that digest",
+			expected: pinned(mk(t, "oci://example2.com/b@sha256:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"), "sha256:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"),
+			existing: pinned(mk(t, "oci://example2.com/b:1.4.3"), "sha256:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"),
+			want:     true,
+		},
+		{
+			name:     "digest-pinned, locked at another digest",
+			expected: pinned(mk(t, "oci://example2.com/b@sha256:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"), "sha256:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"),
+			existing: pinned(mk(t, "oci://example2.com/b:1.4.3"), "sha256:aaaa"),
+			want:     false,
+		},
+		{
+			name:     "locked version outside range",
+			expected: ranged(mk(t, "oci://example2.com/b"), ">=2.0 <3.0"),
+			existing: ranged(mk(t, "oci://example2.com/b:1.4.0"), "^1.2"),
+			want:     false,
+		},
+		{
+			name:     "transitive dars are ignored",
+			expected: mk(t, "oci://example2.com/b:1.2.3"),
+			existing: withTransitive(t, mk(t, "oci://example2.com/b:1.2.3"), "oci://example3.com/c:4.5.6"),
+			want:     true,
+		},
+		{
+			name:     "https dar not pinned inline",
+			expected: mk(t, "https://example.com/foo-1.0.0.dar"),
+			existing: pinned(mk(t, "https://example.com/foo-1.0.0.dar"), "sha256:aaaa"),
+			want:     true,
+		},
+		{
+			name:     "https dar pinned inline",
+			expected: pinned(mk(t, "https://example.com/foo-1.0.0.dar"), "sha256:aaaa"),
+			existing: pinned(mk(t, "https://example.com/foo-1.0.0.dar"), "sha256:aaaa"),
+			want:     true,
+		},
+		{
+			name:     "https dar pinned inline to another digest",
+			expected: pinned(mk(t, "https://example.com/foo-1.0.0.dar"), "sha256:bbbb"),
+			existing: pinned(mk(t, "https://example.com/foo-1.0.0.dar"), "sha256:aaaa"),
+			want:     false,
+		},
+		{
+			name:     "https dar url diff",
+			expected: mk(t, "https://example.com/foo-1.0.1.dar"),
+			existing: pinned(mk(t, "https://example.com/foo-1.0.0.dar"), "sha256:aaaa"),
+			want:     false,
+		},
+		{
+			name:     "git dar locked",
+			expected: mk(t, "git+https://github.com/example/foo//foo.dar@v1.0.0"),
+			existing: pinned(mk(t, "git+https://github.com/example/foo//foo.dar@v1.0.0"), "sha256:aaaa"),
+			want:     true,
+		},
+		{
+			name:     "git dar rev diff",
+			expected: mk(t, "git+https://github.com/example/foo//foo.dar@v1.0.1"),
+			existing: pinned(mk(t, "git+https://github.com/example/foo//foo.dar@v1.0.0"), "sha256:aaaa"),
+			want:     false,
+		},
 		{
 			name:     "builtin diff",
 			expected: mk(t, "builtin://daml-script"),
@@ -71,3 +169,122 @@ func TestIsInSync(t *testing.T) {
 		})
 	}
 }
+
+func TestReadPackageLockVersions(t *testing.T) {
+	t.Run("v2", func(t *testing.T) {
+		u, err := url.Parse("oci://example.com/components/damlc:3.4.11")
+		require.NoError(t, err)
+		written := &PackageLock{
+			ManifestMeta: schema.ManifestMeta{APIVersion: PackageLockAPIVersion, Kind: PackageLockKind},
+			SdkVersion:   SdkVersion{Version: "3.4.11", Digest: "sha256:aaaa"},
+			Components:   []*Component{{Name: "damlc", URI: u, Digest: "sha256:bbbb", Source: ComponentSourceSdk}},
+		}
+		b, err := yaml.Marshal(written)
+		require.NoError(t, err)
+
+		lock, err := ReadPackageLockContents(b)
+		require.NoError(t, err)
+		assert.Equal(t, "sha256:aaaa", lock.SdkVersion.Digest)
+		c, ok := lock.FindComponent("damlc")
+		require.True(t, ok)
+		assert.Equal(t, u.String(), c.URI.String())
+		assert.Equal(t, "sha256:bbbb", c.Digest)
+		assert.Equal(t, ComponentSourceSdk, c.Source)
+	})
+
+	t.Run("v1 is readable but out of sync", func(t *testing.T) {
+		lock, err := ReadPackageLockContents([]byte(`apiVersion: digitalasset.com/v1
+kind: PackageLock
+sdk-version:
+  version: ""
+dars: []
+`))
+		require.NoError(t, err)
+		inSync, err := lock.isInSync(&PackageLock{ManifestMeta: schema.ManifestMeta{APIVersion: PackageLockAPIVersion, Kind: PackageLockKind}})
+		require.NoError(t, err)
+		assert.False(t, inSync)
+	})
+}
+
+func TestIsInSyncComponents(t *testing.T) {
+	component := func(name, uri, source string) *Component {
+		u, err := url.Parse(uri)
+		require.NoError(t, err)
+		return &Component{Name: name, URI: u, Source: source}
+	}
+	rangedComponent := func(name, uri, semverRange string) *Component {
+		c := component(name, uri, ComponentSourceDamlPackage)
+		c.Range = semverRange
+		return c
+	}
+	withComponents := func(version string, comps ...*Component) *PackageLock {
+		pl := mk(t, "oci://example1.com/a:1.2.3")
+		pl.SdkVersion.Version = version
+		pl.Components = comps
+		return pl
+	}
+
+	tests := []struct {
+		name     string
+		expected *PackageLock
+		existing *PackageLock
+		want     bool
+	}{
+		{
+			name:     "sdk components are ignored",
+			expected: withComponents("3.4.11"),
+			existing: withComponents("3.4.11", component("damlc", "oci://example.com/components/damlc:3.4.11", ComponentSourceSdk)),
+			want:     true,
+		},
+		{
+			name:     "sdk version changed",
+			expected: withComponents("3.4.12"),
+			existing: withComponents("3.4.11"),
+			want:     false,
+		},
+		{
+			name:     "floaty sdk version",
+			expected: withComponents("latest"),
+			existing: withComponents("3.4.11"),
+			want:     true,
+		},
+		{
+			name:     "declared component added",
+			expected: withComponents("", component("meep", "oci://example.com/meep:1.0.0", ComponentSourceDamlPackage)),
+			existing: withComponents(""),
+			want:     false,
+		},
+		{
+			name:     "declared component changed",
+			expected: withComponents("", component("meep", "oci://example.com/meep:1.0.1", ComponentSourceDamlPackage)),
+			existing: withComponents("", component("meep", "oci://example.com/meep:1.0.0", ComponentSourceDamlPackage)),
+			want:     false,
+		},
+		{
+			name:     "declared component unchanged",
+			expected: withComponents("", component("meep", "oci://example.com/meep:1.0.0", ComponentSourceMultiPackage)),
+			existing: withComponents("", component("meep", "oci://example.com/meep:1.0.0", ComponentSourceMultiPackage)),
+			want:     true,
+		},
+		{
+			name:     "declared range unchanged",
+			expected: withComponents("", rangedComponent("meep", "oci://example.com/meep", "^1.0")),
+			existing: withComponents("", rangedComponent("meep", "oci://example.com/meep:1.3.0", "^1.0")),
+			want:     true,
+		},
+		{
+			name:     "declared range changed",
+			expected: withComponents("", rangedComponent("meep", "oci://example.com/meep", "^2.0")),
+			existing: withComponents("", rangedComponent("meep", "oci://example.com/meep:1.3.0", "^1.0")),
+			want:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.existing.isInSync(tt.expected)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
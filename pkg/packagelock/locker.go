@@ -13,11 +13,15 @@ import (
 	"daml.com/x/assistant/cmd/dpm/cmd/resolve/resolutionerrors"
 	"daml.com/x/assistant/pkg/assistantconfig"
 	"daml.com/x/assistant/pkg/damlpackage"
+	"daml.com/x/assistant/pkg/dargraph"
 	"daml.com/x/assistant/pkg/darpuller"
+	"daml.com/x/assistant/pkg/gitsource"
+	"daml.com/x/assistant/pkg/httpdar"
 	"daml.com/x/assistant/pkg/multipackage"
 	"daml.com/x/assistant/pkg/schema"
 	"daml.com/x/assistant/pkg/versions"
 	"github.com/goccy/go-yaml"
+	"github.com/opencontainers/go-digest"
 	"github.com/samber/lo"
 	"oras.land/oras-go/v2/registry"
 )
@@ -141,17 +145,41 @@ func (l *Locker) checkLockfile(expectedLockfile *PackageLock, lockfilePath strin
 }
 
 func (l *Locker) create(ctx context.Context, expected *PackageLock, lockfilePath string) (*PackageLock, error) {
+	if err := l.lockComponents(ctx, expected); err != nil {
+		return nil, err
+	}
+
+	previous, err := ReadPackageLock(lockfilePath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	graph := dargraph.New()
+	pulledDirs := map[*Dar]string{}
 	for _, d := range expected.Dars {
 		if d.URI.Scheme == "builtin" {
 			d.Path = d.URI.Host
 			continue
 		}
+		if httpdar.IsHttp(d.Dependency) {
+			if err := l.lockHttpDar(ctx, d, previous); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if d.Dependency.Git != nil {
+			if err := l.lockGitDar(ctx, d); err != nil {
+				return nil, err
+			}
+			continue
+		}
 
 		pulledDar, err := darpuller.New(l.config).PullDar(ctx, d.Dependency)
 		if err != nil {
 			return nil, err
 		}
 		d.Digest = pulledDar.Descriptor.Digest.String()
+		pulledDirs[d] = pulledDar.PulledImagePath
 
 		ref, err := registry.ParseReference(strings.TrimPrefix(d.URI.String(), "oci://"))
 		if err != nil {
@@ -162,7 +190,21 @@ func (l *Locker) create(ctx context.Context, expected *PackageLock, lockfilePath
 		resolvedRef := ":" + pulledDar.Version.String()
 		d.URI, _ = url.Parse(fmt.Sprintf("oci://%s/%s%s", ref.Registry, ref.Repository, resolvedRef))
 		d.Path = pulledDar.DarFilePath
+
+		dep, err := dargraph.NewDependency(d.URI.String()+"@"+d.Digest, nil)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := graph.Require(dep); err != nil {
+			return nil, err
+		}
+	}
+
+	transitive, err := l.lockTransitiveDars(ctx, graph, expected.Dars, pulledDirs)
+	if err != nil {
+		return nil, err
 	}
+	expected.Dars = append(expected.Dars, transitive...)
 
 	data, err := yaml.Marshal(expected)
 	if err != nil {
@@ -171,9 +213,122 @@ func (l *Locker) create(ctx context.Context, expected *PackageLock, lockfilePath
 	if err := os.WriteFile(lockfilePath, data, 0644); err != nil {
 		return nil, err
 	}
+	// so that 'dpm cache gc' keeps the dars pinned by this lockfile
+	if err := l.config.KnownProjects.Add(filepath.Dir(lockfilePath)); err != nil {
+		return nil, err
+	}
 	return expected, nil
 }
 
+// lockHttpDar downloads an http(s) dar, checking it against its inline digest or else the one already locked.
+// A dar that's pinned by neither is locked at the digest it's downloaded with
+func (l *Locker) lockHttpDar(ctx context.Context, d *Dar, previous *PackageLock) error {
+	expected := digest.Digest(d.Digest)
+	if expected == "" && previous != nil {
+		if locked, ok := previous.FindDar(d.URI); ok {
+			expected = digest.Digest(locked.Digest)
+		}
+	}
+	pulled, err := httpdar.Pull(ctx, l.config, d.Dependency, expected)
+	if err != nil {
+		return err
+	}
+	d.Digest = pulled.Digest.String()
+	d.Path = pulled.DarFilePath
+	return nil
+}
+
+// lockGitDar checks out a git dar at the commit its rev currently resolves to, and locks both that commit
+// and the digest of the dar file
+func (l *Locker) lockGitDar(ctx context.Context, d *Dar) error {
+	authPath := ""
+	if d.Dependency.Location != nil {
+		authPath = d.Dependency.Location.Auth
+	}
+	commit, err := l.resolveCommit(ctx, d.URI.String(), authPath)
+	if err != nil {
+		return err
+	}
+	darPath, err := gitsource.Checkout(ctx, l.config, d.Dependency.Git, commit, authPath)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(darPath)
+	if err != nil {
+		return fmt.Errorf("%s has no dar at %s: %w", d.Dependency.Git.Repo, d.Dependency.Git.Subdir, err)
+	}
+	defer func() { _ = f.Close() }()
+	dgst, err := digest.Canonical.FromReader(f)
+	if err != nil {
+		return err
+	}
+	d.Commit = commit
+	d.Digest = dgst.String()
+	d.Path = darPath
+	return nil
+}
+
+// lockTransitiveDars pulls and locks the dars that the (already pulled) declared ones transitively depend on
+func (l *Locker) lockTransitiveDars(ctx context.Context, graph *dargraph.Graph, declared []*Dar, pulledDirs map[*Dar]string) ([]*Dar, error) {
+	pulled := map[string]*darpuller.PulledDar{}
+	pull := func(ctx context.Context, dep *dargraph.Dependency) (string, error) {
+		u, err := url.Parse(dep.URI)
+		if err != nil {
+			return "", err
+		}
+		p, err := darpuller.New(l.config).PullDar(ctx, &damlpackage.ParsedDarDependency{
+			FullUrl:  u,
+			Location: &damlpackage.ArtifactLocation{Auth: l.config.RegistryAuthPath, Insecure: l.config.Insecure},
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to pull %s (required via %s): %w", dep.URI, strings.Join(dep.RequiredBy, " -> "), err)
+		}
+		pulled[dep.URI] = p
+		return p.PulledImagePath, nil
+	}
+
+	var result []*Dar
+	for _, d := range declared {
+		dir, ok := pulledDirs[d]
+		if !ok {
+			continue
+		}
+		deps, err := graph.Walk(ctx, dir, []string{d.URI.String()}, pull)
+		if err != nil {
+			return nil, err
+		}
+		for _, dep := range deps {
+			p := pulled[dep.URI]
+			u, err := url.Parse(fmt.Sprintf("oci://%s/%s:%s", dep.Ref.Registry, dep.Ref.Repository, p.Version.String()))
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, &Dar{
+				URI:        u,
+				Digest:     p.Descriptor.Digest.String(),
+				Path:       p.DarFilePath,
+				RequiredBy: dep.RequiredBy,
+			})
+		}
+	}
+	return result, nil
+}
+
+// declaredDigest is the digest the dependency is pinned to inline (@sha256:...) in daml.yaml, if any
+func declaredDigest(d *damlpackage.ParsedDarDependency) string {
+	if d.Digest != "" || d.FullUrl == nil || d.FullUrl.Scheme != "oci" {
+		return d.Digest
+	}
+	ref, err := registry.ParseReference(strings.TrimPrefix(d.FullUrl.String(), "oci://"))
+	if err != nil {
+		return ""
+	}
+	if dgst, err := ref.Digest(); err == nil {
+		return dgst.String()
+	}
+	return ""
+}
+
 func (l *Locker) computeExpectedLockfile(packageDirAbsPath string) (*PackageLock, error) {
 	p, err := damlpackage.Read(filepath.Join(packageDirAbsPath, assistantconfig.DamlPackageFilename))
 	if err != nil {
@@ -182,18 +337,27 @@ func (l *Locker) computeExpectedLockfile(packageDirAbsPath string) (*PackageLock
 
 	// TODO de-duplicate p.ResolvedDependencies first
 	expectedDars := lo.MapToSlice(p.ParsedDarDependencies.Dependencies, func(_ string, d *damlpackage.ParsedDarDependency) *Dar {
+		if d.Workspace != "" {
+			// built by the multi-package, rather than locked
+			return nil
+		}
 		return &Dar{
 			URI:        d.FullUrl,
+			Range:      d.Range,
+			Digest:     declaredDigest(d),
 			Dependency: d,
-
-			// TODO diff digests too
-			// Digest:
 		}
 	})
+	expectedDars = lo.Compact(expectedDars)
 	slices.SortFunc(expectedDars, func(a, b *Dar) int {
 		return strings.Compare(a.URI.String(), b.URI.String())
 	})
 
+	expectedComponents, err := declaredComponents(l.config, p.Components, ComponentSourceDamlPackage)
+	if err != nil {
+		return nil, err
+	}
+
 	lockSdkVersion, err := l.getSdkVersion(filepath.Join(packageDirAbsPath, assistantconfig.DamlPackageFilename))
 	if err != nil {
 		return nil, err
@@ -204,14 +368,27 @@ func (l *Locker) computeExpectedLockfile(packageDirAbsPath string) (*PackageLock
 			Kind:       PackageLockKind,
 		},
 		SdkVersion: lockSdkVersion,
+		Components: expectedComponents,
 		Dars:       expectedDars,
 	}, nil
 }
 
+// computeMultiExpectedLockfile locks the multi-package's sdk and components.
+// The packages' dars are locked by their own dpm.lock
 func (l *Locker) computeMultiExpectedLockfile(multiPackageDirAbsPath string) (*PackageLock, error) {
 	var expectedDars []*Dar
 
-	lockSdkVersion, err := l.getSdkVersion(filepath.Join(multiPackageDirAbsPath, assistantconfig.DamlMultiPackageFilename))
+	multiPackagePath := filepath.Join(multiPackageDirAbsPath, assistantconfig.DamlMultiPackageFilename)
+	m, err := multipackage.Read(multiPackagePath)
+	if err != nil {
+		return nil, err
+	}
+	expectedComponents, err := declaredComponents(l.config, m.Components, ComponentSourceMultiPackage)
+	if err != nil {
+		return nil, err
+	}
+
+	lockSdkVersion, err := l.getSdkVersion(multiPackagePath)
 	if err != nil {
 		return nil, err
 	}
@@ -221,6 +398,7 @@ func (l *Locker) computeMultiExpectedLockfile(multiPackageDirAbsPath string) (*P
 			Kind:       PackageLockKind,
 		},
 		SdkVersion: lockSdkVersion,
+		Components: expectedComponents,
 		Dars:       expectedDars,
 	}, nil
 }
@@ -243,7 +421,7 @@ func (l *Locker) getSdkVersion(packageDirAbsPath string) (SdkVersion, error) {
 	if err != nil {
 		return SdkVersion{}, err
 	}
-	u, err := url.Parse(fmt.Sprintf("oci://%s:%s", sdkRepo, sdkVersion))
+	u, err := url.Parse(fmt.Sprintf("oci://%s/%s:%s", l.config.Registry, sdkRepo, sdkVersion))
 	if err != nil {
 		return SdkVersion{}, err
 	}
@@ -0,0 +1,47 @@
+package packagelock
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"daml.com/x/assistant/cmd/dpm/cmd/resolve/resolutionerrors"
+	"daml.com/x/assistant/pkg/damlpackage"
+	"daml.com/x/assistant/pkg/gitsource"
+	"daml.com/x/assistant/pkg/testutil"
+	"github.com/opencontainers/go-digest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockGitDar(t *testing.T) {
+	ctx := testutil.Context(t)
+	config := testutil.MkConfig(t)
+
+	darContent, err := os.ReadFile(testutil.TestdataPath(t, "test-dar", "test.dar"))
+	require.NoError(t, err)
+	gitsource.AllowFileReposInTests(t)
+	repoDir, commit := testutil.GitRepo(t, map[string][]byte{"dars/test.dar": darContent}, "v1.0.0")
+
+	dep := &damlpackage.ParsedDarDependency{}
+	dep.Git, err = gitsource.Parse("git+file://" + filepath.ToSlash(repoDir) + "//dars/test.dar@v1.0.0")
+	require.NoError(t, err)
+	dep.FullUrl, err = url.Parse(dep.Git.String())
+	require.NoError(t, err)
+
+	d := &Dar{URI: dep.FullUrl, Dependency: dep}
+	require.NoError(t, New(config, Regular).lockGitDar(ctx, d))
+	assert.Equal(t, commit, d.Commit)
+	assert.Equal(t, digest.FromBytes(darContent).String(), d.Digest)
+	assert.Equal(t, gitsource.Dir(config, dep.Git, commit), d.Path)
+	assert.FileExists(t, d.Path)
+
+	lock := &PackageLock{Dars: []*Dar{d}}
+	require.NoError(t, VerifyDigests(ctx, config, lock, false))
+	require.NoError(t, os.WriteFile(d.Path, []byte("tampered"), 0644))
+	var resErr *resolutionerrors.ResolutionError
+	require.True(t, errors.As(VerifyDigests(ctx, config, lock, false), &resErr))
+	assert.Equal(t, resolutionerrors.LockfileDigestMismatch, resErr.Code)
+}
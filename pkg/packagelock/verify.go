@@ -0,0 +1,243 @@
+package packagelock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"daml.com/x/assistant/cmd/dpm/cmd/resolve/resolutionerrors"
+	"daml.com/x/assistant/pkg/assistantconfig"
+	"daml.com/x/assistant/pkg/assistantconfig/assistantremote"
+	"daml.com/x/assistant/pkg/ocicache"
+	"github.com/opencontainers/go-digest"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/samber/lo"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/content/oci"
+	"oras.land/oras-go/v2/errdef"
+	"oras.land/oras-go/v2/registry"
+)
+
+// VerifyDigests checks that the dars pinned by the lockfile haven't changed since it was written:
+// the dar on disk must still match the dar layer of the locked manifest, and, with checkRegistry, the locked URI
+// must still resolve to that manifest in the registry (so re-pushed tags are caught).
+// Only checkRegistry needs the network, the other checks are all local.
+// Mismatches are returned as (joined) LockfileDigestMismatch resolution errors.
+// Checks that can't be performed (e.g. the registry is unreachable) are skipped with a warning
+func VerifyDigests(ctx context.Context, config *assistantconfig.Config, lock *PackageLock, checkRegistry bool) error {
+	var errs []error
+	for _, d := range lock.Dars {
+		if d.URI == nil || d.Digest == "" {
+			continue
+		}
+		var err error
+		switch d.URI.Scheme {
+		case "oci":
+			err = verifyDar(ctx, config, d, checkRegistry)
+		case "https", "http", "git+https", "git+file":
+			err = verifyDarFileDigest(d)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func verifyDar(ctx context.Context, config *assistantconfig.Config, d *Dar, checkRegistry bool) error {
+	locked, err := digest.Parse(d.Digest)
+	if err != nil {
+		return resolutionerrors.NewLockfileDigestMismatchError(fmt.Errorf("%s has an invalid digest %q in the lockfile: %w", d.URI, d.Digest, err))
+	}
+	if err := verifyDarFile(ctx, config, d, locked); err != nil {
+		return err
+	}
+	return verifyRegistryDigest(ctx, config, d, locked, checkRegistry)
+}
+
+// verifyDarFile re-hashes the dar at d.Path against the layer of the locked manifest (from the oci-layout cache) it was pulled from
+func verifyDarFile(ctx context.Context, config *assistantconfig.Config, d *Dar, locked digest.Digest) error {
+	store, err := ocicache.Store(config.OciLayoutCache)
+	if err != nil {
+		return err
+	}
+	desc, err := store.Resolve(ctx, locked.String())
+	if errors.Is(err, errdef.ErrNotFound) {
+		slog.Debug("locked manifest isn't in the oci-layout cache, skipping verification of the dar on disk", "uri", d.URI.String(), "digest", locked.String())
+		return nil
+	} else if err != nil {
+		return err
+	}
+	b, err := content.FetchAll(ctx, store, desc)
+	if err != nil {
+		return resolutionerrors.NewLockfileDigestMismatchError(fmt.Errorf("locked manifest %s of %s in the cache is corrupt: %w", locked, d.URI, err))
+	}
+	var manifest v1.Manifest
+	if err := json.Unmarshal(b, &manifest); err != nil {
+		return err
+	}
+
+	var layer *v1.Descriptor
+	for i, l := range manifest.Layers {
+		title := l.Annotations[v1.AnnotationTitle]
+		if title != "" && (d.Path == title || strings.HasSuffix(d.Path, string(filepath.Separator)+filepath.FromSlash(title))) {
+			layer = &manifest.Layers[i]
+			break
+		}
+	}
+	if layer == nil {
+		return resolutionerrors.NewLockfileDigestMismatchError(fmt.Errorf("%q isn't part of the locked manifest %s of %s", d.Path, locked, d.URI))
+	}
+
+	f, err := os.Open(d.Path)
+	if os.IsNotExist(err) {
+		return resolutionerrors.NewDarNotInstalled(fmt.Errorf("dar %q is not installed. Run 'dpm install package' to install missing dars", d.URI))
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+	actual, err := layer.Digest.Algorithm().FromReader(f)
+	if err != nil {
+		return err
+	}
+	if actual != layer.Digest {
+		return resolutionerrors.NewLockfileDigestMismatchError(fmt.Errorf("%q (%s) has digest %s, but the locked manifest %s expects %s", d.Path, d.URI, actual, locked, layer.Digest))
+	}
+	return nil
+}
+
+// verifyDarFileDigest re-hashes the downloaded (or checked out) dar at d.Path against its locked digest
+func verifyDarFileDigest(d *Dar) error {
+	locked, err := digest.Parse(d.Digest)
+	if err != nil {
+		return resolutionerrors.NewLockfileDigestMismatchError(fmt.Errorf("%s has an invalid digest %q in the lockfile: %w", d.URI, d.Digest, err))
+	}
+	f, err := os.Open(d.Path)
+	if os.IsNotExist(err) {
+		return resolutionerrors.NewDarNotInstalled(fmt.Errorf("dar %q is not installed. Run 'dpm install package' to install missing dars", d.URI))
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+	actual, err := locked.Algorithm().FromReader(f)
+	if err != nil {
+		return err
+	}
+	if actual != locked {
+		return resolutionerrors.NewLockfileDigestMismatchError(fmt.Errorf("%q (%s) has digest %s, but is locked at %s", d.Path, d.URI, actual, locked))
+	}
+	return nil
+}
+
+// verifyRegistryDigest checks that the locked URI still resolves to the locked digest.
+// Unless checkRegistry, only URIs that are pinned by digest themselves are checked
+func verifyRegistryDigest(ctx context.Context, config *assistantconfig.Config, d *Dar, locked digest.Digest, checkRegistry bool) error {
+	ref, err := registry.ParseReference(strings.TrimPrefix(d.URI.String(), "oci://"))
+	if err != nil {
+		return err
+	}
+
+	if pinned, err := ref.Digest(); err == nil {
+		if pinned != locked {
+			return resolutionerrors.NewLockfileDigestMismatchError(fmt.Errorf("%s is locked at %s", d.URI, locked))
+		}
+		return nil
+	}
+	if !checkRegistry {
+		return nil
+	}
+
+	client, err := assistantremote.New(ref.Registry, config.RegistryAuthPath, config.Insecure)
+	if err != nil {
+		return err
+	}
+	repo, err := client.Repo(ref.Repository)
+	if err != nil {
+		return err
+	}
+	desc, err := repo.Resolve(ctx, ref.Reference)
+	if errors.Is(err, assistantremote.ErrOffline) {
+		slog.Debug("can't check the locked digest against the registry while offline", "uri", d.URI.String())
+		return nil
+	} else if err != nil {
+		slog.Warn("couldn't check the locked digest against the registry", "uri", d.URI.String(), "err", err.Error())
+		return nil
+	}
+	if desc.Digest != locked {
+		return resolutionerrors.NewLockfileDigestMismatchError(fmt.Errorf("%s is locked at %s, but the registry now resolves it to %s. Run 'dpm update' if the change is expected", d.URI, locked, desc.Digest))
+	}
+	return nil
+}
+
+// VerifySdkManifest checks the installed sdk manifest at manifestPath against the locked sdk manifest,
+// as found in the oci-layout cache. The check is skipped if the locked manifest isn't in the cache
+func VerifySdkManifest(ctx context.Context, config *assistantconfig.Config, sdk SdkVersion, manifestPath string) error {
+	locked, err := digest.Parse(sdk.Digest)
+	if err != nil {
+		return resolutionerrors.NewLockfileDigestMismatchError(fmt.Errorf("sdk %s has an invalid digest %q in the lockfile: %w", sdk.Version, sdk.Digest, err))
+	}
+	store, err := ocicache.Store(config.OciLayoutCache)
+	if err != nil {
+		return err
+	}
+	layers, err := manifestLayers(ctx, store, locked)
+	if err != nil {
+		return err
+	}
+
+	title := filepath.Base(manifestPath)
+	layers = lo.Filter(layers, func(l v1.Descriptor, _ int) bool {
+		return l.Annotations[v1.AnnotationTitle] == title
+	})
+	if len(layers) == 0 {
+		slog.Debug("locked sdk manifest isn't in the oci-layout cache, skipping verification of the installed one", "version", sdk.Version, "digest", locked.String())
+		return nil
+	}
+
+	b, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return err
+	}
+	for _, l := range layers {
+		if l.Digest.Algorithm().FromBytes(b) == l.Digest {
+			return nil
+		}
+	}
+	return resolutionerrors.NewLockfileDigestMismatchError(fmt.Errorf("the installed sdk manifest %q doesn't match sdk %s as locked at %s. Run 'dpm update' if the change is expected", manifestPath, sdk.Version, locked))
+}
+
+// manifestLayers returns the layers of the cached manifest d, or of all the cached manifests of the index d
+func manifestLayers(ctx context.Context, store *oci.Store, d digest.Digest) ([]v1.Descriptor, error) {
+	desc, err := store.Resolve(ctx, d.String())
+	if errors.Is(err, errdef.ErrNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	b, err := content.FetchAll(ctx, store, desc)
+	if err != nil {
+		return nil, err
+	}
+	var m struct {
+		Manifests []v1.Descriptor `json:"manifests"`
+		Layers    []v1.Descriptor `json:"layers"`
+	}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+
+	layers := m.Layers
+	for _, child := range m.Manifests {
+		l, err := manifestLayers(ctx, store, child.Digest)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, l...)
+	}
+	return layers, nil
+}
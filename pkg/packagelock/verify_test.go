@@ -0,0 +1,130 @@
+package packagelock
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"daml.com/x/assistant/cmd/dpm/cmd/resolve/resolutionerrors"
+	"daml.com/x/assistant/pkg/assistantconfig"
+	"daml.com/x/assistant/pkg/ocicache"
+	"github.com/opencontainers/go-digest"
+	"github.com/opencontainers/image-spec/specs-go"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyDigestsDetectsTamperedDar(t *testing.T) {
+	ctx := context.Background()
+	config := &assistantconfig.Config{OciLayoutCache: filepath.Join(t.TempDir(), "oci-layout")}
+
+	darContent := []byte("the original dar")
+	layer := v1.Descriptor{
+		MediaType:   "application/octet-stream",
+		Digest:      digest.FromBytes(darContent),
+		Size:        int64(len(darContent)),
+		Annotations: map[string]string{v1.AnnotationTitle: "foo.dar"},
+	}
+	manifestBytes, err := json.Marshal(v1.Manifest{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		MediaType: v1.MediaTypeImageManifest,
+		Config:    v1.DescriptorEmptyJSON,
+		Layers:    []v1.Descriptor{layer},
+	})
+	require.NoError(t, err)
+	manifest := v1.Descriptor{MediaType: v1.MediaTypeImageManifest, Digest: digest.FromBytes(manifestBytes), Size: int64(len(manifestBytes))}
+
+	store, err := ocicache.Store(config.OciLayoutCache)
+	require.NoError(t, err)
+	require.NoError(t, store.Push(ctx, manifest, bytes.NewReader(manifestBytes)))
+
+	darPath := filepath.Join(t.TempDir(), "foo.dar")
+	require.NoError(t, os.WriteFile(darPath, darContent, 0644))
+
+	uri, err := url.Parse("oci://example.invalid/dars/foo@" + manifest.Digest.String())
+	require.NoError(t, err)
+	lock := &PackageLock{Dars: []*Dar{{URI: uri, Digest: manifest.Digest.String(), Path: darPath}}}
+
+	require.NoError(t, VerifyDigests(ctx, config, lock, false))
+
+	require.NoError(t, os.WriteFile(darPath, []byte("something else"), 0644))
+	err = VerifyDigests(ctx, config, lock, false)
+	require.Error(t, err)
+	var resErr *resolutionerrors.ResolutionError
+	require.True(t, errors.As(err, &resErr))
+	assert.Equal(t, resolutionerrors.LockfileDigestMismatch, resErr.Code)
+
+	require.NoError(t, os.WriteFile(darPath, darContent, 0644))
+	lock.Dars[0].Digest = digest.FromString("another manifest").String()
+	err = VerifyDigests(ctx, config, lock, false)
+	require.True(t, errors.As(err, &resErr))
+	assert.Equal(t, resolutionerrors.LockfileDigestMismatch, resErr.Code)
+}
+
+func TestVerifyDigestsDetectsTamperedHttpDar(t *testing.T) {
+	ctx := context.Background()
+	config := &assistantconfig.Config{}
+
+	darContent := []byte("the original dar")
+	darPath := filepath.Join(t.TempDir(), "foo.dar")
+	require.NoError(t, os.WriteFile(darPath, darContent, 0644))
+
+	uri, err := url.Parse("https://example.invalid/releases/foo.dar")
+	require.NoError(t, err)
+	lock := &PackageLock{Dars: []*Dar{{URI: uri, Digest: digest.FromBytes(darContent).String(), Path: darPath}}}
+
+	require.NoError(t, VerifyDigests(ctx, config, lock, false))
+
+	require.NoError(t, os.WriteFile(darPath, []byte("something else"), 0644))
+	err = VerifyDigests(ctx, config, lock, false)
+	var resErr *resolutionerrors.ResolutionError
+	require.True(t, errors.As(err, &resErr))
+	assert.Equal(t, resolutionerrors.LockfileDigestMismatch, resErr.Code)
+
+	require.NoError(t, os.Remove(darPath))
+	err = VerifyDigests(ctx, config, lock, false)
+	require.True(t, errors.As(err, &resErr))
+	assert.Equal(t, resolutionerrors.DarNotInstalled, resErr.Code)
+}
+
+func TestVerifyDigestsOnlyChecksRegistryWhenAsked(t *testing.T) {
+	ctx := context.Background()
+	config := &assistantconfig.Config{OciLayoutCache: filepath.Join(t.TempDir(), "oci-layout"), Insecure: true}
+
+	repushed := digest.FromString("the re-pushed manifest")
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Path != "/v2/dars/foo/manifests/1.0.0" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", v1.MediaTypeImageManifest)
+		w.Header().Set("Docker-Content-Digest", repushed.String())
+		w.Header().Set("Content-Length", "2")
+		_, _ = w.Write([]byte("{}"))
+	}))
+	t.Cleanup(server.Close)
+
+	uri, err := url.Parse("oci://" + server.Listener.Addr().String() + "/dars/foo:1.0.0")
+	require.NoError(t, err)
+	lock := &PackageLock{Dars: []*Dar{{URI: uri, Digest: digest.FromString("the locked manifest").String(), Path: "foo.dar"}}}
+
+	require.NoError(t, VerifyDigests(ctx, config, lock, false))
+	assert.Zero(t, requests.Load())
+
+	err = VerifyDigests(ctx, config, lock, true)
+	var resErr *resolutionerrors.ResolutionError
+	require.True(t, errors.As(err, &resErr))
+	assert.Equal(t, resolutionerrors.LockfileDigestMismatch, resErr.Code)
+	assert.NotZero(t, requests.Load())
+}
@@ -0,0 +1,304 @@
+// Copyright (c) 2017-2026 Digital Asset (Switzerland) GmbH and/or its affiliates. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package progress reports per-blob and aggregate progress (bytes, speed and ETA) of OCI pulls and pushes,
+// either as a TTY progress bar, as periodic log lines, or as machine-readable JSON events.
+package progress
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2"
+)
+
+type Mode string
+
+const (
+	// ModeAuto renders a progress bar when writing to a terminal, and periodic log lines otherwise
+	ModeAuto  Mode = "auto"
+	ModeTTY   Mode = "tty"
+	ModePlain Mode = "plain"
+	ModeJSON  Mode = "json"
+	ModeNone  Mode = "none"
+)
+
+var Modes = []Mode{ModeAuto, ModeTTY, ModePlain, ModeJSON, ModeNone}
+
+func ParseMode(s string) (Mode, error) {
+	if s == "" {
+		return ModeAuto, nil
+	}
+	m := Mode(s)
+	if !slices.Contains(Modes, m) {
+		return "", fmt.Errorf("invalid progress mode %q, must be one of %v", s, Modes)
+	}
+	return m, nil
+}
+
+type Operation string
+
+const (
+	OperationPull   Operation = "pull"
+	OperationPush   Operation = "push"
+	OperationBundle Operation = "bundle"
+)
+
+type EventType string
+
+const (
+	EventStart    EventType = "start"
+	EventProgress EventType = "progress"
+	EventDone     EventType = "done"
+	// the blob already existed at the destination (or in the cache), so no bytes were transferred
+	EventSkipped EventType = "skipped"
+)
+
+type Event struct {
+	Type      EventType `json:"type"`
+	Operation Operation `json:"operation"`
+	// the blob's file name (if it has one) or its digest
+	Name    string `json:"name"`
+	Digest  string `json:"digest"`
+	Current int64  `json:"current"`
+	Total   int64  `json:"total"`
+
+	// across all the blobs currently in flight
+	AggregateCurrent int64 `json:"aggregate-current"`
+	AggregateTotal   int64 `json:"aggregate-total"`
+	BytesPerSecond   int64 `json:"bytes-per-second"`
+	// estimated seconds remaining, -1 if unknown
+	EtaSeconds int64 `json:"eta-seconds"`
+}
+
+type blob struct {
+	op      Operation
+	desc    v1.Descriptor
+	current int64
+	done    bool
+}
+
+// Reporter tracks the blobs being transferred, and renders their progress.
+// It's safe for concurrent use
+type Reporter struct {
+	renderer renderer
+
+	mu sync.Mutex
+	// digest -> blob, reset once all blobs are done
+	blobs map[string]*blob
+	// bytes actually transferred (as opposed to skipped) since the first blob started
+	transferred int64
+	started     time.Time
+}
+
+var (
+	defaultMu       sync.Mutex
+	defaultReporter = &Reporter{renderer: noopRenderer{}, blobs: map[string]*blob{}}
+)
+
+// Default returns the process-wide reporter, which doesn't report anything until SetDefault is called
+func Default() *Reporter {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	return defaultReporter
+}
+
+func SetDefault(r *Reporter) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultReporter = r
+}
+
+// New creates a reporter writing to w. With ModeAuto, a progress bar is only rendered if w is a terminal
+func New(mode Mode, w io.Writer) *Reporter {
+	if mode == ModeAuto {
+		mode = ModePlain
+		if isTerminal(w) {
+			mode = ModeTTY
+		}
+	}
+
+	var r renderer
+	switch mode {
+	case ModeTTY:
+		r = &ttyRenderer{w: w}
+	case ModeJSON:
+		r = &jsonRenderer{w: w}
+	case ModePlain:
+		r = &plainRenderer{}
+	default:
+		r = noopRenderer{}
+	}
+	return &Reporter{renderer: r, blobs: map[string]*blob{}}
+}
+
+// CopyOptions hooks the reporter into the given oras copy options
+func (r *Reporter) CopyOptions(op Operation, opts oras.CopyOptions) oras.CopyOptions {
+	preCopy, postCopy, onCopySkipped := opts.PreCopy, opts.PostCopy, opts.OnCopySkipped
+
+	opts.PreCopy = func(ctx context.Context, desc v1.Descriptor) error {
+		r.start(op, desc)
+		if preCopy != nil {
+			return preCopy(ctx, desc)
+		}
+		return nil
+	}
+	opts.PostCopy = func(ctx context.Context, desc v1.Descriptor) error {
+		// blobs that were served from the cache never went through Reader
+		r.finish(desc, EventDone)
+		if postCopy != nil {
+			return postCopy(ctx, desc)
+		}
+		return nil
+	}
+	opts.OnCopySkipped = func(ctx context.Context, desc v1.Descriptor) error {
+		r.start(op, desc)
+		r.finish(desc, EventSkipped)
+		if onCopySkipped != nil {
+			return onCopySkipped(ctx, desc)
+		}
+		return nil
+	}
+	return opts
+}
+
+// Reader counts the bytes read from rc towards desc's progress
+func (r *Reporter) Reader(op Operation, desc v1.Descriptor, rc io.ReadCloser) io.ReadCloser {
+	r.start(op, desc)
+	return &trackedReader{ReadCloser: rc, reporter: r, desc: desc}
+}
+
+// Target wraps src so that the bytes fetched from it are reported, e.g. when pushing from a local store
+func (r *Reporter) Target(op Operation, src oras.ReadOnlyTarget) oras.ReadOnlyTarget {
+	return &trackedTarget{ReadOnlyTarget: src, reporter: r, op: op}
+}
+
+func (r *Reporter) start(op Operation, desc v1.Descriptor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := desc.Digest.String()
+	if _, ok := r.blobs[key]; ok {
+		return
+	}
+	if len(r.blobs) == 0 {
+		r.started = time.Now()
+		r.transferred = 0
+	}
+	b := &blob{op: op, desc: desc}
+	r.blobs[key] = b
+	r.renderer.render(r.event(EventStart, b), false)
+}
+
+func (r *Reporter) advance(desc v1.Descriptor, n int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	b, ok := r.blobs[desc.Digest.String()]
+	if !ok || b.done {
+		return
+	}
+	b.current += n
+	r.transferred += n
+	r.renderer.render(r.event(EventProgress, b), false)
+}
+
+func (r *Reporter) finish(desc v1.Descriptor, t EventType) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	b, ok := r.blobs[desc.Digest.String()]
+	if !ok || b.done {
+		return
+	}
+	b.done = true
+	b.current = b.desc.Size
+
+	allDone := true
+	for _, other := range r.blobs {
+		allDone = allDone && other.done
+	}
+	r.renderer.render(r.event(t, b), allDone)
+	if allDone {
+		r.blobs = map[string]*blob{}
+	}
+}
+
+// event must be called with the lock held
+func (r *Reporter) event(t EventType, b *blob) Event {
+	e := Event{
+		Type:       t,
+		Operation:  b.op,
+		Name:       blobName(b.desc),
+		Digest:     b.desc.Digest.String(),
+		Current:    b.current,
+		Total:      b.desc.Size,
+		EtaSeconds: -1,
+	}
+	for _, other := range r.blobs {
+		e.AggregateCurrent += other.current
+		e.AggregateTotal += other.desc.Size
+	}
+
+	elapsed := time.Since(r.started).Seconds()
+	if elapsed > 0 {
+		e.BytesPerSecond = int64(float64(r.transferred) / elapsed)
+	}
+	if e.BytesPerSecond > 0 {
+		e.EtaSeconds = (e.AggregateTotal - e.AggregateCurrent) / e.BytesPerSecond
+	}
+	return e
+}
+
+func blobName(desc v1.Descriptor) string {
+	if title, ok := desc.Annotations[v1.AnnotationTitle]; ok && title != "" {
+		return title
+	}
+	return desc.Digest.String()
+}
+
+type trackedReader struct {
+	io.ReadCloser
+	reporter *Reporter
+	desc     v1.Descriptor
+}
+
+func (t *trackedReader) Read(p []byte) (int, error) {
+	n, err := t.ReadCloser.Read(p)
+	if n > 0 {
+		t.reporter.advance(t.desc, int64(n))
+	}
+	if err == io.EOF {
+		t.reporter.finish(t.desc, EventDone)
+	}
+	return n, err
+}
+
+type trackedTarget struct {
+	oras.ReadOnlyTarget
+	reporter *Reporter
+	op       Operation
+}
+
+func (t *trackedTarget) Fetch(ctx context.Context, target v1.Descriptor) (io.ReadCloser, error) {
+	rc, err := t.ReadOnlyTarget.Fetch(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	return t.reporter.Reader(t.op, target, rc), nil
+}
+
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
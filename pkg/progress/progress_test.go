@@ -0,0 +1,69 @@
+// Copyright (c) 2017-2026 Digital Asset (Switzerland) GmbH and/or its affiliates. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package progress
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	v1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"oras.land/oras-go/v2"
+)
+
+func TestJsonEvents(t *testing.T) {
+	var out bytes.Buffer
+	r := New(ModeJSON, &out)
+
+	content := strings.Repeat("meep", 1000)
+	desc := v1.Descriptor{
+		Digest:      digest.FromString(content),
+		Size:        int64(len(content)),
+		Annotations: map[string]string{v1.AnnotationTitle: "meep.jar"},
+	}
+	skipped := v1.Descriptor{Digest: digest.FromString("sheep"), Size: 5}
+
+	opts := r.CopyOptions(OperationPull, oras.CopyOptions{})
+	require.NoError(t, opts.OnCopySkipped(t.Context(), skipped))
+
+	require.NoError(t, opts.PreCopy(t.Context(), desc))
+	b, err := io.ReadAll(r.Reader(OperationPull, desc, io.NopCloser(strings.NewReader(content))))
+	require.NoError(t, err)
+	assert.Equal(t, content, string(b))
+	require.NoError(t, opts.PostCopy(t.Context(), desc))
+
+	var events []Event
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var e Event
+		require.NoError(t, json.Unmarshal([]byte(line), &e))
+		events = append(events, e)
+	}
+
+	types := lo.Map(events, func(e Event, _ int) EventType { return e.Type })
+	assert.Equal(t, []EventType{EventStart, EventSkipped, EventStart}, types[:3])
+	assert.Equal(t, EventDone, types[len(types)-1])
+
+	last := events[len(events)-1]
+	assert.Equal(t, "meep.jar", last.Name)
+	assert.Equal(t, OperationPull, last.Operation)
+	assert.Equal(t, desc.Size, last.Current)
+	// the skipped blob was done before the other one started, so it's no longer part of the aggregate
+	assert.Equal(t, desc.Size, last.AggregateTotal)
+	assert.Equal(t, last.AggregateTotal, last.AggregateCurrent)
+}
+
+func TestParseMode(t *testing.T) {
+	m, err := ParseMode("")
+	require.NoError(t, err)
+	assert.Equal(t, ModeAuto, m)
+
+	_, err = ParseMode("fancy")
+	assert.ErrorContains(t, err, "invalid progress mode")
+}
@@ -0,0 +1,122 @@
+// Copyright (c) 2017-2026 Digital Asset (Switzerland) GmbH and/or its affiliates. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package progress
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	"daml.com/x/assistant/pkg/utils"
+)
+
+const (
+	ttyInterval   = 100 * time.Millisecond
+	jsonInterval  = 500 * time.Millisecond
+	plainInterval = 5 * time.Second
+
+	barWidth = 30
+)
+
+// renderer is always called with the reporter's lock held.
+// final is set once all the blobs in flight are done
+type renderer interface {
+	render(e Event, final bool)
+}
+
+type noopRenderer struct{}
+
+func (noopRenderer) render(Event, bool) {}
+
+// ttyRenderer redraws a single progress bar line
+type ttyRenderer struct {
+	w    io.Writer
+	last time.Time
+}
+
+func (t *ttyRenderer) render(e Event, final bool) {
+	if !final && time.Since(t.last) < ttyInterval {
+		return
+	}
+	t.last = time.Now()
+
+	filled := 0
+	if e.AggregateTotal > 0 {
+		filled = int(float64(barWidth) * float64(e.AggregateCurrent) / float64(e.AggregateTotal))
+	}
+	filled = min(filled, barWidth)
+
+	line := fmt.Sprintf("%s [%s%s] %s / %s  %s/s",
+		e.Operation,
+		strings.Repeat("=", filled),
+		strings.Repeat(" ", barWidth-filled),
+		utils.HumanSize(e.AggregateCurrent),
+		utils.HumanSize(e.AggregateTotal),
+		utils.HumanSize(e.BytesPerSecond),
+	)
+	if e.EtaSeconds >= 0 && !final {
+		line += fmt.Sprintf("  ETA %s", time.Duration(e.EtaSeconds)*time.Second)
+	}
+
+	// \r and clear-to-end-of-line, so that the bar is redrawn in place
+	_, _ = fmt.Fprintf(t.w, "\r\033[K%s", line)
+	if final {
+		_, _ = fmt.Fprintln(t.w)
+	}
+}
+
+// plainRenderer logs the aggregate progress periodically, for when there's no terminal to draw a bar on
+type plainRenderer struct {
+	last time.Time
+}
+
+func (p *plainRenderer) render(e Event, final bool) {
+	if e.Type == EventStart && p.last.IsZero() {
+		p.last = time.Now()
+		return
+	}
+	if !final && time.Since(p.last) < plainInterval {
+		return
+	}
+	p.last = time.Now()
+
+	attrs := []any{
+		"operation", e.Operation,
+		"bytes", fmt.Sprintf("%s/%s", utils.HumanSize(e.AggregateCurrent), utils.HumanSize(e.AggregateTotal)),
+		"speed", utils.HumanSize(e.BytesPerSecond) + "/s",
+	}
+	if final {
+		slog.Info("transfer complete", attrs...)
+		p.last = time.Time{}
+		return
+	}
+	if e.EtaSeconds >= 0 {
+		attrs = append(attrs, "eta", (time.Duration(e.EtaSeconds) * time.Second).String())
+	}
+	slog.Info("transfer in progress", attrs...)
+}
+
+// jsonRenderer writes one JSON event per line. Progress events are throttled, all other events are always written
+type jsonRenderer struct {
+	w    io.Writer
+	last time.Time
+}
+
+func (j *jsonRenderer) render(e Event, final bool) {
+	if e.Type == EventProgress {
+		if time.Since(j.last) < jsonInterval {
+			return
+		}
+		j.last = time.Now()
+	}
+
+	b, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	_, _ = fmt.Fprintln(j.w, string(b))
+}
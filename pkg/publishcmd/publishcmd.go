@@ -31,6 +31,7 @@ type PublishDarCmd struct {
 	Version                string
 	Annotations            map[string]string
 	Dars                   []string
+	Dependencies           []string
 	ExtraTags              []string
 	ExcludeLicense         bool
 	LicenseFile            string
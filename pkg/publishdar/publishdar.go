@@ -25,11 +25,13 @@ import (
 	"github.com/go-git/go-git/v5/plumbing"
 	v1 "github.com/opencontainers/image-spec/specs-go/v1"
 	"oras.land/oras-go/v2/errdef"
+	"oras.land/oras-go/v2/registry"
 )
 
 type DarConfig struct {
 	Name                   string
 	Dars                   []string
+	Dependencies           []string
 	Version                *semver.Version
 	DryRun, IncludeGitInfo bool
 	Annotations            map[string]string
@@ -113,11 +115,17 @@ func (p *DarPublisher) prepare(ctx context.Context) (*darpusher.DarPushOperation
 	var artifact ociconsts.Artifact
 	artifact = &ociconsts.DarArtifact{DarRepo: p.config.Destination.Artifact.RepoName()}
 
+	dependencies, err := p.pinDependencies(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	opts := darpusher.DarOpts{
-		Artifact:    artifact,
-		Version:     *p.config.Version,
-		Dars:        p.config.Dars,
-		LicenseFile: p.config.LicenseFile,
+		Artifact:     artifact,
+		Version:      *p.config.Version,
+		Dars:         p.config.Dars,
+		Dependencies: dependencies,
+		LicenseFile:  p.config.LicenseFile,
 	}
 
 	pushOp, err := darpusher.DarNew(ctx, opts)
@@ -132,6 +140,35 @@ Consider reducing the number of files at the root by moving them to subdirectori
 	return pushOp, nil
 }
 
+// pinDependencies resolves the dependencies to "oci://<registry>/<repo>:<version>@<digest>",
+// so that consumers of the dar get exactly the dars it was built against
+func (p *DarPublisher) pinDependencies(ctx context.Context) ([]string, error) {
+	var pinned []string
+	for _, uri := range p.config.Dependencies {
+		if !strings.HasPrefix(uri, "oci://") {
+			return nil, fmt.Errorf("invalid dependency %q: must be an oci uri, e.g. oci://whatever.dev/bar/test/foo:1.2.3", uri)
+		}
+		ref, err := registry.ParseReference(strings.TrimPrefix(uri, "oci://"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid dependency %q: %w", uri, err)
+		}
+		client, err := assistantremote.New(ref.Registry, p.config.AuthFilePath, p.config.Insecure)
+		if err != nil {
+			return nil, err
+		}
+		d, manifest, err := ocilister.FetchManifest(ctx, client, ref)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve dependency %q: %w", uri, err)
+		}
+		version, ok := utils.GetWithFallback(manifest.Annotations, v1.AnnotationVersion, ociconsts.LegacyVersionAnnotation)
+		if !ok {
+			return nil, fmt.Errorf("dependency %q has no version annotation", uri)
+		}
+		pinned = append(pinned, fmt.Sprintf("oci://%s/%s:%s@%s", ref.Registry, ref.Repository, version, d))
+	}
+	return pinned, nil
+}
+
 func (p *DarPublisher) push(ctx context.Context, client *assistantremote.Remote, pushOp *darpusher.DarPushOperation) (*v1.Descriptor, error) {
 	coloredDest := color.GreenString(pushOp.DarDestination(client.Registry))
 
@@ -21,6 +21,8 @@ type Resolution struct {
 	schema.ManifestMeta `yaml:",inline"`
 	Packages            Packages   `yaml:"packages"`
 	DefaultSDK          DefaultSDK `yaml:"default-sdk"`
+	// the paths of a multi-package's packages, each after the ones it depends on via workspace:<package> dependencies
+	BuildOrder []string `yaml:"build-order,omitempty"`
 }
 
 // Packages is a <package path> -> Package mapping
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"daml.com/x/assistant/cmd/dpm/cmd/resolve/resolutionerrors"
@@ -16,18 +17,28 @@ import (
 	"daml.com/x/assistant/pkg/assembler/assemblyplan"
 	"daml.com/x/assistant/pkg/assistantconfig"
 	"daml.com/x/assistant/pkg/damlpackage"
-	"daml.com/x/assistant/pkg/darmanifest"
+	"daml.com/x/assistant/pkg/dargraph"
+	"daml.com/x/assistant/pkg/gitsource"
+	"daml.com/x/assistant/pkg/httpdar"
 	"daml.com/x/assistant/pkg/multipackage"
 	"daml.com/x/assistant/pkg/packagelock"
 	"daml.com/x/assistant/pkg/resolution"
 	"daml.com/x/assistant/pkg/schema"
 	"daml.com/x/assistant/pkg/utils"
+	"github.com/opencontainers/go-digest"
 	"github.com/samber/lo"
 )
 
 type DeepResolver struct {
 	assembler *assembler.Assembler
 	config    *assistantconfig.Config
+
+	// the packages of the multi-package in scope (if any), for resolving workspace:<package> dependencies
+	workspace *damlpackage.Workspace
+
+	// VerifyRegistryDigests also checks that the dars' locked tags still resolve to their locked digests in the registry.
+	// It needs the network, so it's off for the resolutions that precede every SDK command
+	VerifyRegistryDigests bool
 }
 
 func New(config *assistantconfig.Config, a *assembler.Assembler) *DeepResolver {
@@ -53,9 +64,39 @@ func (d *DeepResolver) RunDeepResolution(ctx context.Context) (*resolution.Resol
 		},
 		Packages:   pkgs,
 		DefaultSDK: defaultSdk,
+		BuildOrder: d.buildOrder(pkgs),
 	}, nil
 }
 
+// buildOrder orders the multi-package's packages by their workspace:<package> dependencies.
+// A dependency cycle is reported as an error of every package in it, instead
+func (d *DeepResolver) buildOrder(pkgs resolution.Packages) []string {
+	if d.workspace == nil {
+		return nil
+	}
+	order, err := d.workspace.BuildOrder()
+	var cycle *damlpackage.CycleError
+	if errors.As(err, &cycle) {
+		for _, dir := range cycle.Dirs {
+			if pkg, ok := pkgs[evalSymlinks(dir)]; ok {
+				pkg.Errors = append(pkg.Errors, resolutionerrors.NewInvalidWorkspaceDependencyError(cycle))
+			}
+		}
+		return nil
+	}
+	return lo.Map(order, func(dir string, _ int) string {
+		return evalSymlinks(dir)
+	})
+}
+
+// evalSymlinks resolves the symlinks in the path of a package (like resolve does), if it can
+func evalSymlinks(p string) string {
+	if resolved, err := filepath.EvalSymlinks(p); err == nil {
+		return resolved
+	}
+	return p
+}
+
 func (d *DeepResolver) resolvePackages(ctx context.Context) (resolution.Packages, error) {
 	// multi-package
 	multiPackagePath, isMultiPackage, err := assistantconfig.GetMultiPackageAbsolutePath()
@@ -67,6 +108,7 @@ func (d *DeepResolver) resolvePackages(ctx context.Context) (resolution.Packages
 		if err != nil {
 			return nil, err
 		}
+		d.workspace = damlpackage.ReadWorkspace(multiPackage)
 		return d.resolve(ctx, multiPackage.AbsolutePackages()...)
 	}
 
@@ -85,6 +127,8 @@ func (d *DeepResolver) resolvePackages(ctx context.Context) (resolution.Packages
 
 func (d *DeepResolver) resolve(ctx context.Context, packageAbsolutePaths ...string) (resolution.Packages, error) {
 	pkgs := make(resolution.Packages)
+	// <package path> -> the oci dars it resolved to
+	installed := make(map[string][]*dargraph.InstalledDar)
 
 	for _, p := range packageAbsolutePaths {
 		// if the path is a symlink, resolve it first
@@ -96,132 +140,339 @@ func (d *DeepResolver) resolve(ctx context.Context, packageAbsolutePaths ...stri
 			continue
 		}
 
-		if result, err := d.resolvePackageAndDars(ctx, resolvedPath); err != nil {
+		if result, dars, err := d.resolvePackageAndDars(ctx, resolvedPath); err != nil {
 			pkgs[resolvedPath] = &resolution.Package{
 				Errors: resolutionerrors.Standardize(err),
 			}
 		} else {
 			pkgs[resolvedPath] = result
+			installed[resolvedPath] = dars
+		}
+	}
+
+	// conflicts are checked across all the packages, as they end up in the same build
+	for _, c := range dargraph.PackageIdConflicts(lo.Flatten(lo.Values(installed))) {
+		for path, dars := range installed {
+			if lo.Some(dars, c.Dars) {
+				pkgs[path].Errors = append(pkgs[path].Errors, c.Err)
+			}
 		}
 	}
 
 	return pkgs, nil
 }
 
-func (d *DeepResolver) resolvePackageAndDars(ctx context.Context, absPath string) (*resolution.Package, error) {
-	result, err := d.resolvePackage(ctx, absPath)
+func (d *DeepResolver) resolvePackageAndDars(ctx context.Context, absPath string) (*resolution.Package, []*dargraph.InstalledDar, error) {
+	result, installed, err := d.resolvePackage(ctx, absPath)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	if !assistantconfig.DpmLockfileEnabled() {
-		return result.ShallowResolution, nil
+		return result.ShallowResolution, installed, nil
 
 	}
 	lock, err := packagelock.ReadPackageLock(filepath.Join(absPath, assistantconfig.DpmLockFileName))
-	if errors.Is(err, os.ErrNotExist) {
-		return nil, err
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := packagelock.VerifyDigests(ctx, d.config, lock, d.VerifyRegistryDigests); err != nil {
+		return nil, nil, err
 	}
 
 	paths := lo.Map(lock.Dars, func(d *packagelock.Dar, _ int) string {
 		return d.Path
 	})
+	// workspace dars are built rather than locked
+	workspaceDars, err := d.workspaceDars(absPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	paths = append(paths, workspaceDars...)
 	if len(paths) > 0 {
 		result.ShallowResolution.Imports[resolution.DarImportsFields] = paths
 	}
 
-	return result.ShallowResolution, nil
+	return result.ShallowResolution, installed, nil
 }
 
-func (d *DeepResolver) resolvePackage(ctx context.Context, absPath string) (*assembler.AssemblyResult, error) {
+func (d *DeepResolver) resolvePackage(ctx context.Context, absPath string) (*assembler.AssemblyResult, []*dargraph.InstalledDar, error) {
 	assemblyPlan, err := assemblyplan.NewShallow(ctx, d.config, d.assembler, filepath.Join(absPath, assistantconfig.DamlPackageFilename))
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	result, err := assemblyPlan.Assemble(ctx)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	resolvedDeps, resolvedDataDeps, err := d.resolvePackageDars(absPath)
+	resolvedDeps, resolvedDataDeps, installed, err := d.resolvePackageDars(ctx, absPath)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	result.ShallowResolution.Imports[resolution.ResolvedDependenciesField] = resolvedDeps
 	result.ShallowResolution.Imports[resolution.ResolvedDataDependenciesField] = resolvedDataDeps
 
-	return result, nil
+	return result, installed, nil
 }
 
-func (d *DeepResolver) resolvePackageDars(absPath string) (deps []string, dataDeps []string, err error) {
+func (d *DeepResolver) resolvePackageDars(ctx context.Context, absPath string) (deps []string, dataDeps []string, installed []*dargraph.InstalledDar, err error) {
 	p, err := damlpackage.Read(filepath.Join(absPath, assistantconfig.DamlPackageFilename))
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
+	}
+
+	// pins the http(s) dars that aren't pinned inline, and git dars to their commits
+	lock, lockErr := packagelock.ReadPackageLock(filepath.Join(absPath, assistantconfig.DpmLockFileName))
+	if lockErr != nil && !os.IsNotExist(lockErr) {
+		return nil, nil, nil, lockErr
 	}
 
 	var errs []error
 
+	// the direct dependencies are required first, so that the transitive ones are checked against all of them
+	graph := dargraph.New()
+	for _, dar := range slices.Concat(lo.Values(p.ParsedDarDependencies.Dependencies), lo.Values(p.ParsedDarDependencies.DataDependencies)) {
+		if dar.FullUrl.Scheme != "oci" {
+			continue
+		}
+		dep, err := dargraph.NewDependency(dar.FullUrl.String(), nil)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if lock != nil {
+			if locked, ok := lock.FindDar(dar.FullUrl); ok {
+				dep.Digest = locked.Digest
+			}
+		}
+		if _, err := graph.Require(dep); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
 	for _, dar := range p.ParsedDarDependencies.Dependencies {
-		r, err := d.resolveDar(dar)
+		r, dars, err := d.resolveDar(ctx, dar, graph, lock)
 		if err != nil {
 			errs = append(errs, err)
 			continue
 		}
 		deps = append(deps, r...)
+		installed = append(installed, dars...)
 	}
 
 	for _, dar := range p.ParsedDarDependencies.DataDependencies {
-		r, err := d.resolveDar(dar)
+		r, dars, err := d.resolveDar(ctx, dar, graph, lock)
 		if err != nil {
 			errs = append(errs, err)
 			continue
 		}
 		dataDeps = append(dataDeps, r...)
+		installed = append(installed, dars...)
 	}
 
 	if err := errors.Join(errs...); err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	return
 }
 
-func (d *DeepResolver) resolveDar(dar *damlpackage.ParsedDarDependency) ([]string, error) {
+// resolveDar returns the paths of a dar dependency's files, followed by those of the dars it (transitively) depends on.
+// For oci dependencies, those dars are returned as well
+func (d *DeepResolver) resolveDar(ctx context.Context, dar *damlpackage.ParsedDarDependency, graph *dargraph.Graph, lock *packagelock.PackageLock) ([]string, []*dargraph.InstalledDar, error) {
 	scheme := dar.FullUrl.Scheme
 
 	if scheme == "builtin" || scheme == "file" {
-		return []string{strings.TrimPrefix(dar.FullUrl.String(), scheme+"://")}, nil
+		return []string{strings.TrimPrefix(dar.FullUrl.String(), scheme+"://")}, nil, nil
+	}
+	if httpdar.IsHttp(dar) {
+		return d.resolveHttpDar(dar, lock)
+	}
+	if dar.Git != nil {
+		return d.resolveGitDar(dar, lock)
+	}
+	if dar.Workspace != "" {
+		p, err := d.resolveWorkspaceDar(dar)
+		if err != nil {
+			return nil, nil, err
+		}
+		return []string{p}, nil, nil
 	}
 	if scheme == "oci" {
 		_, ref, err := dar.GetOciRemote()
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		darDir := d.config.CachePathForDar(ref)
 		ok, err := utils.DirExists(darDir)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		if !ok {
-			return nil, resolutionerrors.NewDarNotInstalled(fmt.Errorf("dar %q is not installed. Run 'dpm install package' to install missing dars", dar.FullUrl))
+			return nil, nil, resolutionerrors.NewDarNotInstalled(fmt.Errorf("dar %q is not installed. Run 'dpm install package' to install missing dars", dar.FullUrl))
 		}
 
-		darManifestPath := filepath.Join(darDir, assistantconfig.DarManifestName)
-		darManifest, err := darmanifest.ReadDarManifest(darManifestPath)
+		installed, err := dargraph.InstalledDars(darDir, dar.String())
 		if err != nil {
-			return nil, err
+			return nil, nil, err
+		}
+		if err := checkMainPackageId(dar, installed); err != nil {
+			return nil, nil, err
 		}
 
-		var dars []string
-		for _, obj := range darManifest.Spec.Dars {
-			dars = append(dars, utils.ResolvePath(darDir, obj.Path))
+		transitive, err := graph.Walk(ctx, darDir, []string{dar.String()}, d.installedDar)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, t := range transitive {
+			dars, err := dargraph.InstalledDars(t.Dir, t.URI)
+			if err != nil {
+				return nil, nil, err
+			}
+			installed = append(installed, dars...)
 		}
-		return dars, nil
+
+		paths := lo.Map(installed, func(d *dargraph.InstalledDar, _ int) string {
+			return d.Path
+		})
+		return paths, installed, nil
+	}
+
+	return nil, nil, fmt.Errorf("unsupported schema %s", scheme)
+}
+
+// resolveHttpDar resolves an http(s) dar to its download in the cache, by the digest it's pinned to inline or else in the lockfile
+func (d *DeepResolver) resolveHttpDar(dar *damlpackage.ParsedDarDependency, lock *packagelock.PackageLock) ([]string, []*dargraph.InstalledDar, error) {
+	dgst := dar.Digest
+	if dgst == "" && lock != nil {
+		if locked, ok := lock.FindDar(dar.FullUrl); ok {
+			dgst = locked.Digest
+		}
+	}
+	if dgst == "" {
+		return nil, nil, httpdar.UnpinnedError(dar)
+	}
+
+	pulled, ok, err := httpdar.Installed(d.config, digest.Digest(dgst))
+	if err != nil {
+		return nil, nil, err
+	}
+	if !ok {
+		return nil, nil, resolutionerrors.NewDarNotInstalled(fmt.Errorf("dar %q is not installed. Run 'dpm install package' to install missing dars", dar.StringWithAlias()))
+	}
+	installed, err := dargraph.InstalledDars(pulled.Dir, dar.String())
+	if err != nil {
+		return nil, nil, err
 	}
+	if err := checkMainPackageId(dar, installed); err != nil {
+		return nil, nil, err
+	}
+	return []string{pulled.DarFilePath}, installed, nil
+}
 
-	return nil, fmt.Errorf("unsupported schema %s", scheme)
+// resolveGitDar resolves a git dar to its checkout in the cache, at the commit its rev is locked to (unless the rev is a commit).
+// Without a lockfile in use, the rev resolves to wherever it was when the dar was last installed
+func (d *DeepResolver) resolveGitDar(dar *damlpackage.ParsedDarDependency, lock *packagelock.PackageLock) ([]string, []*dargraph.InstalledDar, error) {
+	commit := ""
+	if dar.Git.IsCommit() {
+		commit = dar.Git.Rev
+	} else if lock != nil {
+		if locked, ok := lock.FindDar(dar.FullUrl); ok {
+			commit = locked.Commit
+		}
+	}
+	if commit == "" && !assistantconfig.DpmLockfileEnabled() {
+		var ok bool
+		var err error
+		if commit, ok, err = gitsource.ResolveCached(d.config, dar.Git); err != nil {
+			return nil, nil, err
+		}
+		if !ok {
+			return nil, nil, resolutionerrors.NewDarNotInstalled(fmt.Errorf("dar %q is not installed. Run 'dpm install package' to install missing dars", dar.StringWithAlias()))
+		}
+	}
+	if commit == "" {
+		return nil, nil, resolutionerrors.NewUnpinnedDarError(fmt.Errorf("dar %q isn't locked to a commit yet. Run 'dpm update' to lock it", dar.StringWithAlias()))
+	}
+
+	darPath, ok, err := gitsource.CheckedOut(d.config, dar.Git, commit)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !ok {
+		return nil, nil, resolutionerrors.NewDarNotInstalled(fmt.Errorf("dar %q is not installed. Run 'dpm install package' to install missing dars", dar.StringWithAlias()))
+	}
+	installed := []*dargraph.InstalledDar{dargraph.InstalledDarFile(darPath, dar.String(), "")}
+	if err := checkMainPackageId(dar, installed); err != nil {
+		return nil, nil, err
+	}
+	return []string{darPath}, installed, nil
+}
+
+// resolveWorkspaceDar returns the path of the dar that the package of the multi-package builds.
+// It needn't exist yet, as it's for the build tool to build the packages in the resolution's build order
+func (d *DeepResolver) resolveWorkspaceDar(dar *damlpackage.ParsedDarDependency) (string, error) {
+	if d.workspace == nil {
+		return "", resolutionerrors.NewInvalidWorkspaceDependencyError(fmt.Errorf("dependency %q can only be used in a package of a multi-package", dar.FullUrl))
+	}
+	p, err := d.workspace.Dar(dar.Workspace)
+	if err != nil {
+		return "", resolutionerrors.NewInvalidWorkspaceDependencyError(err)
+	}
+	return p, nil
+}
+
+// workspaceDars returns the paths of the workspace:<package> dependencies (and data-dependencies) of the package at absPath
+func (d *DeepResolver) workspaceDars(absPath string) ([]string, error) {
+	p, err := damlpackage.Read(filepath.Join(absPath, assistantconfig.DamlPackageFilename))
+	if err != nil {
+		return nil, err
+	}
+	deps := slices.Concat(lo.Values(p.ParsedDarDependencies.Dependencies), lo.Values(p.ParsedDarDependencies.DataDependencies))
+	var paths []string
+	for _, dar := range deps {
+		if dar.Workspace == "" {
+			continue
+		}
+		path, err := d.resolveWorkspaceDar(dar)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// checkMainPackageId checks the main-package-id declared in daml.yaml (if any) against the installed dars
+func checkMainPackageId(dar *damlpackage.ParsedDarDependency, installed []*dargraph.InstalledDar) error {
+	if dar.MainPackageId == nil || *dar.MainPackageId == "" {
+		return nil
+	}
+	ids := lo.FilterMap(installed, func(d *dargraph.InstalledDar, _ int) (string, bool) {
+		return d.MainPackageId, d.MainPackageId != ""
+	})
+	if len(ids) == 0 || slices.Contains(ids, *dar.MainPackageId) {
+		return nil
+	}
+	return resolutionerrors.NewPackageIdConflictError(
+		fmt.Errorf("%s declares main-package-id %s, but resolved to %s", dar.FullUrl, *dar.MainPackageId, strings.Join(ids, ", ")),
+	)
+}
+
+// installedDar resolves a transitive dar dependency to its directory in the cache
+func (d *DeepResolver) installedDar(_ context.Context, dep *dargraph.Dependency) (string, error) {
+	darDir := d.config.CachePathForDar(&dep.Ref)
+	ok, err := utils.DirExists(darDir)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", resolutionerrors.NewDarNotInstalled(fmt.Errorf("dar %q (required via %s) is not installed. Run 'dpm install package' to install missing dars", dep.URI, strings.Join(dep.RequiredBy, " -> ")))
+	}
+	return darDir, nil
 }
 
 func (d *DeepResolver) resolveDefaultSdk(ctx context.Context) resolution.DefaultSDK {
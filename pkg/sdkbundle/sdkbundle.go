@@ -22,6 +22,7 @@ import (
 	"daml.com/x/assistant/pkg/ociindex"
 	"daml.com/x/assistant/pkg/ocipuller/localpuller"
 	"daml.com/x/assistant/pkg/ocipusher/sdkmanifestpusher"
+	"daml.com/x/assistant/pkg/progress"
 	"daml.com/x/assistant/pkg/schema"
 	"daml.com/x/assistant/pkg/sdkinstall"
 	"daml.com/x/assistant/pkg/sdkmanifest"
@@ -191,7 +192,7 @@ func validate(ctx context.Context, bundlePath, blobCache string, platform *simpl
 	}
 	config.Edition = assistantconfig.NewLazyEdition(edition)
 	config.OciLayoutCache = blobCache
-	if err := config.EnsureDirs(); err != nil {
+	if err := config.EnsureDirs(ctx); err != nil {
 		return err
 	}
 	return bootstrap(ctx, config, bundlePath, platform)
@@ -367,7 +368,7 @@ func clone(ctx context.Context, client *assistantremote.Remote, registryPath, re
 		return nil, err
 	}
 
-	opts := oras.DefaultCopyOptions
+	opts := progress.Default().CopyOptions(progress.OperationBundle, oras.DefaultCopyOptions)
 	if descriptor.Platform != nil {
 		opts.WithTargetPlatform(descriptor.Platform)
 	}
@@ -53,8 +53,8 @@ func (s *Spec) UnmarshalYAML(bytes []byte) error {
 
 	if alias.Assistant != nil {
 		alias.Assistant.Name = AssistantName
-		if alias.Assistant.LocalPath != nil {
-			return fmt.Errorf("%w: assistant can only be an OCI and not a local-path", ErrInvalidAssemblyManifest)
+		if alias.Assistant.LocalPath != nil || alias.Assistant.Git != nil {
+			return fmt.Errorf("%w: assistant can only be an OCI and not a local-path or git", ErrInvalidAssemblyManifest)
 		}
 	}
 
@@ -74,6 +74,18 @@ type Component struct {
 	ImageTag  *string `yaml:"image-tag,omitempty"`
 	LocalPath *string `yaml:"local-path,omitempty"`
 	Uri       *string `yaml:"uri,omitempty"`
+	// Git is a git source (git+https://<host>/<repo>[//<subdir>]@<rev>), checked out like a local-path component
+	Git *string `yaml:"git,omitempty"`
+
+	// Digest pins a component given by version to the digest of its OCI index (e.g. from the lockfile)
+	Digest string `yaml:"-"`
+
+	// Commit pins a git component to the commit its rev resolved to (e.g. from the lockfile)
+	Commit string `yaml:"-"`
+
+	// Range is a semver range (e.g. ^1.4) for components given as "<name>:<range>" in daml.yaml or multi-package.yaml.
+	// It's resolved to the highest matching version on assembly, unless the lockfile pins a Version
+	Range string `yaml:"-"`
 
 	YamlEditTarget *yamledit.YamlTarget `yaml:"-"`
 }
@@ -82,12 +94,16 @@ type Component struct {
 func (c *Component) String() string {
 	if c.Version != nil {
 		return fmt.Sprintf("%s:%s", c.Name, c.Version.Value().String())
+	} else if c.Range != "" {
+		return fmt.Sprintf("%s:%s", c.Name, c.Range)
 	} else if c.ImageTag != nil {
 		return fmt.Sprintf("%s:%s", c.Name, *c.ImageTag)
 	} else if c.LocalPath != nil {
 		return fmt.Sprintf("%s@%s", c.Name, *c.LocalPath)
 	} else if c.Uri != nil {
 		return fmt.Sprintf("%s@%s", c.Name, *c.Uri)
+	} else if c.Git != nil {
+		return fmt.Sprintf("%s@%s", c.Name, *c.Git)
 	} //TODO - Incorrect formatting?
 	return c.Name
 }
@@ -99,14 +115,17 @@ func (c *Component) UnmarshalYAML(bytes []byte) error {
 		return fmt.Errorf("failed to unmarshal Component: %w", err)
 	}
 
-	if alias.Version == nil && alias.LocalPath == nil && alias.ImageTag == nil && alias.Uri == nil {
-		return fmt.Errorf("%w: a component must include `local-path`, `image-tag`, `uri` or `version` field", ErrInvalidAssemblyManifest)
+	if alias.Version == nil && alias.LocalPath == nil && alias.ImageTag == nil && alias.Uri == nil && alias.Git == nil {
+		return fmt.Errorf("%w: a component must include `local-path`, `image-tag`, `uri`, `git` or `version` field", ErrInvalidAssemblyManifest)
 	}
 	if alias.LocalPath != nil {
-		if alias.ImageTag != nil || alias.Version != nil || alias.Uri != nil {
-			return fmt.Errorf("%w: a component can't simultaneously be local ('local-path') and remote ('version', 'image-tag', 'uri')", ErrInvalidAssemblyManifest)
+		if alias.ImageTag != nil || alias.Version != nil || alias.Uri != nil || alias.Git != nil {
+			return fmt.Errorf("%w: a component can't simultaneously be local ('local-path') and remote ('version', 'image-tag', 'uri', 'git')", ErrInvalidAssemblyManifest)
 		}
 	}
+	if alias.Git != nil && (alias.ImageTag != nil || alias.Version != nil || alias.Uri != nil) {
+		return fmt.Errorf("%w: a component can't simultaneously be from git ('git') and OCI ('version', 'image-tag', 'uri')", ErrInvalidAssemblyManifest)
+	}
 	*c = Component(alias)
 	return nil
 }
@@ -9,10 +9,12 @@ import (
 	"fmt"
 	"net/http/httptest"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"runtime"
 	"strings"
 	"testing"
+	"time"
 
 	"daml.com/x/assistant/pkg/assistantconfig"
 	"daml.com/x/assistant/pkg/assistantconfig/assistantremote"
@@ -26,6 +28,8 @@ import (
 	"daml.com/x/assistant/pkg/simpleplatform"
 	"daml.com/x/assistant/pkg/utils"
 	"github.com/Masterminds/semver/v3"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/goccy/go-yaml"
 	"github.com/google/go-containerregistry/pkg/registry"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
@@ -304,3 +308,34 @@ func FindManifestByAnnotation(path string, name string, value string) (string, e
 
 	return "", nil
 }
+
+// GitRepo creates a git repo with the given files (executable if their name ends in .sh),
+// committed and tagged with tag, and returns the repo's dir and the commit.
+// Fetching it from git+file://<dir> needs gitsource.AllowFileReposInTests, and the git binary,
+// so the test is skipped without one
+func GitRepo(t *testing.T, files map[string][]byte, tag string) (dir, commit string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git isn't installed")
+	}
+
+	dir = t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	require.NoError(t, err)
+	wt, err := repo.Worktree()
+	require.NoError(t, err)
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0755))
+		require.NoError(t, os.WriteFile(p, content, lo.Ternary[os.FileMode](strings.HasSuffix(name, ".sh"), 0755, 0644)))
+		_, err := wt.Add(name)
+		require.NoError(t, err)
+	}
+	hash, err := wt.Commit("test", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	require.NoError(t, err)
+	_, err = repo.CreateTag(tag, hash, nil)
+	require.NoError(t, err)
+	return dir, hash.String()
+}
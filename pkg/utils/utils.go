@@ -24,6 +24,20 @@ func BoolEnvVar(key string) (val bool, ok bool, err error) {
 	return b, ok, nil
 }
 
+// IntEnvVar parses an env var as int
+func IntEnvVar(key string) (val int, ok bool, err error) {
+	var valStr string
+	valStr, ok = os.LookupEnv(key)
+	if !ok {
+		return 0, ok, nil
+	}
+	i, err := strconv.Atoi(valStr)
+	if err != nil {
+		return 0, ok, fmt.Errorf("invalid value for '%s' env var. Must be an integer", key)
+	}
+	return i, ok, nil
+}
+
 var envVarRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
 
 func IsValidEnvVarIdentifier(key string) bool {
@@ -0,0 +1,447 @@
+package yamledit
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+	"github.com/goccy/go-yaml/token"
+)
+
+// Document is a yaml file parsed into goccy's AST, so that it can be edited in place
+// without losing its comments, flow-style collections, anchors or line endings.
+//
+// Fields are addressed by their keys joined with '.', e.g. "sdk-version" or "build-options.target".
+// Values are either an ast.Node (e.g. from ParseFragment) or any value that marshals to yaml.
+type Document struct {
+	file *ast.File
+	crlf bool
+}
+
+// Parse parses raw into a Document
+func Parse(raw []byte) (*Document, error) {
+	crlf := bytes.Contains(raw, []byte("\r\n"))
+	if crlf {
+		raw = bytes.ReplaceAll(raw, []byte("\r\n"), []byte("\n"))
+	}
+	f, err := parser.ParseBytes(raw, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	return &Document{file: f, crlf: crlf}, nil
+}
+
+// ReadFile reads and parses the yaml file at path
+func ReadFile(path string) (*Document, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	d, err := Parse(b)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse %q: %w", path, err)
+	}
+	return d, nil
+}
+
+// WriteFile writes the document to path
+func (d *Document) WriteFile(path string) error {
+	return os.WriteFile(path, []byte(d.String()), 0644)
+}
+
+// String prints the document, with the line endings it was parsed with
+func (d *Document) String() string {
+	s := d.file.String()
+	if s != "" && !strings.HasSuffix(s, "\n") {
+		s += "\n"
+	}
+	if d.crlf {
+		s = strings.ReplaceAll(s, "\n", "\r\n")
+	}
+	return s
+}
+
+// ParseFragment parses a yaml fragment (e.g. a scalar, or a whole object) into a node
+func ParseFragment(s string) (ast.Node, error) {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	if strings.TrimSpace(s) == "" {
+		return nil, fmt.Errorf("empty YAML fragment")
+	}
+	f, err := parser.ParseBytes([]byte(strings.Trim(s, "\n")+"\n"), parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("invalid YAML fragment: %w", err)
+	}
+	if len(f.Docs) != 1 || f.Docs[0].Body == nil {
+		return nil, fmt.Errorf("invalid YAML fragment %q: must be a single value", s)
+	}
+	return f.Docs[0].Body, nil
+}
+
+// Get returns the value of the field, or nil if it isn't set
+func (d *Document) Get(field string) (ast.Node, error) {
+	_, mv, err := d.lookup(field)
+	if err != nil || mv == nil {
+		return nil, err
+	}
+	return unwrapAnchor(mv.Value), nil
+}
+
+// Set sets the field to value, keeping any comment on the line.
+// Missing fields (and the maps they're in) are added at the end of their map
+func (d *Document) Set(field string, value any) error {
+	node, err := toNode(value)
+	if err != nil {
+		return err
+	}
+
+	parent, mv, err := d.lookup(field)
+	if err != nil {
+		return err
+	}
+	if mv != nil {
+		return replaceValue(mv, node)
+	}
+	return d.insert(parent, field, node)
+}
+
+// Delete removes the field (and the comments above it) from its map, if it's there
+func (d *Document) Delete(field string) error {
+	parent, mv, err := d.lookup(field)
+	if err != nil || mv == nil {
+		return err
+	}
+	parent.Values = slices.DeleteFunc(parent.Values, func(v *ast.MappingValueNode) bool {
+		return v == mv
+	})
+	return nil
+}
+
+// Append adds item (with an optional line comment) to the end of the list in the field, creating the list if need be
+func (d *Document) Append(field string, item any, lineComment string) error {
+	node, err := toNode(item)
+	if err != nil {
+		return err
+	}
+
+	_, mv, err := d.lookup(field)
+	if err != nil {
+		return err
+	}
+	if mv == nil || isNull(mv.Value) {
+		// a new list, in block style
+		seq, err := toNode([]any{nil})
+		if err != nil {
+			return err
+		}
+		if err := seq.(*ast.SequenceNode).Replace(0, node); err != nil {
+			return err
+		}
+		if err := setLineComment(node, lineComment); err != nil {
+			return err
+		}
+		return d.Set(field, seq)
+	}
+
+	seq, err := sequence(field, mv)
+	if err != nil {
+		return err
+	}
+	if seq.IsFlowStyle {
+		setFlowStyle(node)
+	} else if err := setLineComment(node, lineComment); err != nil {
+		return err
+	}
+
+	if len(seq.Values) > 0 {
+		last := seq.Values[len(seq.Values)-1]
+		node.AddColumn(last.GetToken().Position.Column - node.GetToken().Position.Column)
+	} else {
+		node.AddColumn(seq.Start.Position.Column + 2 - node.GetToken().Position.Column)
+	}
+	seq.Values = append(seq.Values, node)
+	if len(seq.ValueHeadComments) > 0 {
+		seq.ValueHeadComments = append(seq.ValueHeadComments, nil)
+	}
+	return nil
+}
+
+// Replace replaces the item at index of the list in the field with item (and its optional line comment)
+func (d *Document) Replace(field string, index int, item any, lineComment string) error {
+	node, err := toNode(item)
+	if err != nil {
+		return err
+	}
+	seq, err := d.sequence(field, index)
+	if err != nil {
+		return err
+	}
+	if seq.IsFlowStyle {
+		setFlowStyle(node)
+	} else if err := setLineComment(node, lineComment); err != nil {
+		return err
+	}
+	return seq.Replace(index, node)
+}
+
+// Remove removes the item at index (and the comments above it) from the list in the field.
+// The field itself is removed once its list is empty
+func (d *Document) Remove(field string, index int) error {
+	seq, err := d.sequence(field, index)
+	if err != nil {
+		return err
+	}
+
+	seq.Values = slices.Delete(seq.Values, index, index+1)
+	if index < len(seq.ValueHeadComments) {
+		seq.ValueHeadComments = slices.Delete(seq.ValueHeadComments, index, index+1)
+	}
+	// in block style, the comment above the first item belongs to the list
+	if index == 0 && !seq.IsFlowStyle {
+		if err := seq.SetComment(nil); err != nil {
+			return err
+		}
+	}
+
+	if len(seq.Values) == 0 {
+		return d.Delete(field)
+	}
+	return nil
+}
+
+func (d *Document) sequence(field string, index int) (*ast.SequenceNode, error) {
+	_, mv, err := d.lookup(field)
+	if err != nil {
+		return nil, err
+	}
+	if mv == nil {
+		return nil, fmt.Errorf("field %q not found", field)
+	}
+	seq, err := sequence(field, mv)
+	if err != nil {
+		return nil, err
+	}
+	if index < 0 || index >= len(seq.Values) {
+		return nil, fmt.Errorf("field %q has no item at index %d", field, index)
+	}
+	return seq, nil
+}
+
+func sequence(field string, mv *ast.MappingValueNode) (*ast.SequenceNode, error) {
+	if _, ok := mv.Value.(*ast.AliasNode); ok {
+		return nil, fmt.Errorf("field %q is an alias: edit the anchored list instead", field)
+	}
+	seq, ok := unwrapAnchor(mv.Value).(*ast.SequenceNode)
+	if !ok {
+		return nil, fmt.Errorf("field %q is not a list", field)
+	}
+	return seq, nil
+}
+
+// lookup returns the map that holds the field (or else its closest existing ancestor, for a missing field),
+// and the field's key-value node within it, if it's there
+func (d *Document) lookup(field string) (*ast.MappingNode, *ast.MappingValueNode, error) {
+	keys := strings.Split(field, ".")
+	if slices.Contains(keys, "") {
+		return nil, nil, fmt.Errorf("invalid field %q", field)
+	}
+
+	root, err := d.root()
+	if err != nil || root == nil {
+		return nil, nil, err
+	}
+
+	m := root
+	for i, key := range keys {
+		mv := findKey(m, key)
+		if mv == nil {
+			return m, nil, nil
+		}
+		if i == len(keys)-1 {
+			return m, mv, nil
+		}
+		switch value := unwrapAnchor(mv.Value).(type) {
+		case *ast.MappingNode:
+			m = value
+		case *ast.AliasNode:
+			return nil, nil, fmt.Errorf("field %q is an alias: edit the anchored map instead", strings.Join(keys[:i+1], "."))
+		default:
+			if !isNull(value) {
+				return nil, nil, fmt.Errorf("field %q is not a map", strings.Join(keys[:i+1], "."))
+			}
+			// e.g. "build-options:" with nothing under it yet
+			return nil, nil, fmt.Errorf("field %q is empty: set it to a map first", strings.Join(keys[:i+1], "."))
+		}
+	}
+	return m, nil, nil
+}
+
+// root returns the document's top-level map, or nil if the document is empty
+func (d *Document) root() (*ast.MappingNode, error) {
+	if len(d.file.Docs) == 0 || d.file.Docs[0].Body == nil {
+		return nil, nil
+	}
+	m, ok := unwrapAnchor(d.file.Docs[0].Body).(*ast.MappingNode)
+	if !ok {
+		return nil, fmt.Errorf("the document is not a map")
+	}
+	return m, nil
+}
+
+// insert adds the (nested) field to parent, which is its closest existing ancestor (or nil for an empty document)
+func (d *Document) insert(parent *ast.MappingNode, field string, node ast.Node) error {
+	keys := strings.Split(field, ".")
+	if parent != nil {
+		keys = keys[d.depth(parent):]
+	}
+
+	// build the missing maps around a placeholder, and put the node in its place
+	var value any = nil
+	for i := len(keys) - 1; i >= 0; i-- {
+		value = yaml.MapSlice{{Key: keys[i], Value: value}}
+	}
+	fragment, err := toNode(value)
+	if err != nil {
+		return err
+	}
+	leaf := fragment.(*ast.MappingNode)
+	for {
+		mv := leaf.Values[0]
+		next, ok := mv.Value.(*ast.MappingNode)
+		if !ok {
+			if err := replaceValue(mv, node); err != nil {
+				return err
+			}
+			break
+		}
+		leaf = next
+	}
+
+	if parent == nil {
+		if len(d.file.Docs) == 0 {
+			d.file.Docs = append(d.file.Docs, ast.Document(nil, fragment))
+		} else {
+			d.file.Docs[0].Body = fragment
+		}
+		return nil
+	}
+	parent.Merge(fragment.(*ast.MappingNode))
+	return nil
+}
+
+// depth returns how many keys deep m is in the document
+func (d *Document) depth(m *ast.MappingNode) int {
+	root, _ := d.root()
+	var walk func(n *ast.MappingNode, level int) int
+	walk = func(n *ast.MappingNode, level int) int {
+		if n == m {
+			return level
+		}
+		for _, mv := range n.Values {
+			if child, ok := unwrapAnchor(mv.Value).(*ast.MappingNode); ok {
+				if found := walk(child, level+1); found >= 0 {
+					return found
+				}
+			}
+		}
+		return -1
+	}
+	return walk(root, 0)
+}
+
+func findKey(m *ast.MappingNode, key string) *ast.MappingValueNode {
+	for _, mv := range m.Values {
+		if mv.Key.GetToken().Value == key {
+			return mv
+		}
+	}
+	return nil
+}
+
+// replaceValue replaces the value of mv with node, keeping the old value's line comment (unless node has its own)
+func replaceValue(mv *ast.MappingValueNode, node ast.Node) error {
+	if node.GetComment() == nil && isScalar(node) {
+		if err := node.SetComment(mv.Value.GetComment()); err != nil {
+			return err
+		}
+	}
+	if anchor, ok := mv.Value.(*ast.AnchorNode); ok {
+		node.AddColumn(anchor.Value.GetToken().Position.Column - node.GetToken().Position.Column)
+		anchor.Value = node
+		return nil
+	}
+	if err := mv.Replace(node); err != nil {
+		return err
+	}
+	// a collection that replaced a scalar goes on the lines below its key
+	if !isScalar(node) && !isFlowStyle(node) {
+		node.AddColumn(mv.Key.GetToken().Position.Column + 2 - node.GetToken().Position.Column)
+		node.GetToken().Position.IndentLevel = mv.Key.GetToken().Position.IndentLevel + 1
+	}
+	return nil
+}
+
+func toNode(value any) (ast.Node, error) {
+	if node, ok := value.(ast.Node); ok {
+		return node, nil
+	}
+	b, err := yaml.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return ParseFragment(string(b))
+}
+
+func unwrapAnchor(n ast.Node) ast.Node {
+	if anchor, ok := n.(*ast.AnchorNode); ok {
+		return anchor.Value
+	}
+	return n
+}
+
+func isNull(n ast.Node) bool {
+	return n == nil || n.Type() == ast.NullType
+}
+
+func isScalar(n ast.Node) bool {
+	_, ok := n.(ast.ScalarNode)
+	return ok
+}
+
+func isFlowStyle(n ast.Node) bool {
+	switch n := n.(type) {
+	case *ast.SequenceNode:
+		return n.IsFlowStyle
+	case *ast.MappingNode:
+		return n.IsFlowStyle
+	}
+	return false
+}
+
+func setFlowStyle(n ast.Node) {
+	switch n := n.(type) {
+	case *ast.SequenceNode:
+		n.SetIsFlowStyle(true)
+	case *ast.MappingNode:
+		n.SetIsFlowStyle(true)
+	}
+}
+
+// setLineComment sets the comment following node on its (first) line
+func setLineComment(node ast.Node, comment string) error {
+	comment = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(comment), "#"))
+	if comment == "" {
+		return nil
+	}
+	tk := token.Comment(" "+comment, "# "+comment, &token.Position{})
+	group := ast.CommentGroup([]*token.Token{tk})
+	if m, ok := node.(*ast.MappingNode); ok && len(m.Values) > 0 {
+		// on the line of the object's first key
+		return m.Values[0].Value.SetComment(group)
+	}
+	return node.SetComment(group)
+}
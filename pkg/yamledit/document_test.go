@@ -0,0 +1,147 @@
+package yamledit
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustParse(t *testing.T, s string) *Document {
+	d, err := Parse([]byte(s))
+	require.NoError(t, err)
+	return d
+}
+
+func TestRoundTrip(t *testing.T) {
+	for name, s := range map[string]string{
+		"block": "# hello\nsdk-version: 3.4.0 # pinned\n\ncomponents:\n  - damlc:1.2.3\n",
+		"flow":  "packages: [./a, ./b] # the packages\nbuild-options: {target: 2.1}\n",
+		"anchors": `base: &base
+  - oci://example.com/foo:1.0.0
+components: *base
+`,
+		"crlf": "sdk-version: 3.4.0\r\ncomponents:\r\n  - damlc:1.2.3\r\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, s, mustParse(t, s).String())
+		})
+	}
+}
+
+func TestSet(t *testing.T) {
+	t.Run("existing scalar keeps its comment", func(t *testing.T) {
+		d := mustParse(t, "# hello\nsdk-version: 3.4.0 # pinned\nname: foo\n")
+		require.NoError(t, d.Set("sdk-version", "3.5.0"))
+		assert.Equal(t, "# hello\nsdk-version: 3.5.0 # pinned\nname: foo\n", d.String())
+	})
+
+	t.Run("values are quoted as needed", func(t *testing.T) {
+		d := mustParse(t, "version: 1.0.0\n")
+		require.NoError(t, d.Set("version", "1.0"))
+		assert.Equal(t, "version: \"1.0\"\n", d.String())
+	})
+
+	t.Run("new key", func(t *testing.T) {
+		d := mustParse(t, "sdk-version: 3.4.0\n\n# the deps\ndependencies:\n  - daml-prim\n")
+		require.NoError(t, d.Set("name", "foo"))
+		assert.Equal(t, "sdk-version: 3.4.0\n\n# the deps\ndependencies:\n  - daml-prim\nname: foo\n", d.String())
+	})
+
+	t.Run("new nested key", func(t *testing.T) {
+		d := mustParse(t, "name: foo\nbuild-options:\n  target: 2.1\n")
+		require.NoError(t, d.Set("build-options.output", "out"))
+		require.NoError(t, d.Set("codegen.java.package", "com.example"))
+		assert.Equal(t, "name: foo\nbuild-options:\n  target: 2.1\n  output: out\ncodegen:\n  java:\n    package: com.example\n", d.String())
+	})
+
+	t.Run("empty document", func(t *testing.T) {
+		d := mustParse(t, "")
+		require.NoError(t, d.Set("sdk-version", "3.4.0"))
+		assert.Equal(t, "sdk-version: 3.4.0\n", d.String())
+	})
+
+	t.Run("through a scalar", func(t *testing.T) {
+		d := mustParse(t, "name: foo\n")
+		assert.ErrorContains(t, d.Set("name.first", "bar"), `field "name" is not a map`)
+	})
+}
+
+func TestDelete(t *testing.T) {
+	d := mustParse(t, "sdk-version: 3.4.0\n# about foo\nname: foo\nversion: 1.0.0\nbuild-options:\n  target: 2.1\n  output: out\n")
+	require.NoError(t, d.Delete("name"))
+	require.NoError(t, d.Delete("build-options.target"))
+	require.NoError(t, d.Delete("missing"))
+	assert.Equal(t, "sdk-version: 3.4.0\nversion: 1.0.0\nbuild-options:\n  output: out\n", d.String())
+}
+
+func TestNestedLists(t *testing.T) {
+	d := mustParse(t, `sdk-version: 3.4.0
+packages:
+  - ./a
+  - ./b
+codegen:
+  js:
+    modules:
+      - Main # the entrypoint
+`)
+	require.NoError(t, d.Append("packages", "./c", ""))
+	require.NoError(t, d.Remove("packages", 0))
+	require.NoError(t, d.Append("codegen.js.modules", "Lib", "the library"))
+	require.NoError(t, d.Replace("codegen.js.modules", 0, "App", ""))
+	assert.Equal(t, `sdk-version: 3.4.0
+packages:
+  - ./b
+  - ./c
+codegen:
+  js:
+    modules:
+      - App
+      - Lib # the library
+`, d.String())
+}
+
+func TestFlowStyle(t *testing.T) {
+	d := mustParse(t, "packages: [./a, ./b] # the packages\ncomponents: []\n")
+	require.NoError(t, d.Append("packages", "./c", "ignored in flow style"))
+	require.NoError(t, d.Replace("packages", 0, "./z", ""))
+	require.NoError(t, d.Remove("packages", 1))
+	require.NoError(t, d.Append("components", "damlc:1.2.3", ""))
+	assert.Equal(t, "packages: [./z, ./c] # the packages\ncomponents: [damlc:1.2.3]\n", d.String())
+
+	_, err := Parse([]byte(d.String()))
+	require.NoError(t, err)
+}
+
+func TestAnchors(t *testing.T) {
+	d := mustParse(t, `base: &base
+  - oci://example.com/foo:1.0.0
+components: *base
+`)
+	require.NoError(t, d.Append("base", "oci://example.com/bar:1.0.0", ""))
+	assert.Equal(t, `base: &base
+  - oci://example.com/foo:1.0.0
+  - oci://example.com/bar:1.0.0
+components: *base
+`, d.String())
+
+	assert.ErrorContains(t, d.Append("components", "damlc:1.2.3", ""), "alias")
+	assert.ErrorContains(t, d.Remove("components", 0), "alias")
+}
+
+func TestCRLF(t *testing.T) {
+	d := mustParse(t, "sdk-version: 3.4.0\r\n# comps\r\ncomponents:\r\n  - damlc:1.2.3\r\n")
+	require.NoError(t, d.Set("sdk-version", "3.5.0"))
+	require.NoError(t, d.Append("components", "daml-script:1.2.3", "1.2.3"))
+	s := d.String()
+	assert.Equal(t, "sdk-version: 3.5.0\r\n# comps\r\ncomponents:\r\n  - damlc:1.2.3\r\n  - daml-script:1.2.3 # 1.2.3\r\n", s)
+	assert.NotContains(t, strings.ReplaceAll(s, "\r\n", ""), "\n", "no bare line feeds")
+}
+
+func TestAppendNewList(t *testing.T) {
+	d := mustParse(t, "sdk-version: 3.4.0\ndependencies:\n")
+	require.NoError(t, d.Append("dependencies", "daml-prim", ""))
+	require.NoError(t, d.Append("data-dependencies", "./foo.dar", "local"))
+	assert.Equal(t, "sdk-version: 3.4.0\ndependencies:\n  - daml-prim\ndata-dependencies:\n  - ./foo.dar # local\n", d.String())
+}
@@ -25,3 +25,15 @@ var InputReplaceNotLast []byte
 
 //go:embed replace/not-last/expected.yaml
 var ExpectedReplaceNotLast []byte
+
+//go:embed remove/first/input.yaml
+var InputRemoveFirst []byte
+
+//go:embed remove/first/expected.yaml
+var ExpectedRemoveFirst []byte
+
+//go:embed remove/only/input.yaml
+var InputRemoveOnly []byte
+
+//go:embed remove/only/expected.yaml
+var ExpectedRemoveOnly []byte
@@ -1,12 +1,7 @@
 package yamledit
 
 import (
-	"fmt"
-	"os"
-	"strings"
-
-	"github.com/goccy/go-yaml"
-	"github.com/goccy/go-yaml/parser"
+	"github.com/goccy/go-yaml/ast"
 )
 
 type YamlTarget struct {
@@ -29,168 +24,82 @@ func (t *YamlTarget) Copy() YamlTarget {
 // EditYaml adds an item to list in yaml file
 // or replace the given index with it
 func EditYaml(info YamlTarget, item string) error {
-	b, err := os.ReadFile(info.YamlFilePath)
+	d, err := ReadFile(info.YamlFilePath)
 	if err != nil {
 		return err
 	}
 
-	item = attachLineComment(item, info.LineComment)
+	node, err := ParseFragment(item)
+	if err != nil {
+		return err
+	}
 
-	var out string
 	if info.Index != -1 {
-		out, err = ReplaceItemInList(b, info.FieldName, info.Index, item)
+		err = d.Replace(info.FieldName, info.Index, node, info.LineComment)
 	} else {
-		out, err = AddToList(b, info.FieldName, item)
+		err = d.Append(info.FieldName, node, info.LineComment)
 	}
 	if err != nil {
 		return err
 	}
 
-	return os.WriteFile(info.YamlFilePath, []byte(out), 0644)
+	return d.WriteFile(info.YamlFilePath)
 }
 
-// AddToList adds item to the given target field.
-// item can be a simple value or a YAML object.
-func AddToList(raw []byte, field string, item string) (string, error) {
-	f, err := parser.ParseBytes(raw, parser.ParseComments)
-	if err != nil {
-		return "", err
-	}
-
-	itemListYAML, err := marshalOneItemList(item)
+// RemoveFromYaml removes the item at the target's index from the list in the yaml file
+func RemoveFromYaml(info YamlTarget) error {
+	d, err := ReadFile(info.YamlFilePath)
 	if err != nil {
-		return "", err
-	}
-
-	itemListFile, err := parser.ParseBytes(itemListYAML, parser.ParseComments)
-	if err != nil {
-		return "", err
-	}
-
-	path, err := yaml.PathString("$." + field)
-	if err != nil {
-		return "", err
-	}
-
-	err = path.MergeFromFile(f, itemListFile)
-	if err == nil {
-		return f.String(), nil
-	}
-
-	if !yaml.IsNotFoundNodeError(err) {
-		return "", err
-	}
-
-	// field does not exist yet. Add:
-	//
-	// field:
-	//   - <item> # <comment>
-	rootFragment := field + ":\n" + indentYaml(string(itemListYAML))
-
-	rootFragmentFile, err := parser.ParseBytes([]byte(rootFragment), parser.ParseComments)
-	if err != nil {
-		return "", err
-	}
-
-	root, err := yaml.PathString("$")
-	if err != nil {
-		return "", err
-	}
-
-	if err := root.MergeFromFile(f, rootFragmentFile); err != nil {
-		return "", err
-	}
-
-	return f.String(), nil
-}
-
-func marshalOneItemList(item string) ([]byte, error) {
-	item = normalizeYAMLFragment(item)
-	if strings.TrimSpace(item) == "" {
-		return nil, fmt.Errorf("empty YAML item")
-	}
-
-	// Validate the raw item first.
-	if _, err := parser.ParseBytes([]byte(item), parser.ParseComments); err != nil {
-		return nil, fmt.Errorf("invalid YAML item: %w", err)
-	}
-
-	lines := strings.Split(strings.TrimRight(item, "\n"), "\n")
-	out := make([]string, len(lines))
-
-	out[0] = "- " + lines[0]
-	for i := 1; i < len(lines); i++ {
-		out[i] = "  " + lines[i]
+		return err
 	}
 
-	seq := []byte(strings.Join(out, "\n") + "\n")
-
-	// Validate the generated one-item sequence too.
-	if _, err := parser.ParseBytes(seq, parser.ParseComments); err != nil {
-		return nil, fmt.Errorf("invalid YAML list item: %w", err)
+	if err := d.Remove(info.FieldName, info.Index); err != nil {
+		return err
 	}
 
-	return seq, nil
-}
-
-func normalizeYAMLFragment(s string) string {
-	s = strings.ReplaceAll(s, "\r\n", "\n")
-	s = strings.Trim(s, "\n")
-	return s + "\n"
-}
-
-func indentYaml(s string) string {
-	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
-	for i := range lines {
-		lines[i] = "  " + lines[i]
-	}
-	return strings.Join(lines, "\n") + "\n"
+	return d.WriteFile(info.YamlFilePath)
 }
 
-func attachLineComment(item, comment string) string {
-	comment = strings.TrimSpace(comment)
-	if comment == "" {
-		return item
-	}
-
-	comment = strings.TrimSpace(strings.TrimPrefix(comment, "#"))
-	if comment == "" {
-		return item
-	}
-
-	item = strings.TrimRight(item, "\n")
-	lines := strings.Split(item, "\n")
-	lines[0] = strings.TrimRight(lines[0], " \t") + " # " + comment
-	return strings.Join(lines, "\n")
+// AddToList adds item to the given target field.
+// item can be a simple value or a YAML object.
+func AddToList(raw []byte, field string, item string) (string, error) {
+	return edit(raw, func(d *Document, node ast.Node) error {
+		return d.Append(field, node, "")
+	}, item)
 }
 
 // ReplaceItemInList replaces the specified item in given field.
 // item can be a simple value or a whole object
 func ReplaceItemInList(raw []byte, field string, index int, replacement string) (string, error) {
-	return replace(raw, fmt.Sprintf("$.%s[%d]", field, index), replacement)
+	return edit(raw, func(d *Document, node ast.Node) error {
+		return d.Replace(field, index, node, "")
+	}, replacement)
 }
 
-func replace(raw []byte, path string, replacement string) (string, error) {
-	f, err := parser.ParseBytes(raw, parser.ParseComments)
+// RemoveFromList removes the item at index from the given field, along with the comments above it.
+// The field itself is removed once its list is empty
+func RemoveFromList(raw []byte, field string, index int) (string, error) {
+	d, err := Parse(raw)
 	if err != nil {
 		return "", err
 	}
+	if err := d.Remove(field, index); err != nil {
+		return "", err
+	}
+	return d.String(), nil
+}
 
-	p, err := yaml.PathString(path)
+func edit(raw []byte, fn func(*Document, ast.Node) error, item string) (string, error) {
+	d, err := Parse(raw)
 	if err != nil {
 		return "", err
 	}
-
-	replacement = normalizeYAMLFragment(replacement)
-
-	replacementFile, err := parser.ParseBytes([]byte(replacement), parser.ParseComments)
+	node, err := ParseFragment(item)
 	if err != nil {
-		return "", fmt.Errorf("invalid YAML replacement: %w", err)
+		return "", err
 	}
-
-	if err := p.ReplaceWithFile(f, replacementFile); err != nil {
+	if err := fn(d, node); err != nil {
 		return "", err
 	}
-
-	return f.String(), nil
+	return d.String(), nil
 }
@@ -8,8 +8,8 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-var item = attachLineComment(`name: newly-added
-path: /newly/added`, "# some foo comment")
+var item = `name: newly-added # some foo comment
+path: /newly/added`
 
 func TestAddToList(t *testing.T) {
 	t.Run("non-empty list", func(t *testing.T) {
@@ -38,3 +38,22 @@ func TestUpdateItemInList(t *testing.T) {
 		assert.Equal(t, string(testdata.ExpectedReplaceNotLast), output)
 	})
 }
+
+func TestRemoveFromList(t *testing.T) {
+	t.Run("first item", func(t *testing.T) {
+		output, err := RemoveFromList(testdata.InputRemoveFirst, "components", 0)
+		require.NoError(t, err)
+		assert.Equal(t, string(testdata.ExpectedRemoveFirst), output)
+	})
+
+	t.Run("only item", func(t *testing.T) {
+		output, err := RemoveFromList(testdata.InputRemoveOnly, "components", 0)
+		require.NoError(t, err)
+		assert.Equal(t, string(testdata.ExpectedRemoveOnly), output)
+	})
+
+	t.Run("out of range", func(t *testing.T) {
+		_, err := RemoveFromList(testdata.InputRemoveOnly, "components", 1)
+		assert.ErrorContains(t, err, "no item at index 1")
+	})
+}